@@ -2,100 +2,293 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 	"database/sql"
 	"strings"
-	"fmt"
 	"log"
+	"net/url"
 	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/Marcus-Gustafsson/gator/internal/simhash"
 	"github.com/google/uuid"
 )
 
 // handlerAgg starts the aggregation loop that fetches RSS feeds at the interval specified
-// by the user (time_between_reqs argument; e.g., "1m", "10s"). It validates arguments,
-// parses duration, prints the loop interval, and runs scrapeFeeds using a ticker. Returns
-// an error if argument parsing or duration parsing fails.
+// by the user (time_between_reqs argument; e.g., "1m", "10s"). It parses the duration,
+// prints the loop interval, and runs scrapeFeeds using a ticker, pruning old posts after
+// each fetch with --prune. Returns an error if duration parsing fails.
 func handlerAgg(stPtr *state, cmd command) error {
-	if len(cmd.Args) != 1 {
-		return fmt.Errorf("handlerAgg: usage: %v <time_between_reqs>", cmd.Name)
-	}
-
 	// Parse the requested time interval, report error with context if invalid.
 	timeBetweenRequests, err := time.ParseDuration(cmd.Args[0])
 	if err != nil {
-		return fmt.Errorf("handlerAgg: invalid duration: %w", err)
+		return cmd.usageError("invalid duration %q: %v", cmd.Args[0], err)
 	}
 
 	log.Printf("Collecting feeds every %s...", timeBetweenRequests)
+	aggregateForever(stPtr, timeBetweenRequests, cmd.boolFlag("prune"))
+	return nil
+}
 
+// aggregateForever scrapes the next feed immediately and then once per interval,
+// never returning. With prune, posts past their retention are deleted after each
+// fetch. Shared by the agg and serve commands.
+func aggregateForever(stPtr *state, timeBetweenRequests time.Duration, prune bool) {
 	ticker := time.NewTicker(timeBetweenRequests)
 
 	// Loop forever, scraping feeds immediately and then at each interval.
 	for ; ; <-ticker.C {
 		scrapeFeeds(stPtr)
+		if prune {
+			pruneAfterCycle(stPtr)
+		}
 	}
 }
 
 // scrapeFeeds retrieves the next feed to fetch from the database and processes it.
-// Logs errors if feed retrieval fails, otherwise it calls scrapeFeed.
+// In serve mode, feeds with an active WebSub lease are skipped since the hub pushes
+// their updates. Logs errors if feed retrieval fails, otherwise it calls scrapeFeed.
 func scrapeFeeds(stPtr *state) {
-	feed, err := stPtr.dbPtr.GetNextFeedToFetch(context.Background())
+	var feed database.Feed
+	var err error
+	if stPtr.webSubPtr != nil {
+		feed, err = stPtr.dbPtr.GetNextPolledFeedToFetch(context.Background(), time.Now().UTC())
+	} else {
+		feed, err = stPtr.dbPtr.GetNextFeedToFetch(context.Background())
+	}
 	if err != nil {
 		log.Println("scrapeFeeds: couldn't get next feed to fetch:", err)
 		return
 	}
 	log.Printf("scrapeFeeds: found a feed to fetch: %s", feed.Name)
-	scrapeFeed(stPtr.dbPtr, feed)
+	scrapeFeed(stPtr, feed)
 }
 
-// scrapeFeed marks a feed as fetched in the database, collects its posts via fetchFeed,
-// and saves each post to the database with proper time parsing. Handles duplicate post
-// URLs by continuing, logs other database errors, and reports the total number of posts
-// processed. Errors in marking, fetching, or post creation are logged with context.
-func scrapeFeed(dbPtr *database.Queries, feed database.Feed) {
+// scrapeFeed collects a feed's posts via fetchFeed and hands them to ingestFeedItems,
+// which marks the feed as fetched in the same transaction as it saves the posts. If the
+// fetch fails the feed is still marked, so that one broken feed doesn't hold up the
+// others. If running in serve mode and the feed advertises a WebSub hub, it also makes
+// sure we're subscribed to it once the posts are saved. Errors are logged with context.
+func scrapeFeed(stPtr *state, feed database.Feed) {
 
-	_, err := dbPtr.MarkFeedFetched(context.Background(), feed.ID)
+	feedData, err := fetchFeed(context.Background(), feed.Url)
 	if err != nil {
-		log.Printf("scrapeFeed: couldn't mark feed %s as fetched: %v", feed.Name, err)
+		log.Printf("scrapeFeed: couldn't collect feed %s: %v", feed.Name, err)
+		if _, err := stPtr.dbPtr.MarkFeedFetched(context.Background(), database.MarkFeedFetchedParams{
+			Now: time.Now().UTC(),
+			ID:  feed.ID,
+		}); err != nil {
+			log.Printf("scrapeFeed: couldn't mark feed %s as fetched: %v", feed.Name, err)
+		}
 		return
 	}
 
-	feedData, err := fetchFeed(context.Background(), feed.Url)
-	if err != nil {
-		log.Printf("scrapeFeed: couldn't collect feed %s: %v", feed.Name, err)
+	if err := ingestFeedItems(stPtr, feed, feedData, true); err != nil {
+		log.Printf("scrapeFeed: couldn't save feed %s: %v", feed.Name, err)
 		return
 	}
+	log.Printf("scrapeFeed: feed %s collected, %d posts found", feed.Name, len(feedData.Channel.Item))
+
+	// Only once the posts are saved: a lease stops polling, which would otherwise be
+	// what retries posts that failed to save.
+	hubURL := feedData.atomLink("hub")
+	if hubURL != "" && stPtr.webSubPtr != nil {
+		topicURL := feedData.atomLink("self")
+		if topicURL == "" {
+			topicURL = feed.Url
+		}
+		stPtr.webSubPtr.ensureSubscribed(stPtr, feed, hubURL, topicURL)
+	} else if hubURL == "" {
+		dropWebSubSubscription(stPtr, feed)
+	}
+}
 
-	for _, item := range feedData.Channel.Item {
-		publishedAt := sql.NullTime{}
-		if t, err := time.Parse(time.RFC1123Z, item.PubDate); err == nil {
-			publishedAt = sql.NullTime{
-				Time:  t,
-				Valid: true,
+// ingestFeedItems saves each item of a feed as a post with proper time parsing. It is
+// the single post-creation path, used both for polled feeds (markFetched, so the feed
+// is marked as fetched along with its posts) and for WebSub pushes. The new posts are
+// saved in one transaction, grouped with the same story from other feeds and run
+// through the feed's rules; if any post can't be saved or have its rules applied, none
+// are saved, and the next fetch tries again. Post URLs are normalised first (the link as published is kept as
+// original_url), so the same article behind different tracking links is skipped like
+// any other duplicate, as are posts deleted by prune. Full content is downloaded after
+// the transaction, and its failures only logged.
+func ingestFeedItems(stPtr *state, feed database.Feed, feedData *RSSFeed, markFetched bool) error {
+	var newPosts []database.Post
+	err := stPtr.withTx(context.Background(), func(txPtr *state) error {
+		if markFetched {
+			if _, err := txPtr.dbPtr.MarkFeedFetched(context.Background(), database.MarkFeedFetchedParams{
+				Now: time.Now().UTC(),
+				ID:  feed.ID,
+			}); err != nil {
+				return fmt.Errorf("couldn't mark feed as fetched: %w", err)
 			}
 		}
 
-		_, err = dbPtr.CreatePost(context.Background(), database.CreatePostParams{
-			ID:        uuid.New(),
-			CreatedAt: time.Now().UTC(),
-			UpdatedAt: time.Now().UTC(),
-			FeedID:    feed.ID,
-			Title:     item.Title,
-			Description: sql.NullString{
-				String: item.Description,
-				Valid:  true,
-			},
-			Url:         item.Link,
-			PublishedAt: publishedAt,
-		})
+		stories := &storyFinder{feedID: feed.ID}
+		feedRules, err := loadFeedRules(txPtr, feed)
 		if err != nil {
-			if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+			return err
+		}
+		listed := map[string]bool{}
+
+		for _, item := range feedData.Channel.Item {
+			publishedAt := sql.NullTime{}
+			if t, err := time.Parse(time.RFC1123Z, item.PubDate); err == nil {
+				publishedAt = sql.NullTime{
+					Time:  t,
+					Valid: true,
+				}
+			}
+
+			// Posts pruned earlier stay pruned while the feed still lists them.
+			postURL := stPtr.normalizePostURL(item.Link)
+			listed[postURL] = true
+			pruned, err := txPtr.dbPtr.IsPostPruned(context.Background(), database.IsPostPrunedParams{
+				FeedID: feed.ID,
+				Url:    postURL,
+			})
+			if err != nil {
+				return fmt.Errorf("couldn't check whether %s was pruned: %w", item.Link, err)
+			}
+			if pruned {
 				continue
 			}
-			log.Printf("scrapeFeed: couldn't create post: %v", err)
+
+			// Every post starts as its own story; assignStory may merge it into another.
+			postID := uuid.New()
+			fingerprint := sql.NullInt64{}
+			if fp, ok := simhash.Fingerprint(item.Title); ok {
+				fingerprint = sql.NullInt64{Int64: int64(fp), Valid: true}
+			}
+
+			post, err := txPtr.dbPtr.CreatePost(context.Background(), database.CreatePostParams{
+				ID:        postID,
+				CreatedAt: time.Now().UTC(),
+				UpdatedAt: time.Now().UTC(),
+				FeedID:    feed.ID,
+				Title:     item.Title,
+				Description: sql.NullString{
+					String: item.Description,
+					Valid:  true,
+				},
+				Url: postURL,
+				OriginalUrl: sql.NullString{
+					String: item.Link,
+					Valid:  true,
+				},
+				PublishedAt: publishedAt,
+				BaseUrl: sql.NullString{
+					String: itemBaseURL(feed.Url, feedData, item),
+					Valid:  true,
+				},
+				Fingerprint: fingerprint,
+				StoryID:     postID,
+				Author:      itemAuthor(item),
+				Categories:  item.Categories,
+			})
+			if errors.Is(err, sql.ErrNoRows) {
+				continue // the feed already has a post with this URL
+			}
+			if err != nil {
+				return fmt.Errorf("couldn't create post for %s: %w", item.Link, err)
+			}
+
+			// Only new posts get here (duplicates were skipped above), so stories are
+			// matched and each page is downloaded at most once.
+			stories.assignStory(txPtr, post)
+			if err := applyFeedRules(txPtr, feedRules, feed, post); err != nil {
+				return fmt.Errorf("couldn't apply rules to %s: %w", item.Link, err)
+			}
+			newPosts = append(newPosts, post)
+		}
+
+		// A polled feed lists everything it still offers, so pruned posts it dropped
+		// can't come back and their tombstones can go. Pushes only carry new items.
+		if markFetched {
+			if err := forgetUnlistedPruned(txPtr, feed, listed); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if feed.FetchFullContent {
+		for _, post := range newPosts {
+			storeFullContent(stPtr, post)
+		}
+	}
+	return nil
+}
+
+// forgetUnlistedPruned deletes the feed's pruned-post records whose URL isn't in listed,
+// the normalised URLs of the feed's current items.
+func forgetUnlistedPruned(stPtr *state, feed database.Feed, listed map[string]bool) error {
+	urls, err := stPtr.dbPtr.GetFeedPrunedURLs(context.Background(), feed.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get pruned posts: %w", err)
+	}
+	for _, url := range urls {
+		if listed[url] {
 			continue
 		}
+		err := stPtr.dbPtr.DeletePrunedPost(context.Background(), database.DeletePrunedPostParams{
+			FeedID: feed.ID,
+			Url:    url,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't forget pruned post %s: %w", url, err)
+		}
 	}
+	return nil
+}
 
-	log.Printf("scrapeFeed: feed %s collected, %d posts found", feed.Name, len(feedData.Channel.Item))
-}
\ No newline at end of file
+// storeFullContent downloads and extracts the article a post links to and saves it as
+// the post's content. Failures are logged and leave the post with its description only.
+func storeFullContent(stPtr *state, post database.Post) {
+	content, err := fetchArticle(context.Background(), post.Url)
+	if err != nil {
+		log.Printf("storeFullContent: couldn't fetch article for %s: %v", post.Url, err)
+		return
+	}
+
+	err = stPtr.dbPtr.UpdatePostContent(context.Background(), database.UpdatePostContentParams{
+		ID:        post.ID,
+		Content:   sql.NullString{String: content, Valid: true},
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("storeFullContent: couldn't save content for %s: %v", post.Url, err)
+	}
+}
+
+// itemAuthor returns the item's author: dc:creator when present (usually a name),
+// otherwise the RSS author element (usually an email address).
+func itemAuthor(item RSSItem) sql.NullString {
+	author := item.Creator
+	if author == "" {
+		author = item.Author
+	}
+	return sql.NullString{String: author, Valid: author != ""}
+}
+
+// itemBaseURL works out what relative links in an item's HTML resolve against: the
+// item's xml:base, applied on top of the channel's xml:base, on top of the channel's
+// <link>, on top of the feed's own URL. Unparseable parts are skipped.
+func itemBaseURL(feedURL string, feedData *RSSFeed, item RSSItem) string {
+	base, err := url.Parse(feedURL)
+	if err != nil {
+		return feedURL
+	}
+	for _, ref := range []string{feedData.Channel.Link, feedData.Channel.Base, item.Base} {
+		if ref == "" {
+			continue
+		}
+		if refURL, err := url.Parse(strings.TrimSpace(ref)); err == nil {
+			base = base.ResolveReference(refURL)
+		}
+	}
+	return base.String()
+}
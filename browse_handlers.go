@@ -2,54 +2,322 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/Marcus-Gustafsson/gator/internal/render"
 	"github.com/google/uuid"
 )
 
-// handlerBrowse retrieves and displays posts for the current user with an optional limit
-// parameter (defaults to 2 if not provided). It validates the limit argument if given,
-// queries posts from feeds the user follows, and prints formatted post details including
-// publication date, feed name, title, description, and URL. Returns an error if limit
-// parsing or post retrieval fails.
+// handlerBrowse retrieves and displays posts for the current user. By default it shows
+// the 2 newest unread posts; a positional limit (or --limit) changes the page size. Flags
+// filter by feed URL (--feed) or folder (--folder), date range (--since inclusive,
+// --until exclusive), read state (--unread=false or --all to include read posts), stars
+// (--starred) and tag (--tag), include posts hidden by rules (--hidden), pick the order
+// (--sort published, fetched or feed; posts without a date sort last), and page through
+// results with --offset, --page or the --cursor printed after a full page. A story that
+// arrived through several followed feeds is shown once, listing the other feeds it also
+// appeared in. Returns an error if argument parsing or post retrieval fails.
 func handlerBrowse(stPtr *state, cmd command, user database.User) error {
-	limit := 2
+	limit := cmd.intFlag("limit")
 	if len(cmd.Args) == 1 {
-		if specifiedLimit, err := strconv.Atoi(cmd.Args[0]); err == nil {
-			limit = specifiedLimit
-		} else {
-			return fmt.Errorf("handlerBrowse: invalid limit argument: %w", err)
+		specifiedLimit, err := strconv.Atoi(cmd.Args[0])
+		if err != nil {
+			return cmd.usageError("invalid limit %q", cmd.Args[0])
 		}
+		limit = specifiedLimit
 	}
+	all, unread, starred, hidden := cmd.boolFlag("all"), cmd.boolFlag("unread"), cmd.boolFlag("starred"), cmd.boolFlag("hidden")
+	tag, feedURL, folderName := cmd.stringFlag("tag"), cmd.stringFlag("feed"), cmd.stringFlag("folder")
+	since, until, sortBy, cursor := cmd.stringFlag("since"), cmd.stringFlag("until"), cmd.stringFlag("sort"), cmd.stringFlag("cursor")
+	offset, page := cmd.intFlag("offset"), cmd.intFlag("page")
 
-	posts, err := stPtr.getPostsForUser(user.ID, limit)
+	if limit <= 0 {
+		return cmd.usageError("limit must be positive")
+	}
+	if sortBy != "published" && sortBy != "fetched" && sortBy != "feed" {
+		return cmd.usageError("unknown sort %q, expected published, fetched or feed", sortBy)
+	}
+	if page != 0 && offset != 0 {
+		return cmd.usageError("use either --page or --offset, not both")
+	}
+	if page < 0 || offset < 0 {
+		return cmd.usageError("--page and --offset can't be negative")
+	}
+	if page > 0 {
+		offset = (page - 1) * limit
+	}
+
+	params := database.GetPostsForUserParams{
+		UserID:      user.ID,
+		UnreadOnly:  unread && !all,
+		StarredOnly: starred,
+		ShowHidden:  hidden,
+		Sort:        sortBy,
+		Offset:      int32(offset),
+		Limit:       int32(limit),
+	}
+	if tag != "" {
+		params.Tag = sql.NullString{String: normalizeTag(tag), Valid: true}
+	}
+	if feedURL != "" {
+		feed, err := stPtr.dbPtr.GetFeedByURL(context.Background(), feedURL)
+		if err != nil {
+			return fmt.Errorf("handlerBrowse: couldn't get feed: %w", err)
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	if folderName != "" {
+		folderID, err := getFolderID(stPtr, user, folderName)
+		if err != nil {
+			return fmt.Errorf("handlerBrowse: %w", err)
+		}
+		params.FolderID = folderID
+	}
+	if since != "" {
+		sinceTime, err := stPtr.parseDate(since)
+		if err != nil {
+			return fmt.Errorf("handlerBrowse: --since: %w", err)
+		}
+		params.Since = sql.NullTime{Time: sinceTime.UTC(), Valid: true}
+	}
+	if until != "" {
+		untilTime, err := stPtr.parseDate(until)
+		if err != nil {
+			return fmt.Errorf("handlerBrowse: --until: %w", err)
+		}
+		params.Until = sql.NullTime{Time: untilTime.UTC(), Valid: true}
+	}
+	if cursor != "" {
+		position, err := decodeBrowseCursor(cursor)
+		if err != nil {
+			return fmt.Errorf("handlerBrowse: %w", err)
+		}
+		if position.Sort != sortBy {
+			return fmt.Errorf("handlerBrowse: cursor was made for --sort %s", position.Sort)
+		}
+		params.CursorGroup = sql.NullString{String: position.Group, Valid: true}
+		params.CursorTime = sql.NullTime{Time: position.Time, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: position.ID, Valid: true}
+	}
+
+	posts, err := stPtr.getPostsForUser(params)
 	if err != nil {
 		return fmt.Errorf("handlerBrowse: couldn't retrieve posts for user: %w", err)
 	}
+	dates, err := stPtr.dateDisplay(postDateLayout)
+	if err != nil {
+		return fmt.Errorf("handlerBrowse: %w", err)
+	}
 
-	fmt.Printf("Found %d posts for user %s:\n", len(posts), user.Name.String)
-	for _, post := range posts {
-		fmt.Printf("%s from %s\n", post.PublishedAt.Time.Format("Mon Jan 2"), post.FeedName)
-		fmt.Printf("--- %s ---\n", post.Title)
-		fmt.Printf("    %v\n", post.Description.String)
-		fmt.Printf("Link: %s\n", post.Url)
-		fmt.Println("=====================================")
+	views := make([]postView, len(posts))
+	for i, post := range posts {
+		views[i] = postView{
+			ID:            post.ID,
+			PublishedAt:   post.PublishedAt,
+			FeedName:      post.FeedName,
+			AlsoIn:        post.AlsoIn,
+			Title:         post.Title,
+			Description:   post.Description,
+			Content:       post.Content,
+			BaseUrl:       post.BaseUrl,
+			Url:           post.Url,
+			IsRead:        post.IsRead,
+			IsStarred:     post.IsStarred,
+			IsHighlighted: post.IsHighlighted,
+			Tags:          post.Tags,
+		}
+	}
+
+	err = stPtr.outPtr.Print(postItems(views), func() {
+		if params.UnreadOnly {
+			fmt.Printf("Found %d unread posts for user %s:\n", len(posts), user.Name.String)
+		} else {
+			fmt.Printf("Found %d posts for user %s:\n", len(posts), user.Name.String)
+		}
+		for _, view := range views {
+			printPost(view, dates)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// A full page means there may be more; print where the next one starts. Structured
+	// output keeps stdout to the posts, so the hint goes to stderr there.
+	if len(posts) == limit {
+		last := posts[len(posts)-1]
+		next := browseCursor{Sort: sortBy, Group: last.SortGroup, Time: last.SortTime, ID: last.ID}
+		hint := os.Stdout
+		if !stPtr.outPtr.IsText() {
+			hint = os.Stderr
+		}
+		fmt.Fprintf(hint, "Next page: repeat with --cursor %s\n", next.encode())
 	}
 
 	return nil
 }
 
-// getPostsForUser retrieves posts for the given user up to the specified limit.
+// browseCursor marks the position after the last post of a browse page: the sort it was
+// made for and that post's sort keys as returned by GetPostsForUser.
+type browseCursor struct {
+	Sort  string    `json:"s"`
+	Group string    `json:"g"`
+	Time  time.Time `json:"t"`
+	ID    uuid.UUID `json:"i"`
+}
+
+// encode returns the cursor as an opaque, shell-safe token.
+func (c browseCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeBrowseCursor parses a token made by browseCursor.encode.
+func decodeBrowseCursor(token string) (browseCursor, error) {
+	var c browseCursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil || c.ID == uuid.Nil {
+		return browseCursor{}, fmt.Errorf("invalid cursor %q", token)
+	}
+	return c, nil
+}
+
+// getPostsForUser retrieves a page of posts for the given user.
 // It delegates to the database query and returns any error encountered.
-func (st *state) getPostsForUser(userID uuid.UUID, limit int) ([]database.GetPostsForUserRow, error) {
-	posts, err := st.dbPtr.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
-		UserID: userID,
-		Limit:  int32(limit),
-	})
+func (st *state) getPostsForUser(params database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+	posts, err := st.dbPtr.GetPostsForUser(context.Background(), params)
 	if err != nil {
 		return nil, err
 	}
 	return posts, nil
 }
+
+// postView holds what printPost shows of a post, so browse and starred can share one
+// layout even though their queries return different row types.
+type postView struct {
+	ID            uuid.UUID
+	PublishedAt   sql.NullTime
+	FeedName      string
+	AlsoIn        []string
+	Title         string
+	Description   sql.NullString
+	Content       sql.NullString
+	BaseUrl       sql.NullString
+	Url           string
+	IsRead        bool
+	IsStarred     bool
+	IsHighlighted bool
+	Tags          []string
+}
+
+// postItem is a post as listed by browse and starred in structured output. The body is
+// left out; follow the URL or use the text output to read it.
+type postItem struct {
+	ID          uuid.UUID  `json:"id"`
+	PublishedAt *time.Time `json:"published_at"`
+	Feed        string     `json:"feed"`
+	AlsoIn      []string   `json:"also_in"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Read        bool       `json:"read"`
+	Starred     bool       `json:"starred"`
+	Highlighted bool       `json:"highlighted"`
+	Tags        []string   `json:"tags"`
+}
+
+// postItems converts posts for structured output.
+func postItems(views []postView) []postItem {
+	items := make([]postItem, len(views))
+	for i, view := range views {
+		items[i] = postItem{
+			ID:          view.ID,
+			PublishedAt: nullTime(view.PublishedAt),
+			Feed:        view.FeedName,
+			AlsoIn:      view.AlsoIn,
+			Title:       view.Title,
+			URL:         view.Url,
+			Read:        view.IsRead,
+			Starred:     view.IsStarred,
+			Highlighted: view.IsHighlighted,
+			Tags:        view.Tags,
+		}
+	}
+	return items
+}
+
+// postDateLayout is how post dates are shown in listings unless the config file sets a
+// date_format.
+const postDateLayout = "Mon Jan 2"
+
+// printPost prints one post: short ID, date, feed and read/starred/highlighted markers,
+// tags, the title, the content (or description when no full content was extracted)
+// rendered as wrapped plain text, and the link.
+func printPost(post postView, dates dateDisplay) {
+	markers := ""
+	if post.IsRead {
+		markers += " (read)"
+	}
+	if post.IsStarred {
+		markers += " (starred)"
+	}
+	if post.IsHighlighted {
+		markers += " (highlighted)"
+	}
+	fmt.Printf("[%s] %s from %s%s\n", shortID(post.ID), dates.formatNull(post.PublishedAt), post.FeedName, markers)
+	if len(post.AlsoIn) > 0 {
+		fmt.Printf("Also in: %s\n", strings.Join(post.AlsoIn, ", "))
+	}
+	if len(post.Tags) > 0 {
+		fmt.Printf("Tags: %s\n", strings.Join(post.Tags, ", "))
+	}
+	fmt.Printf("--- %s ---\n", post.Title)
+	body := post.Description.String
+	if post.Content.Valid {
+		body = post.Content.String
+	}
+	fmt.Println(indent(renderPostText(body, post.BaseUrl, terminalWidth()-4), "    "))
+	fmt.Printf("Link: %s\n", post.Url)
+	fmt.Println("=====================================")
+}
+
+// renderPostText converts a post's stored HTML to text wrapped at width with link
+// footnotes, resolving relative links against the post's base URL when it has one.
+func renderPostText(body string, baseURL sql.NullString, width int) string {
+	var base *url.URL
+	if baseURL.Valid {
+		base, _ = url.Parse(baseURL.String)
+	}
+	return render.Text(body, base, width)
+}
+
+// terminalWidth returns the width to wrap text at, taken from $COLUMNS when the shell
+// exports it and defaulting to 80 otherwise.
+func terminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return 80
+}
+
+// indent prefixes every non-empty line of text with prefix.
+func indent(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -1,19 +1,408 @@
 package main
 
-import "fmt"
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 
-// run executes the given command using the associated handler, if it exists.
-func (cmdsPtr *cmds) run(stPtr *state, cmd command) error{
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+)
 
-    function, ok := cmdsPtr.FunctionMap[cmd.Name]
-    // check if a handler exists before calling it, prevents a panic from a nil map entry.
-    if !ok {
-        return fmt.Errorf("unknown command: %s", cmd.Name)
-    }
-    return function(stPtr, cmd)
+// Exit codes: a command that ran but failed exits with exitFailure; an unknown command
+// or invalid arguments exit with exitUsage, like the flag package does.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// flagKind is the type of a command flag's value.
+type flagKind int
+
+const (
+	flagString flagKind = iota
+	flagBool
+	flagInt
+)
+
+// flagSpec declares a flag a command accepts.
+type flagSpec struct {
+	Name     string
+	Kind     flagKind
+	Arg      string // placeholder for the value in usage lines, e.g. "<n>"
+	Default  string // default value as typed on the command line; "" is the zero value
+	Usage    string
+	Complete string // what shell completion offers for the value, see completionSource
+}
+
+// argSpec declares a positional argument. Optional arguments must come after the
+// required ones, and only the last argument may be variadic.
+type argSpec struct {
+	Name     string
+	Optional bool
+	Variadic bool   // takes one or more (or, if Optional, zero or more) values
+	Complete string // what shell completion offers, see completionSource
+}
+
+// commandSpec describes a command for dispatch, argument parsing and help.
+type commandSpec struct {
+	Name        string
+	Group       string // heading the command is listed under in "gator help"
+	Summary     string // one line for "gator help"
+	Description string // extra detail for "gator help <command>"
+	Args        []argSpec
+	Flags       []flagSpec
+	DashArgs    bool // arguments starting with "-" that aren't declared flags are positional (search terms, rule expressions)
+	Hidden      bool // left out of help and completion
+	RawArgs     bool // the handler gets the arguments as typed, without flag parsing
+	NoDatabase  bool // runs without checking that the database schema is up to date
+
+	// Exactly one of Handler and UserHandler is set; UserHandler commands require a
+	// logged-in user, which register wires up with middlewareLoggedIn.
+	Handler     func(*state, command) error
+	UserHandler func(*state, command, database.User) error
+}
+
+// usageError reports a command invoked incorrectly. main exits with exitUsage for it.
+type usageError struct {
+	msg   string
+	usage string // the command's usage line, if known
+}
+
+func (e *usageError) Error() string {
+	if e.usage == "" {
+		return e.msg
+	}
+	return e.msg + "\nusage: " + e.usage
+}
+
+// exitCode returns the process exit status for an error returned by run.
+func exitCode(err error) int {
+	var usageErr *usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	default:
+		return exitFailure
+	}
 }
 
-// register adds a command handler function for a given command name to used cmds structs map.
-func (cmdsPtr *cmds) register(name string, function func(*state, command) error){
-    cmdsPtr.FunctionMap[name] = function
-}
\ No newline at end of file
+// register adds a command to the registry.
+func (cmdsPtr *cmds) register(spec commandSpec) {
+	if spec.UserHandler != nil {
+		spec.Handler = middlewareLoggedIn(spec.UserHandler)
+	}
+	cmdsPtr.Specs[spec.Name] = &spec
+	cmdsPtr.Order = append(cmdsPtr.Order, spec.Name)
+}
+
+// run executes the given command: it looks up the command, parses its flags and
+// positional arguments according to its spec, and calls the handler.
+func (cmdsPtr *cmds) run(stPtr *state, cmd command) error {
+	spec, ok := cmdsPtr.Specs[cmd.Name]
+	if !ok {
+		return cmdsPtr.unknownCommand(cmd.Name)
+	}
+
+	// Help needs no database, so it works before the schema is set up or upgraded.
+	if !spec.RawArgs {
+		for _, arg := range cmd.Args {
+			if arg == "--" {
+				break
+			}
+			if arg == "-h" || arg == "--help" {
+				cmdsPtr.printCommandHelp(os.Stdout, spec)
+				return nil
+			}
+		}
+	}
+
+	if !spec.NoDatabase {
+		if err := checkSchema(stPtr); err != nil {
+			return err
+		}
+	}
+
+	if spec.RawArgs {
+		return spec.Handler(stPtr, command{Name: spec.Name, Args: cmd.Args, spec: spec})
+	}
+
+	parsed, err := spec.parse(cmd.Args)
+	if err != nil {
+		return err
+	}
+	return spec.Handler(stPtr, parsed)
+}
+
+// parse splits args into flags and positional arguments, parses the flags and checks
+// the number of positional arguments.
+func (spec *commandSpec) parse(args []string) (command, error) {
+	flags := flag.NewFlagSet(spec.Name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	for _, f := range spec.Flags {
+		switch f.Kind {
+		case flagBool:
+			flags.Bool(f.Name, f.Default == "true", f.Usage)
+		case flagInt:
+			value, _ := strconv.Atoi(f.Default)
+			flags.Int(f.Name, value, f.Usage)
+		default:
+			flags.String(f.Name, f.Default, f.Usage)
+		}
+	}
+
+	// Flags may appear anywhere among the positional arguments; "--" ends them.
+	var flagArgs, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		name, hasValue := flagName(arg)
+		if name == "" {
+			positional = append(positional, arg)
+			continue
+		}
+		f := spec.flag(name)
+		if f == nil {
+			if spec.DashArgs {
+				positional = append(positional, arg)
+				continue
+			}
+			return command{}, spec.usageError("unknown flag %s", arg)
+		}
+		flagArgs = append(flagArgs, arg)
+		if f.Kind != flagBool && !hasValue {
+			if i+1 == len(args) {
+				return command{}, spec.usageError("flag --%s needs a value", name)
+			}
+			flagArgs = append(flagArgs, args[i+1])
+			i++
+		}
+	}
+	if err := flags.Parse(flagArgs); err != nil {
+		return command{}, spec.usageError("%v", err)
+	}
+
+	required, variadic := 0, false
+	for _, arg := range spec.Args {
+		if !arg.Optional {
+			required++
+		}
+		variadic = variadic || arg.Variadic
+	}
+	if len(positional) < required || (!variadic && len(positional) > len(spec.Args)) {
+		return command{}, spec.usageError("wrong number of arguments")
+	}
+
+	return command{Name: spec.Name, Args: positional, flags: flags, spec: spec}, nil
+}
+
+// flagName returns the flag name of an argument like "-x", "--name" or "--name=value",
+// or "" if the argument isn't shaped like a flag.
+func flagName(arg string) (name string, hasValue bool) {
+	if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+		return "", false
+	}
+	name = strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+	name, _, hasValue = strings.Cut(name, "=")
+	return name, hasValue
+}
+
+// flag returns the declared flag with this name, or nil.
+func (spec *commandSpec) flag(name string) *flagSpec {
+	for i := range spec.Flags {
+		if spec.Flags[i].Name == name {
+			return &spec.Flags[i]
+		}
+	}
+	return nil
+}
+
+// usageLine returns "name <args> [flags]" for help and error messages; the flags
+// themselves are listed by printCommandHelp.
+func (spec *commandSpec) usageLine() string {
+	parts := []string{spec.Name}
+	for _, arg := range spec.Args {
+		name := "<" + arg.Name + ">"
+		if arg.Variadic {
+			name = "<" + arg.Name + "...>"
+		}
+		if arg.Optional {
+			name = "[" + strings.Trim(name, "<>") + "]"
+		}
+		parts = append(parts, name)
+	}
+	if len(spec.Flags) > 0 {
+		parts = append(parts, "[flags]")
+	}
+	return strings.Join(parts, " ")
+}
+
+// usageError returns a usageError carrying the command's usage line.
+func (spec *commandSpec) usageError(format string, a ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, a...), usage: spec.usageLine()}
+}
+
+// usageError reports invalid arguments that parsing alone couldn't catch, such as a
+// value outside an allowed set.
+func (cmd command) usageError(format string, a ...any) error {
+	if cmd.spec == nil {
+		return &usageError{msg: fmt.Sprintf(format, a...)}
+	}
+	return cmd.spec.usageError(format, a...)
+}
+
+// flagValue returns the parsed value of a declared flag. Asking for a flag the command
+// didn't declare is a programming error.
+func (cmd command) flagValue(name string) any {
+	if cmd.flags != nil {
+		if f := cmd.flags.Lookup(name); f != nil {
+			return f.Value.(flag.Getter).Get()
+		}
+	}
+	panic(fmt.Sprintf("command %s has no flag --%s", cmd.Name, name))
+}
+
+// stringFlag, boolFlag and intFlag return a flag's value, or its default if not given.
+func (cmd command) stringFlag(name string) string { return cmd.flagValue(name).(string) }
+func (cmd command) boolFlag(name string) bool     { return cmd.flagValue(name).(bool) }
+func (cmd command) intFlag(name string) int       { return cmd.flagValue(name).(int) }
+
+// flagIsSet reports whether the flag was given on the command line.
+func (cmd command) flagIsSet(name string) bool {
+	set := false
+	if cmd.flags != nil {
+		cmd.flags.Visit(func(f *flag.Flag) {
+			set = set || f.Name == name
+		})
+	}
+	return set
+}
+
+// handlerHelp lists the commands, or describes one command in detail.
+func (cmdsPtr *cmds) handlerHelp(stPtr *state, cmd command) error {
+	if len(cmd.Args) == 0 {
+		cmdsPtr.printHelp(os.Stdout)
+		return nil
+	}
+
+	spec, ok := cmdsPtr.Specs[cmd.Args[0]]
+	if !ok || spec.Hidden {
+		return cmdsPtr.unknownCommand(cmd.Args[0])
+	}
+	cmdsPtr.printCommandHelp(os.Stdout, spec)
+	return nil
+}
+
+// unknownCommand returns the usage error for a command name that isn't registered (or
+// is hidden, for help), suggesting similar names.
+func (cmdsPtr *cmds) unknownCommand(name string) error {
+	msg := fmt.Sprintf("unknown command %q", name)
+	if suggestions := cmdsPtr.suggest(name); len(suggestions) > 0 {
+		msg += fmt.Sprintf("; did you mean %s?", strings.Join(suggestions, " or "))
+	}
+	return &usageError{msg: msg + "\nrun 'gator help' for a list of commands"}
+}
+
+// printHelp lists every visible command by group in registration order.
+func (cmdsPtr *cmds) printHelp(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gator [--output <format>] <command> [arguments]")
+	group := ""
+	for _, name := range cmdsPtr.Order {
+		spec := cmdsPtr.Specs[name]
+		if spec.Hidden {
+			continue
+		}
+		if spec.Group != group {
+			group = spec.Group
+			fmt.Fprintf(w, "\n%s:\n", group)
+		}
+		fmt.Fprintf(w, "  %-16s %s\n", spec.Name, spec.Summary)
+	}
+	fmt.Fprintln(w, "\nRun 'gator help <command>' for details on a command.")
+}
+
+// printCommandHelp describes one command: usage, description, arguments and flags.
+func (cmdsPtr *cmds) printCommandHelp(w io.Writer, spec *commandSpec) {
+	fmt.Fprintf(w, "Usage: gator %s\n\n%s\n", spec.usageLine(), spec.Summary)
+	if spec.Description != "" {
+		fmt.Fprintf(w, "\n%s\n", spec.Description)
+	}
+	if spec.UserHandler != nil {
+		fmt.Fprintln(w, "\nRequires a logged-in user.")
+	}
+	if len(spec.Flags) > 0 {
+		fmt.Fprintln(w, "\nFlags:")
+		for _, f := range spec.Flags {
+			name := "--" + f.Name
+			if f.Kind != flagBool {
+				name += " " + f.Arg
+			}
+			line := fmt.Sprintf("  %-22s %s", name, f.Usage)
+			if f.Default != "" && f.Default != "false" {
+				line += fmt.Sprintf(" (default %s)", f.Default)
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+}
+
+// suggest returns up to three visible command names close to an unknown one: names it
+// is a prefix of, or names within a small edit distance.
+func (cmdsPtr *cmds) suggest(name string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+	for _, known := range cmdsPtr.Order {
+		if cmdsPtr.Specs[known].Hidden {
+			continue
+		}
+		distance := editDistance(name, known)
+		if strings.HasPrefix(known, name) && len(name) >= 2 {
+			distance = 0
+		}
+		if distance <= 2 || distance <= len(known)/3 {
+			candidates = append(candidates, candidate{known, distance})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+
+	var names []string
+	for i := 0; i < len(candidates) && i < 3; i++ {
+		names = append(names, candidates[i].name)
+	}
+	return names
+}
+
+// editDistance is the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Shell completion works in two halves: "gator completion <shell>" prints a small
+// script for the shell to source, and that script calls the hidden "gator __complete"
+// command with the words typed so far whenever the user presses Tab. __complete uses
+// the command registry to work out what the last word is (a command, a flag, a flag's
+// value or a positional argument) and prints one candidate per line, optionally
+// followed by a tab and a description that zsh and fish show next to it.
+
+// completionScripts holds the script printed by "gator completion <shell>".
+var completionScripts = map[string]string{
+	"bash": `# bash completion for gator; load it with: source <(gator completion bash)
+_gator() {
+    local cur words cword
+    if declare -F _get_comp_words_by_ref >/dev/null; then
+        _get_comp_words_by_ref -n =: cur words cword
+    else
+        cur=${COMP_WORDS[COMP_CWORD]} words=("${COMP_WORDS[@]}") cword=$COMP_CWORD
+    fi
+    local IFS=$'\n'
+    COMPREPLY=($(command gator __complete "${words[@]:1:cword}" 2>/dev/null | cut -f1))
+    if declare -F __ltrim_colon_completions >/dev/null; then
+        __ltrim_colon_completions "$cur"
+    fi
+}
+complete -o default -F _gator gator
+`,
+	"zsh": `#compdef gator
+# zsh completion for gator; load it with: source <(gator completion zsh)
+_gator() {
+    local -a candidates
+    local line
+    for line in "${(@f)$(command gator __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+        [[ -n $line ]] || continue
+        candidates+=("${${line%%$'\t'*}//:/\\:}:${line#*$'\t'}")
+    done
+    if (( ${#candidates} )); then
+        _describe -t gator 'gator' candidates
+    else
+        _files
+    fi
+}
+compdef _gator gator
+`,
+	"fish": `# fish completion for gator; load it with: gator completion fish | source
+function __gator_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    command gator __complete $tokens[2..-1] 2>/dev/null
+end
+complete -c gator -f -a '(__gator_complete)'
+`,
+}
+
+// handlerCompletion prints the completion script for a shell.
+func handlerCompletion(stPtr *state, cmd command) error {
+	script, ok := completionScripts[cmd.Args[0]]
+	if !ok {
+		return cmd.usageError("unsupported shell %q, expected bash, zsh or fish", cmd.Args[0])
+	}
+	fmt.Print(script)
+	return nil
+}
+
+// completion is one candidate printed by __complete.
+type completion struct {
+	Value       string
+	Description string
+}
+
+// handlerComplete implements the hidden __complete command. Its arguments are the words
+// after "gator" up to and including the one being completed, which may be empty. It
+// never fails: a completion that can't be computed (no database, no logged-in user)
+// just offers nothing.
+func (cmdsPtr *cmds) handlerComplete(stPtr *state, cmd command) error {
+	words := cmd.Args
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+
+	for _, candidate := range cmdsPtr.complete(stPtr, words[:len(words)-1], current) {
+		if strings.HasPrefix(candidate.Value, current) {
+			fmt.Printf("%s\t%s\n", candidate.Value, candidate.Description)
+		}
+	}
+	return nil
+}
+
+// complete returns the candidates for current given the words before it. The caller
+// filters them by the prefix typed so far.
+func (cmdsPtr *cmds) complete(stPtr *state, before []string, current string) []completion {
+	// The global --output flag can appear anywhere; drop it and its value.
+	var words []string
+	for i := 0; i < len(before); i++ {
+		if before[i] == "--output" {
+			i++
+			continue
+		}
+		if !strings.HasPrefix(before[i], "--output=") {
+			words = append(words, before[i])
+		}
+	}
+	if len(before) > 0 && before[len(before)-1] == "--output" {
+		return fixedCompletions("text,table,json,ndjson,csv,template=")
+	}
+	if value, ok := strings.CutPrefix(current, "--output="); ok {
+		return prefixCompletions("--output=", fixedCompletions("text,table,json,ndjson,csv,template="), value)
+	}
+
+	if len(words) == 0 {
+		if strings.HasPrefix(current, "-") {
+			return []completion{{Value: "--output", Description: "output format for listing commands"}}
+		}
+		return cmdsPtr.completionSource(stPtr, "commands")
+	}
+
+	spec, ok := cmdsPtr.Specs[words[0]]
+	if !ok || spec.Hidden {
+		return nil
+	}
+
+	// Walk the words after the command name to find out how many positional arguments
+	// came before current and whether current is a flag's value.
+	positional, flagsDone := 0, false
+	var valueOf *flagSpec
+	for _, word := range words[1:] {
+		if valueOf != nil {
+			valueOf = nil
+			continue
+		}
+		if word == "--" {
+			flagsDone = true
+			continue
+		}
+		if name, hasValue := flagName(word); name != "" && !flagsDone {
+			if f := spec.flag(name); f != nil {
+				if f.Kind != flagBool && !hasValue {
+					valueOf = f
+				}
+				continue
+			}
+			if !spec.DashArgs {
+				continue
+			}
+		}
+		positional++
+	}
+
+	if valueOf != nil {
+		return cmdsPtr.completionSource(stPtr, valueOf.Complete)
+	}
+	if strings.HasPrefix(current, "-") && !flagsDone {
+		if name, value, ok := strings.Cut(strings.TrimLeft(current, "-"), "="); ok {
+			if f := spec.flag(name); f != nil {
+				return prefixCompletions("--"+name+"=", cmdsPtr.completionSource(stPtr, f.Complete), value)
+			}
+			return nil
+		}
+		var candidates []completion
+		for _, f := range spec.Flags {
+			candidates = append(candidates, completion{Value: "--" + f.Name, Description: f.Usage})
+		}
+		return append(candidates, completion{Value: "--help", Description: "show help for " + spec.Name})
+	}
+
+	if len(spec.Args) == 0 {
+		return nil
+	}
+	if positional >= len(spec.Args) {
+		last := spec.Args[len(spec.Args)-1]
+		if !last.Variadic {
+			return nil
+		}
+		return cmdsPtr.completionSource(stPtr, last.Complete)
+	}
+	return cmdsPtr.completionSource(stPtr, spec.Args[positional].Complete)
+}
+
+// completionSource returns the candidates for an argSpec or flagSpec Complete value:
+// "commands", "feeds" (feed URLs, described by name), "folders", "tags" and "users"
+// look them up, and a comma-separated list such as "on,off" is offered as is.
+func (cmdsPtr *cmds) completionSource(stPtr *state, source string) []completion {
+	ctx := context.Background()
+	var candidates []completion
+
+	switch source {
+	case "":
+		return nil
+
+	case "commands":
+		for _, name := range cmdsPtr.Order {
+			if spec := cmdsPtr.Specs[name]; !spec.Hidden {
+				candidates = append(candidates, completion{Value: name, Description: spec.Summary})
+			}
+		}
+
+	case "feeds":
+		feeds, err := stPtr.dbPtr.GetFeeds(ctx)
+		if err != nil {
+			return nil
+		}
+		for _, feed := range feeds {
+			candidates = append(candidates, completion{Value: feed.Url, Description: feed.Name})
+		}
+
+	case "users":
+		users, err := stPtr.dbPtr.GetUsers(ctx)
+		if err != nil {
+			return nil
+		}
+		for _, user := range users {
+			if user.Valid {
+				candidates = append(candidates, completion{Value: user.String})
+			}
+		}
+
+	case "folders", "tags":
+		if stPtr.cfgPtr.CurrentUserName == "" {
+			return nil
+		}
+		user, err := stPtr.dbPtr.GetUser(ctx, sql.NullString{String: stPtr.cfgPtr.CurrentUserName, Valid: true})
+		if err != nil {
+			return nil
+		}
+		if source == "folders" {
+			folders, err := stPtr.dbPtr.GetFoldersForUser(ctx, user.ID)
+			if err != nil {
+				return nil
+			}
+			for _, folder := range folders {
+				candidates = append(candidates, completion{Value: folder.Name})
+			}
+		} else {
+			tags, err := stPtr.dbPtr.GetTagsForUser(ctx, user.ID)
+			if err != nil {
+				return nil
+			}
+			for _, tag := range tags {
+				candidates = append(candidates, completion{Value: tag.Name, Description: fmt.Sprintf("%d posts", tag.PostCount)})
+			}
+		}
+
+	default:
+		candidates = fixedCompletions(source)
+	}
+	return candidates
+}
+
+// fixedCompletions turns a comma-separated list of values into candidates.
+func fixedCompletions(values string) []completion {
+	var candidates []completion
+	for _, value := range strings.Split(values, ",") {
+		candidates = append(candidates, completion{Value: value})
+	}
+	return candidates
+}
+
+// prefixCompletions completes the value part of "--flag=value": it keeps the candidates
+// matching value and puts prefix in front of them, since the shell completes the word
+// as a whole.
+func prefixCompletions(prefix string, candidates []completion, value string) []completion {
+	var prefixed []completion
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate.Value, value) {
+			prefixed = append(prefixed, completion{Value: prefix + candidate.Value, Description: candidate.Description})
+		}
+	}
+	return prefixed
+}
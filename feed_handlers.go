@@ -2,15 +2,18 @@ package main
 
 import (
     "context"
+    "database/sql"
     "encoding/xml"
     "errors"
     "fmt"
     "html"
     "io"
+    "math"
     "net/http"
     "time"
 
     "github.com/Marcus-Gustafsson/gator/internal/database"
+    "github.com/Marcus-Gustafsson/gator/internal/readability"
     "github.com/google/uuid"
 )
 
@@ -47,11 +50,19 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error){
         return nil, fmt.Errorf("error reading response body: %w", err)
     }
 
+    return parseFeed(body)
+}
+
+// parseFeed unmarshals raw RSS XML into an RSSFeed struct and unescapes HTML entities
+// in the text fields. It is shared by fetchFeed and the WebSub callback, which receives
+// the same XML pushed by a hub instead of polling for it.
+func parseFeed(body []byte) (*RSSFeed, error) {
+
     // Create a placeholder pointer variable for the struct that will hold the data in the response body
     rssFeedPtr := &RSSFeed{}
 
     // Use xml.unmarshal due to struct expecting xml formatting (not JSON in this case.)
-    err = xml.Unmarshal(body, rssFeedPtr)
+    err := xml.Unmarshal(body, rssFeedPtr)
     if err != nil{
         return nil, fmt.Errorf("error unmarshaling response body into RSSFeed struct: %w", err)
     }
@@ -68,49 +79,107 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error){
     for i := range rssFeedPtr.Channel.Item {
         rssFeedPtr.Channel.Item[i].Title = html.UnescapeString(rssFeedPtr.Channel.Item[i].Title)
         rssFeedPtr.Channel.Item[i].Description = html.UnescapeString(rssFeedPtr.Channel.Item[i].Description)
+        rssFeedPtr.Channel.Item[i].Creator = html.UnescapeString(rssFeedPtr.Channel.Item[i].Creator)
+        rssFeedPtr.Channel.Item[i].Author = html.UnescapeString(rssFeedPtr.Channel.Item[i].Author)
+        for j, category := range rssFeedPtr.Channel.Item[i].Categories {
+            rssFeedPtr.Channel.Item[i].Categories[j] = html.UnescapeString(category)
+        }
     }
 
     return rssFeedPtr, nil
 }
 
+// fetchArticle downloads the web page a post links to and extracts its main article
+// body with the readability package. Used for feeds that only publish a short
+// description. Returns the cleaned article HTML, or an error if the page can't be
+// fetched or no article content is found.
+func fetchArticle(ctx context.Context, pageURL string) (string, error) {
+
+    client := &http.Client{
+        Timeout: time.Second * 10,
+    }
+
+    request, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+    if err != nil {
+        return "", fmt.Errorf("error creating request: %w", err)
+    }
+    request.Header.Add("User-Agent", "gator")
+
+    response, err := client.Do(request)
+    if err != nil {
+        return "", fmt.Errorf("error sending the request: %w", err)
+    }
+    defer response.Body.Close()
+
+    if response.StatusCode != http.StatusOK {
+        return "", fmt.Errorf("unexpected response status: %s", response.Status)
+    }
+
+    // Pages can be large; cap what we read so one huge page can't stall the aggregator.
+    // Redirects are followed by the client, so resolve links against the final URL.
+    article, err := readability.Extract(io.LimitReader(response.Body, 5<<20), response.Request.URL)
+    if err != nil {
+        return "", fmt.Errorf("error extracting article: %w", err)
+    }
+
+    return article.HTML, nil
+}
+
+// atomLink returns the href of the first channel-level <atom:link> with the given
+// rel (e.g. "hub" or "self"), or an empty string if the feed doesn't advertise one.
+func (feedPtr *RSSFeed) atomLink(rel string) string {
+    for _, link := range feedPtr.Channel.AtomLinks {
+        if link.Rel == rel && link.Href != "" {
+            return link.Href
+        }
+    }
+    return ""
+}
+
 // handlerAddFeed creates a new RSS feed record in the database, associated with the
 // provided user (obtained through middleware). It expects exactly two arguments: 
 // the feed's name and its URL. On success, prints the new feed's details and 
 // automatically creates a feed follow relationship. Returns an error if feed
-// creation or feed follow creation fails, or if arguments are missing.
+// creation or feed follow creation fails.
 func handlerAddFeed(stPtr *state, cmd command, currentUser database.User) error {
 
-    if len(cmd.Args) != 2 {
-        return errors.New("handlerAddFeed: expects two arguments: the feed's name and URL")
-    }
+    // The feed and its follow are created together, so a failed follow doesn't leave
+    // the user with a feed they don't follow.
+    var newFeed database.Feed
+    var feedFollow database.CreateFeedFollowRow
+    err := stPtr.withTx(context.Background(), func(txPtr *state) error {
+        var err error
+        newFeed, err = txPtr.dbPtr.CreateFeed(
+            context.Background(),
+            database.CreateFeedParams{
+                ID:        uuid.New(),
+                CreatedAt: time.Now().UTC(),
+                UpdatedAt: time.Now().UTC(),
+                Name:      cmd.Args[0],
+                Url:       cmd.Args[1],
+                UserID:    uuid.NullUUID{UUID: currentUser.ID, Valid: true},
+            },
+        )
+        if err != nil {
+            return fmt.Errorf("handlerAddFeed: failed to create new feed: %w", err)
+        }
 
-    newFeed, err := stPtr.dbPtr.CreateFeed(
-        context.Background(),
-        database.CreateFeedParams{
+        feedFollow, err = txPtr.dbPtr.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
             ID:        uuid.New(),
-            CreatedAt: time.Now(),
-            UpdatedAt: time.Now(),
-            Name:      cmd.Args[0],
-            Url:       cmd.Args[1],
-            UserID:    uuid.NullUUID{UUID: currentUser.ID, Valid: true},
-        },
-    )
+            CreatedAt: time.Now().UTC(),
+            UpdatedAt: time.Now().UTC(),
+            UserID:    currentUser.ID,
+            FeedID:    newFeed.ID,
+        })
+        if err != nil {
+            return fmt.Errorf("handlerAddFeed: couldn't create feed follow: %w", err)
+        }
+        return nil
+    })
     if err != nil {
-        return fmt.Errorf("handlerAddFeed: failed to create new feed: %w", err)
+        return err
     }
 
-
-    feedFollow, err := stPtr.dbPtr.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
-    ID:        uuid.New(),
-    CreatedAt: time.Now(),
-    UpdatedAt: time.Now(),
-    UserID:    currentUser.ID,
-    FeedID:    newFeed.ID,
-	})
-	if err != nil {
-		return fmt.Errorf("handlerAddFeed: couldn't create feed follow: %w", err)
-	}
-
 	fmt.Println("Feed created successfully:")
 
     fmt.Println("DBG: New feed has been created:")
@@ -138,17 +207,41 @@ func handlerGetFeeds(stPtr *state, cmd command) error{
         return errors.New("handlerGetFeeds: no feeds in the retrieved feed slice")
     }
 
-    for _, feed := range feeds{
+    feedUsers := make([]database.User, len(feeds))
+    items := make([]feedItem, len(feeds))
+    for i, feed := range feeds{
 
         feedUser, err := stPtr.dbPtr.GetUserByUUID(context.Background(), feed.UserID.UUID)
         if err != nil {
             return fmt.Errorf("handlerGetFeeds: couldn't retrieve user with uuid from 'users' table: %w", err)  
         }
-        printFeed(feed, feedUser)
-		fmt.Println("=====================================")
+        feedUsers[i] = feedUser
+        items[i] = feedItem{
+            ID:            feed.ID,
+            Name:          feed.Name,
+            URL:           feed.Url,
+            User:          feedUser.Name.String,
+            CreatedAt:     feed.CreatedAt,
+            LastFetchedAt: nullTime(feed.LastFetchedAt),
+        }
     }
 
-    return nil
+    return stPtr.outPtr.Print(items, func() {
+        for i, feed := range feeds{
+            printFeed(feed, feedUsers[i])
+            fmt.Println("=====================================")
+        }
+    })
+}
+
+// feedItem is a feed as listed by the feeds command in structured output.
+type feedItem struct {
+    ID            uuid.UUID  `json:"id"`
+    Name          string     `json:"name"`
+    URL           string     `json:"url"`
+    User          string     `json:"user"`
+    CreatedAt     time.Time  `json:"created_at"`
+    LastFetchedAt *time.Time `json:"last_fetched_at"`
 }
 
 // handlerFollow creates a feed follow relationship between the provided user
@@ -157,10 +250,7 @@ func handlerGetFeeds(stPtr *state, cmd command) error{
 // and feed names. Returns an error if the feed URL is not found or feed follow 
 // creation fails.
 func handlerFollow(stPtr *state, cmd command, currentUser database.User) error {
-    // Check argument count first, like other handlers
-    if len(cmd.Args) != 1 {
-        return errors.New("handlerFollow: expects a single argument, the feed URL")
-    }
+    folderName := cmd.stringFlag("folder")
 
     // Get feed by URL with proper error wrapping
     feed, err := stPtr.dbPtr.GetFeedByURL(context.Background(), cmd.Args[0])
@@ -168,13 +258,22 @@ func handlerFollow(stPtr *state, cmd command, currentUser database.User) error {
         return fmt.Errorf("handlerFollow: couldn't get feed by URL: %w", err)
     }
 
+    var folderID uuid.NullUUID
+    if folderName != "" {
+        folderID, err = getFolderID(stPtr, currentUser, folderName)
+        if err != nil {
+            return fmt.Errorf("handlerFollow: %w", err)
+        }
+    }
+
     // Create feed follow with proper error wrapping
     feedFollow, err := stPtr.dbPtr.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
         ID:        uuid.New(),
-        CreatedAt: time.Now(),
-        UpdatedAt: time.Now(),
+        CreatedAt: time.Now().UTC(),
+        UpdatedAt: time.Now().UTC(),
         UserID:    currentUser.ID,
         FeedID:    feed.ID,
+        FolderID:  folderID,
     })
     if err != nil {
         return fmt.Errorf("handlerFollow: couldn't create feed follow: %w", err)
@@ -183,34 +282,72 @@ func handlerFollow(stPtr *state, cmd command, currentUser database.User) error {
     // Print success message
     fmt.Println("Feed follow created:")
     printFeedFollow(feedFollow.UserName.String, feedFollow.FeedName)
+    if folderName != "" {
+        fmt.Printf("* Folder:        %s\n", folderName)
+    }
     return nil
 }
 
 
 // handlerListFeedFollows retrieves and displays all feeds that the provided user
-// (obtained through middleware) is following. It prints the user's name and a 
-// list of followed feed names. If no feeds are being followed, displays an 
-// appropriate message. Returns an error if feed follow retrieval fails.
+// (obtained through middleware) is following, by the user's own title where set and
+// grouped under the user's folders with feeds outside any folder listed first. Empty
+// folders are listed too. If no feeds are being followed, displays an appropriate
+// message. Returns an error if feed follow or folder retrieval fails.
 func handlerListFeedFollows(stPtr *state, cmd command, currentUser database.User) error {
     // Get feed follows with proper error wrapping
     feedFollows, err := stPtr.dbPtr.GetFeedFollowsForUser(context.Background(), currentUser.ID)
     if err != nil {
         return fmt.Errorf("handlerListFeedFollows: couldn't retrieve feed follows: %w", err)
     }
+    folders, err := stPtr.dbPtr.GetFoldersForUser(context.Background(), currentUser.ID)
+    if err != nil {
+        return fmt.Errorf("handlerListFeedFollows: couldn't retrieve folders: %w", err)
+    }
 
     // Handle empty case
-    if len(feedFollows) == 0 {
+    if len(feedFollows) == 0 && len(folders) == 0 && stPtr.outPtr.IsText() {
         fmt.Println("No feed follows found for this user.")
         return nil
     }
 
-    // Print results
-    fmt.Printf("Feed follows for user %s:\n", currentUser.Name.String)
-    for _, ff := range feedFollows {
-        fmt.Printf("* %s\n", ff.FeedName)
+    items := make([]followItem, len(feedFollows))
+    for i, ff := range feedFollows {
+        items[i] = followItem{
+            FeedID:   ff.FeedID,
+            Feed:     ff.FeedName,
+            Folder:   ff.FolderName.String,
+            Muted:    ff.Muted,
+            Notify:   ff.Notify,
+            Priority: ff.Priority,
+        }
     }
 
-    return nil
+    return stPtr.outPtr.Print(items, func() {
+        // Print results; follows come sorted by folder name, as do the folders.
+        fmt.Printf("Feed follows for user %s:\n", currentUser.Name.String)
+        for len(feedFollows) > 0 && !feedFollows[0].FolderName.Valid {
+            printFollowedFeed(feedFollows[0], "")
+            feedFollows = feedFollows[1:]
+        }
+        for _, folder := range folders {
+            fmt.Printf("%s/\n", folder.Name)
+            for len(feedFollows) > 0 && feedFollows[0].FolderID.UUID == folder.ID {
+                printFollowedFeed(feedFollows[0], "    ")
+                feedFollows = feedFollows[1:]
+            }
+        }
+    })
+}
+
+// followItem is a followed feed as listed by the following command in structured output.
+type followItem struct {
+    FeedID   uuid.UUID `json:"feed_id"`
+    Feed     string    `json:"feed"`
+    Folder   string    `json:"folder"`
+    Muted    bool      `json:"muted"`
+    Notify   bool      `json:"notify"`
+    Priority int32     `json:"priority"`
 }
 
 
@@ -219,10 +356,6 @@ func handlerListFeedFollows(stPtr *state, cmd command, currentUser database.User
 // one argument: the feed's URL. On success, prints confirmation with the feed
 // name. Returns an error if the feed URL is not found or feed follow deletion fails.
 func handlerUnfollow(stPtr *state, cmd command, user database.User) error {
-	if len(cmd.Args) != 1 {
-		return fmt.Errorf("usage: %s <feed_url>", cmd.Name)
-	}
-
 	feed, err := stPtr.dbPtr.GetFeedByURL(context.Background(), cmd.Args[0])
 	if err != nil {
 		return fmt.Errorf("handlerUnfollow: couldn't get feed: %w", err)
@@ -238,4 +371,107 @@ func handlerUnfollow(stPtr *state, cmd command, user database.User) error {
 
 	fmt.Printf("%s unfollowed successfully!\n", feed.Name)
 	return nil
-}
\ No newline at end of file
+}
+
+
+// handlerFullContent turns full-text extraction on or off for a feed. It expects two
+// arguments: the feed's URL and "on" or "off". When on, the aggregator downloads the
+// page behind every new post of the feed and stores the extracted article, which browse
+// then shows instead of the feed's (often one-line) description. The setting belongs to
+// the feed, so it applies to every user following it, and only the user who added the
+// feed can change it. Returns an error if arguments are invalid, the feed is not found,
+// the user didn't add it, or the update fails.
+func handlerFullContent(stPtr *state, cmd command, user database.User) error {
+	if cmd.Args[1] != "on" && cmd.Args[1] != "off" {
+		return cmd.usageError("expected on or off, got %q", cmd.Args[1])
+	}
+
+	feed, err := stPtr.dbPtr.GetFeedByURL(context.Background(), cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("handlerFullContent: couldn't get feed: %w", err)
+	}
+	if !feed.UserID.Valid || feed.UserID.UUID != user.ID {
+		return fmt.Errorf("handlerFullContent: only the user who added %s can change full content fetching", feed.Name)
+	}
+
+	feed, err = stPtr.dbPtr.SetFeedFetchFullContent(context.Background(), database.SetFeedFetchFullContentParams{
+		ID:               feed.ID,
+		FetchFullContent: cmd.Args[1] == "on",
+		UpdatedAt:        time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("handlerFullContent: couldn't update feed: %w", err)
+	}
+
+	fmt.Printf("Full content fetching for %s is now %s.\n", feed.Name, cmd.Args[1])
+	return nil
+}
+
+// handlerFeedSettings shows or changes the current user's own settings for a feed they
+// follow: a display title used instead of the feed's shared name, whether the feed is
+// muted (left out of browse unless asked for with --feed), whether the user wants to be
+// notified of new posts (only recorded, as nothing sends notifications yet), and a
+// priority ordering the following list (higher first). Only the given
+// settings change; with no flags the current settings are printed. Returns an error if
+// the flags are invalid, the feed is not found, or the user doesn't follow it.
+func handlerFeedSettings(stPtr *state, cmd command, user database.User) error {
+	title, clearTitle := cmd.stringFlag("title"), cmd.boolFlag("clear-title")
+	mute, unmute := cmd.boolFlag("mute"), cmd.boolFlag("unmute")
+	notify := cmd.stringFlag("notify")
+	if mute && unmute {
+		return cmd.usageError("use either --mute or --unmute, not both")
+	}
+	if title != "" && clearTitle {
+		return cmd.usageError("use either --title or --clear-title, not both")
+	}
+	if notify != "" && notify != "on" && notify != "off" {
+		return cmd.usageError("--notify expects on or off, got %q", notify)
+	}
+	priority := cmd.intFlag("priority")
+	if priority < math.MinInt32 || priority > math.MaxInt32 {
+		return cmd.usageError("--priority must be between %d and %d", math.MinInt32, math.MaxInt32)
+	}
+
+	feed, err := stPtr.dbPtr.GetFeedByURL(context.Background(), cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("handlerFeedSettings: couldn't get feed: %w", err)
+	}
+
+	params := database.UpdateFeedFollowSettingsParams{
+		UserID:     user.ID,
+		FeedID:     feed.ID,
+		ClearTitle: clearTitle,
+		UpdatedAt:  time.Now().UTC(),
+	}
+	if title != "" {
+		params.Title = sql.NullString{String: title, Valid: true}
+	}
+	if mute || unmute {
+		params.Muted = sql.NullBool{Bool: mute, Valid: true}
+	}
+	if notify != "" {
+		params.Notify = sql.NullBool{Bool: notify == "on", Valid: true}
+	}
+	if cmd.flagIsSet("priority") {
+		params.Priority = sql.NullInt32{Int32: int32(priority), Valid: true}
+	}
+
+	feedFollow, err := stPtr.dbPtr.UpdateFeedFollowSettings(context.Background(), params)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("handlerFeedSettings: you don't follow %s", feed.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("handlerFeedSettings: couldn't update feed settings: %w", err)
+	}
+
+	displayTitle := feed.Name
+	if feedFollow.Title.Valid {
+		displayTitle = feedFollow.Title.String
+	}
+	fmt.Printf("Settings for %s:\n", feed.Url)
+	fmt.Printf("* Title:         %s\n", displayTitle)
+	fmt.Printf("* Muted:         %t\n", feedFollow.Muted)
+	fmt.Printf("* Notify:        %t\n", feedFollow.Notify)
+	fmt.Printf("* Priority:      %d\n", feedFollow.Priority)
+	return nil
+}
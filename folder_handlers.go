@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerFolder manages the current user's folders. "create" adds an empty folder,
+// "rename" renames one, and "delete" removes one; the feeds that were in a deleted folder
+// stay followed but are no longer filed anywhere. Feeds are put into folders with
+// follow --folder or move. Returns an error if the subcommand or its arguments are
+// invalid or the folder doesn't exist.
+func handlerFolder(stPtr *state, cmd command, user database.User) error {
+	switch args := cmd.Args[1:]; cmd.Args[0] {
+	case "create":
+		if len(args) != 1 || args[0] == "" {
+			return cmd.usageError("create takes a folder name")
+		}
+		folder, err := stPtr.dbPtr.CreateFolder(context.Background(), database.CreateFolderParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			UserID:    user.ID,
+			Name:      args[0],
+		})
+		if err != nil {
+			return fmt.Errorf("handlerFolder: couldn't create folder: %w", err)
+		}
+		fmt.Printf("Folder %s created.\n", folder.Name)
+
+	case "rename":
+		if len(args) != 2 || args[1] == "" {
+			return cmd.usageError("rename takes the folder name and its new name")
+		}
+		renamed, err := stPtr.dbPtr.RenameFolder(context.Background(), database.RenameFolderParams{
+			UserID:    user.ID,
+			Name:      args[0],
+			NewName:   args[1],
+			UpdatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return fmt.Errorf("handlerFolder: couldn't rename folder: %w", err)
+		}
+		if renamed == 0 {
+			return fmt.Errorf("handlerFolder: no folder named %q", args[0])
+		}
+		fmt.Printf("Folder %s renamed to %s.\n", args[0], args[1])
+
+	case "delete":
+		if len(args) != 1 {
+			return cmd.usageError("delete takes a folder name")
+		}
+		deleted, err := stPtr.dbPtr.DeleteFolder(context.Background(), database.DeleteFolderParams{
+			UserID: user.ID,
+			Name:   args[0],
+		})
+		if err != nil {
+			return fmt.Errorf("handlerFolder: couldn't delete folder: %w", err)
+		}
+		if deleted == 0 {
+			return fmt.Errorf("handlerFolder: no folder named %q", args[0])
+		}
+		fmt.Printf("Folder %s deleted; its feeds are still followed.\n", args[0])
+
+	default:
+		return cmd.usageError("unknown subcommand %q, expected create, rename or delete", cmd.Args[0])
+	}
+	return nil
+}
+
+// handlerMove files a followed feed into one of the user's folders, or takes it out of
+// its folder with --unfiled. It expects the feed's URL followed by the folder name.
+// Returns an error if the feed or folder is not found or the user doesn't follow the feed.
+func handlerMove(stPtr *state, cmd command, user database.User) error {
+	unfiled, args := cmd.boolFlag("unfiled"), cmd.Args
+	if unfiled && len(args) != 1 {
+		return cmd.usageError("--unfiled takes no folder")
+	}
+	if !unfiled && len(args) != 2 {
+		return cmd.usageError("expected a folder, or --unfiled")
+	}
+
+	feed, err := stPtr.dbPtr.GetFeedByURL(context.Background(), args[0])
+	if err != nil {
+		return fmt.Errorf("handlerMove: couldn't get feed: %w", err)
+	}
+
+	var folderID uuid.NullUUID
+	if !unfiled {
+		folderID, err = getFolderID(stPtr, user, args[1])
+		if err != nil {
+			return fmt.Errorf("handlerMove: %w", err)
+		}
+	}
+
+	moved, err := stPtr.dbPtr.SetFeedFollowFolder(context.Background(), database.SetFeedFollowFolderParams{
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+		FolderID:  folderID,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("handlerMove: couldn't move feed: %w", err)
+	}
+	if moved == 0 {
+		return fmt.Errorf("handlerMove: you don't follow %s", feed.Name)
+	}
+
+	if unfiled {
+		fmt.Printf("%s is no longer in a folder.\n", feed.Name)
+	} else {
+		fmt.Printf("%s moved to %s.\n", feed.Name, args[1])
+	}
+	return nil
+}
+
+// getFolderID looks up one of the user's folders by name.
+func getFolderID(stPtr *state, user database.User, name string) (uuid.NullUUID, error) {
+	folder, err := stPtr.dbPtr.GetFolderByName(context.Background(), database.GetFolderByNameParams{
+		UserID: user.ID,
+		Name:   name,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.NullUUID{}, fmt.Errorf("no folder named %q (create it with \"folder create\")", name)
+	}
+	if err != nil {
+		return uuid.NullUUID{}, fmt.Errorf("couldn't get folder: %w", err)
+	}
+	return uuid.NullUUID{UUID: folder.ID, Valid: true}, nil
+}
@@ -0,0 +1,1480 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Marcus-Gustafsson/gator/internal/config"
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/Marcus-Gustafsson/gator/internal/memdb"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+)
+
+// newFeedEnv returns an environment with alice logged in, following the tech and news
+// feeds, both fetched once.
+func newFeedEnv(t *testing.T) *testEnv {
+	t.Helper()
+	env := newTestEnv(t)
+	env.mustRun("register", "alice")
+	env.mustRun("addfeed", "Tech", env.url(techFeedPath))
+	env.mustRun("addfeed", "News", env.url(newsFeedPath))
+	env.scrapeAll()
+	return env
+}
+
+// titles returns the titles of posts, in order.
+func titles(posts []postItem) []string {
+	var list []string
+	for _, post := range posts {
+		list = append(list, post.Title)
+	}
+	return list
+}
+
+// assertTitles fails the test unless posts have exactly the wanted titles, in order.
+func assertTitles(t *testing.T, posts []postItem, want ...string) {
+	t.Helper()
+	if got := titles(posts); !slices.Equal(got, want) {
+		t.Errorf("got posts %q, want %q", got, want)
+	}
+}
+
+// assertUsageError fails the test unless err is a usage error.
+func assertUsageError(t *testing.T, err error) {
+	t.Helper()
+	if exitCode(err) != exitUsage {
+		t.Errorf("got %v (exit code %d), want a usage error", err, exitCode(err))
+	}
+}
+
+func TestUserCommands(t *testing.T) {
+	env := newTestEnv(t)
+
+	out := env.mustRun("register", "alice")
+	assertContains(t, out, "User created successfully", "alice")
+	env.mustRun("register", "bob")
+	if err := env.mustFail("register", "bob"); !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("registering twice: got %v", err)
+	}
+
+	assertContains(t, env.mustRun("users"), "* alice\n", "* bob (current)\n")
+	env.mustRun("login", "alice")
+	assertContains(t, env.mustRun("users"), "* alice (current)\n", "* bob\n")
+	if err := env.mustFail("login", "carol"); !strings.Contains(err.Error(), "no user named") {
+		t.Errorf("logging in as an unknown user: got %v", err)
+	}
+
+	// The current user is saved in the config file in the home directory.
+	cfg, err := config.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CurrentUserName != "alice" {
+		t.Errorf("config has current user %q, want alice", cfg.CurrentUserName)
+	}
+
+	var users []userItem
+	env.runJSON(&users, "users")
+	if len(users) != 2 || users[0] != (userItem{Name: "alice", Current: true}) {
+		t.Errorf("users as JSON: got %+v", users)
+	}
+
+	assertContains(t, env.mustRun("reset"), "reset successfully")
+	if out := env.mustRun("users"); out != "" {
+		t.Errorf("users after reset: got %q", out)
+	}
+	if _, err := env.run("following"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("following as a deleted user: got %v, want sql.ErrNoRows", err)
+	}
+
+	assertUsageError(t, env.mustFail("register"))
+	assertUsageError(t, env.mustFail("login", "a", "b"))
+}
+
+func TestCommandsNeedCurrentUser(t *testing.T) {
+	env := newTestEnv(t)
+	for _, args := range [][]string{{"browse"}, {"following"}, {"tags"}, {"addfeed", "x", "http://x"}} {
+		if err := env.mustFail(args...); !strings.Contains(err.Error(), "no current user") {
+			t.Errorf("%s without a user: got %v", args[0], err)
+		}
+	}
+}
+
+func TestFeedCommands(t *testing.T) {
+	env := newTestEnv(t)
+	env.mustRun("register", "alice")
+
+	if err := env.mustFail("feeds"); !strings.Contains(err.Error(), "no feeds") {
+		t.Errorf("feeds without feeds: got %v", err)
+	}
+	out := env.mustRun("addfeed", "Tech", env.url(techFeedPath))
+	assertContains(t, out, "Feed created successfully", "* Name:          Tech", "Feed followed successfully", "* Feed:          Tech")
+	env.mustFail("addfeed", "Tech again", env.url(techFeedPath))
+	env.mustRun("addfeed", "News", env.url(newsFeedPath))
+
+	var feeds []feedItem
+	env.runJSON(&feeds, "feeds")
+	if len(feeds) != 2 || feeds[0].Name != "Tech" || feeds[0].User != "alice" || feeds[0].LastFetchedAt != nil {
+		t.Errorf("feeds as JSON: got %+v", feeds)
+	}
+
+	env.mustRun("register", "bob")
+	if err := env.mustFail("follow", env.url(techFeedPath), "--folder", "Work"); !strings.Contains(err.Error(), "no folder named") {
+		t.Errorf("following into a missing folder: got %v", err)
+	}
+	env.mustRun("folder", "create", "Work")
+	assertContains(t, env.mustRun("follow", env.url(techFeedPath), "--folder", "Work"), "Feed follow created", "* User:          bob", "* Folder:        Work")
+	env.mustRun("follow", env.url(newsFeedPath))
+	env.mustFail("follow", env.url(newsFeedPath))
+	env.mustFail("follow", env.url("/missing.xml"))
+	assertContains(t, env.mustRun("following"), "Feed follows for user bob:\n* News\nWork/\n    * Tech\n")
+
+	assertContains(t, env.mustRun("unfollow", env.url(newsFeedPath)), "News unfollowed successfully")
+	var follows []followItem
+	env.runJSON(&follows, "following")
+	if len(follows) != 1 || follows[0].Feed != "Tech" || follows[0].Folder != "Work" {
+		t.Errorf("following as JSON: got %+v", follows)
+	}
+	env.mustRun("unfollow", env.url(techFeedPath))
+	assertContains(t, env.mustRun("following"), "Feed follows for user bob:\nWork/\n")
+	env.mustRun("folder", "delete", "Work")
+	assertContains(t, env.mustRun("following"), "No feed follows found")
+
+	assertUsageError(t, env.mustFail("fullcontent", env.url(techFeedPath), "maybe"))
+	// Only the user who added a feed can change what is downloaded for its followers.
+	if err := env.mustFail("fullcontent", env.url(techFeedPath), "on"); !strings.Contains(err.Error(), "only the user who added") {
+		t.Errorf("fullcontent by a follower: got %v", err)
+	}
+	env.mustRun("login", "alice")
+	assertContains(t, env.mustRun("fullcontent", env.url(techFeedPath), "on"), "Full content fetching for Tech is now on")
+	feed, err := env.store.GetFeedByURL(context.Background(), env.url(techFeedPath))
+	if err != nil || !feed.FetchFullContent {
+		t.Errorf("fullcontent on: got %+v, %v", feed, err)
+	}
+}
+
+func TestFeedSettings(t *testing.T) {
+	env := newFeedEnv(t)
+	tech := env.url(techFeedPath)
+
+	assertContains(t, env.mustRun("feed-settings", tech), "* Title:         Tech", "* Muted:         false", "* Priority:      0")
+	out := env.mustRun("feed-settings", tech, "--title", "Gadgets", "--notify", "on", "--priority", "5")
+	assertContains(t, out, "* Title:         Gadgets", "* Notify:        true", "* Priority:      5")
+	assertContains(t, env.mustRun("following"), "* Gadgets (notify, priority 5)\n* News\n")
+	assertTitles(t, env.browse("--feed", tech, "1"), "Rust borrow checker tips")
+	if post := env.browse("--feed", tech, "1")[0]; post.Feed != "Gadgets" {
+		t.Errorf("browse shows feed %q, want the custom title", post.Feed)
+	}
+
+	// Muted feeds are left out of browse unless asked for by URL.
+	env.mustRun("feed-settings", tech, "--mute")
+	assertTitles(t, env.browse("10"), "Go 1.30 is out")
+	assertTitles(t, env.browse("10", "--feed", tech), "Rust borrow checker tips", "Go 1.30 released", "Undated musings")
+	env.mustRun("feed-settings", tech, "--unmute", "--clear-title")
+	assertContains(t, env.mustRun("feed-settings", tech), "* Title:         Tech", "* Muted:         false", "* Priority:      5")
+
+	assertUsageError(t, env.mustFail("feed-settings", tech, "--mute", "--unmute"))
+	assertUsageError(t, env.mustFail("feed-settings", tech, "--title", "x", "--clear-title"))
+	assertUsageError(t, env.mustFail("feed-settings", tech, "--notify", "sometimes"))
+	assertUsageError(t, env.mustFail("feed-settings", tech, "--priority", "3000000000"))
+	assertUsageError(t, env.mustFail("feed-settings", tech, "--priority", "-3000000000"))
+	env.mustRun("unfollow", tech)
+	if err := env.mustFail("feed-settings", tech); !strings.Contains(err.Error(), "you don't follow Tech") {
+		t.Errorf("settings of an unfollowed feed: got %v", err)
+	}
+}
+
+func TestAggIngestsFeeds(t *testing.T) {
+	env := newTestEnv(t)
+	env.mustRun("register", "alice")
+	env.mustRun("addfeed", "Tech", env.url(techFeedPath))
+	env.mustRun("addfeed", "News", env.url(newsFeedPath))
+	env.mustRun("fullcontent", env.url(techFeedPath), "on")
+
+	assertUsageError(t, env.mustFail("agg"))
+	assertUsageError(t, env.mustFail("agg", "soon"))
+
+	env.scrapeAll()
+	var feeds []feedItem
+	env.runJSON(&feeds, "feeds")
+	for _, feed := range feeds {
+		if feed.LastFetchedAt == nil {
+			t.Errorf("feed %s wasn't marked fetched", feed.Name)
+		}
+	}
+
+	// The Go release arrived through both feeds; it is shown once, as the earliest
+	// published copy, and its link lost the tracking parameter.
+	posts := env.browse("10")
+	assertTitles(t, posts, "Rust borrow checker tips", "Go 1.30 is out", "Undated musings")
+	if !slices.Equal(posts[1].AlsoIn, []string{"Tech"}) {
+		t.Errorf("Go story also in %q, want [Tech]", posts[1].AlsoIn)
+	}
+	for _, post := range env.browse("10", "--feed", env.url(techFeedPath)) {
+		if strings.Contains(post.URL, "utm_source") {
+			t.Errorf("post URL %s wasn't normalised", post.URL)
+		}
+	}
+
+	// Fetching again finds nothing new.
+	env.scrapeAll()
+	if posts := env.browse("10", "--feed", env.url(techFeedPath)); len(posts) != 3 {
+		t.Errorf("got %d tech posts after a second fetch, want 3", len(posts))
+	}
+
+	// Full content was fetched for the tech feed only, and author and categories kept.
+	out := env.mustRun("browse", "1", "--feed", env.url(techFeedPath))
+	assertContains(t, out, "This is the full text of the rust article")
+	rows, err := env.store.GetRuleCandidatePosts(context.Background(), env.currentUser().ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].Title != "Go 1.30 released" || rows[0].Author.String != "Gopher" || !slices.Equal(rows[0].Categories, []string{"golang"}) {
+		t.Errorf("first post: got %+v", rows[0])
+	}
+}
+
+// currentUser returns the logged-in user.
+func (env *testEnv) currentUser() database.User {
+	env.t.Helper()
+	user, err := env.st.dbPtr.GetUser(context.Background(), sql.NullString{String: env.st.cfgPtr.CurrentUserName, Valid: true})
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	return user
+}
+
+func TestBrowse(t *testing.T) {
+	env := newFeedEnv(t)
+	tech := env.url(techFeedPath)
+
+	// Two posts by default, with a cursor for the rest.
+	out := env.mustRun("browse")
+	assertContains(t, out, "Found 2 unread posts for user alice:", "--- Rust borrow checker tips ---", "Also in: Tech", "Next page: repeat with --cursor ")
+	cursor := regexp.MustCompile(`--cursor (\S+)`).FindStringSubmatch(out)[1]
+	assertTitles(t, env.browse("--cursor", cursor), "Undated musings")
+	if err := env.mustFail("browse", "--cursor", cursor, "--sort", "feed"); !strings.Contains(err.Error(), "cursor was made for --sort published") {
+		t.Errorf("cursor with another sort: got %v", err)
+	}
+	env.mustFail("browse", "--cursor", "garbage")
+
+	assertTitles(t, env.browse("1", "--page", "2"), "Go 1.30 is out")
+	assertTitles(t, env.browse("--limit", "5", "--offset", "2"), "Undated musings")
+	assertTitles(t, env.browse("5", "--sort", "feed"), "Go 1.30 is out", "Rust borrow checker tips", "Undated musings")
+	if posts := env.browse("5", "--sort", "fetched"); len(posts) != 3 {
+		t.Errorf("sort by fetch time: got %q", titles(posts))
+	}
+
+	// Date filters use the publication date, or the fetch date (now) for undated posts.
+	assertTitles(t, env.browse("5", "--until", "2026-01-02T00:00:00Z"), "Go 1.30 is out")
+	assertTitles(t, env.browse("5", "--since", "2026-01-02T00:00:00Z"), "Rust borrow checker tips", "Go 1.30 released", "Undated musings")
+	assertTitles(t, env.browse("5", "--feed", tech, "--since", "2026-01-03T00:00:00Z", "--until", "2026-01-04T00:00:00Z"), "Rust borrow checker tips")
+	env.mustFail("browse", "--since", "yesterday")
+	env.mustFail("browse", "--feed", env.url("/missing.xml"))
+
+	env.mustRun("folder", "create", "Reading")
+	assertTitles(t, env.browse("5", "--folder", "Reading"))
+	env.mustRun("move", env.url(newsFeedPath), "Reading")
+	assertTitles(t, env.browse("5", "--folder", "Reading"), "Go 1.30 is out")
+	env.mustFail("browse", "--folder", "Nope")
+
+	assertUsageError(t, env.mustFail("browse", "0"))
+	assertUsageError(t, env.mustFail("browse", "many"))
+	assertUsageError(t, env.mustFail("browse", "--sort", "random"))
+	assertUsageError(t, env.mustFail("browse", "--page", "2", "--offset", "1"))
+	assertUsageError(t, env.mustFail("browse", "--page", "-1"))
+
+	for _, format := range []string{"table", "csv", "ndjson", "template={{.Title}}"} {
+		out := env.mustRun("browse", "1", "--output", format)
+		assertContains(t, out, "Rust borrow checker tips")
+	}
+	assertContains(t, env.mustRun("browse", "-h"), "Usage", "--cursor")
+}
+
+func TestDateDisplay(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			env := newTestEnv(t)
+			useBackend(t, env, backend)
+			env.mustRun("register", "alice")
+			env.mustRun("addfeed", "Tech", env.url(techFeedPath))
+			env.scrapeAll()
+
+			// Times are stored in UTC and shown in the configured zone and layout.
+			env.st.cfgPtr.Timezone = "America/Los_Angeles"
+			env.st.cfgPtr.DateFormat = "2006-01-02 15:04 MST"
+			out := env.mustRun("browse", "5")
+			assertContains(t, out, "] 2026-01-03 01:00 PST from Tech\n", "] 2026-01-02 02:00 PST from Tech\n", "] undated from Tech\n")
+			assertContains(t, env.mustRun("search", "borrow"), "] 2026-01-03 01:00 PST from Tech\n")
+
+			// Plain dates given on the command line are midnight in that zone too.
+			assertTitles(t, env.browse("5", "--until", "2026-01-03"), "Go 1.30 released")
+			env.st.cfgPtr.Timezone = "Pacific/Honolulu"
+			assertTitles(t, env.browse("5", "--until", "2026-01-03"), "Rust borrow checker tips", "Go 1.30 released")
+
+			env.st.cfgPtr.Timezone = "Mars/Olympus_Mons"
+			if err := env.mustFail("browse"); !strings.Contains(err.Error(), "invalid timezone") {
+				t.Errorf("browse with an invalid timezone: got %v", err)
+			}
+		})
+	}
+}
+
+func TestReadState(t *testing.T) {
+	env := newFeedEnv(t)
+	tech := env.url(techFeedPath)
+
+	// Reading one copy of a story reads the other copies too.
+	assertContains(t, env.mustRun("read", env.postID("Go 1.30 is out")), "Marked read: Go 1.30 is out")
+	assertTitles(t, env.browse("5"), "Rust borrow checker tips", "Undated musings")
+	assertTitles(t, env.browse("5", "--feed", tech), "Rust borrow checker tips", "Undated musings")
+	if posts := env.browse("5", "--all"); len(posts) != 3 || !posts[1].Read {
+		t.Errorf("browse --all after reading: got %+v", posts)
+	}
+	assertTitles(t, env.browse("5", "--unread=false"), "Rust borrow checker tips", "Go 1.30 is out", "Undated musings")
+
+	assertContains(t, env.mustRun("unread", env.postID("Go 1.30 released")), "Marked unread: Go 1.30 released")
+	assertTitles(t, env.browse("5"), "Rust borrow checker tips", "Go 1.30 is out", "Undated musings")
+
+	env.mustRun("read", env.postID("Rust borrow checker tips"), env.postID("Undated musings"))
+	assertTitles(t, env.browse("5"), "Go 1.30 is out")
+	env.mustRun("unread", env.postID("Rust borrow checker tips"), env.postID("Undated musings"))
+
+	// Reading a story through one feed reads it in every feed.
+	assertContains(t, env.mustRun("mark-all-read", "--feed", tech, "--before", "2026-01-03T00:00:00Z"), "Marked 1 posts as read.")
+	assertTitles(t, env.browse("5"), "Rust borrow checker tips", "Undated musings")
+	assertContains(t, env.mustRun("mark-all-read"), "Marked 3 posts as read.")
+	assertTitles(t, env.browse("5"))
+	env.mustFail("mark-all-read", "--before", "soon")
+
+	assertUsageError(t, env.mustFail("read"))
+	if err := env.mustFail("read", "zz"); !strings.Contains(err.Error(), "must be between") {
+		t.Errorf("reading a too short ID: got %v", err)
+	}
+	if err := env.mustFail("read", "00000000"); !strings.Contains(err.Error(), "no post with ID") {
+		t.Errorf("reading an unknown ID: got %v", err)
+	}
+	env.mustFail("read", "not-hex!")
+}
+
+func TestReadStoryArrivesAgain(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			env := newTestEnv(t)
+			useBackend(t, env, backend)
+			env.mustRun("register", "alice")
+			env.mustRun("addfeed", "News", env.url(newsFeedPath))
+			env.scrapeAll()
+			env.mustRun("read", env.postID("Go 1.30 is out"))
+
+			// The same story later arrives through another feed; it stays read.
+			env.mustRun("addfeed", "Tech", env.url(techFeedPath))
+			env.scrapeAll()
+			assertTitles(t, env.browse("5"), "Rust borrow checker tips", "Undated musings")
+			results, err := env.st.dbPtr.SearchPosts(context.Background(), database.SearchPostsParams{
+				Query: "released", UserID: env.currentUser().ID, Limit: 5,
+			})
+			if err != nil || len(results) == 0 {
+				t.Fatalf("search for the copy that arrived later: got %+v, %v", results, err)
+			}
+			for _, result := range results {
+				if !result.IsRead {
+					t.Errorf("search result %q of a read story isn't read", result.Title)
+				}
+			}
+		})
+	}
+}
+
+func TestStars(t *testing.T) {
+	env := newFeedEnv(t)
+	rust := env.postID("Rust borrow checker tips")
+
+	assertContains(t, env.mustRun("star", rust), "Starred: Rust borrow checker tips")
+	env.mustRun("star", rust)
+	env.mustRun("star", env.postID("Undated musings"))
+	assertTitles(t, env.browse("5", "--starred"), "Rust borrow checker tips", "Undated musings")
+
+	// Starred posts stay listed, and reachable by ID, after unfollowing their feed.
+	env.mustRun("unfollow", env.url(techFeedPath))
+	out := env.mustRun("starred")
+	assertContains(t, out, "Found 2 starred posts for user alice:", "(starred)", "--- Rust borrow checker tips ---")
+	var starred []postItem
+	env.runJSON(&starred, "starred", "1")
+	assertTitles(t, starred, "Undated musings")
+	assertUsageError(t, env.mustFail("starred", "0"))
+	assertUsageError(t, env.mustFail("starred", "-5"))
+	assertUsageError(t, env.mustFail("starred", "many"))
+
+	assertContains(t, env.mustRun("unstar", rust), "Unstarred: Rust borrow checker tips")
+	env.mustFail("unstar", rust) // no longer visible: not followed, not starred
+	env.mustRun("follow", env.url(techFeedPath))
+	assertContains(t, env.mustRun("unstar", rust), "Post wasn't starred")
+
+	assertUsageError(t, env.mustFail("star"))
+	assertUsageError(t, env.mustFail("starred", "lots"))
+}
+
+func TestTags(t *testing.T) {
+	env := newFeedEnv(t)
+	rust := env.postID("Rust borrow checker tips")
+
+	assertContains(t, env.mustRun("tag", rust, "#Later", "systems"), "Tagged later, systems: Rust borrow checker tips")
+	assertContains(t, env.mustRun("tag", rust, "later"), "Post already had those tags")
+	env.mustRun("tag", env.postID("Undated musings"), "later")
+	env.mustFail("tag", rust, "#")
+
+	assertContains(t, env.mustRun("tags"), "Tags for user alice:\n* later (2)\n* systems (1)\n")
+	posts := env.browse("5", "--tag", "LATER")
+	assertTitles(t, posts, "Rust borrow checker tips", "Undated musings")
+	if !slices.Equal(posts[0].Tags, []string{"later", "systems"}) {
+		t.Errorf("tags of the rust post: got %q", posts[0].Tags)
+	}
+
+	assertContains(t, env.mustRun("untag", rust, "systems", "unknown"), "Untagged systems: Rust borrow checker tips")
+	assertContains(t, env.mustRun("untag", rust, "systems"), "Post didn't have those tags")
+	var tags []tagItem
+	env.runJSON(&tags, "tags")
+	if len(tags) != 1 || tags[0] != (tagItem{Name: "later", Posts: 2}) {
+		t.Errorf("tags as JSON: got %+v", tags)
+	}
+
+	env.mustRun("untag", rust, "later")
+	env.mustRun("untag", env.postID("Undated musings"), "later")
+	assertContains(t, env.mustRun("tags"), "No tags found")
+	assertUsageError(t, env.mustFail("tag", rust))
+}
+
+func TestFolders(t *testing.T) {
+	env := newFeedEnv(t)
+	news := env.url(newsFeedPath)
+
+	assertContains(t, env.mustRun("folder", "create", "Daily"), "Folder Daily created.")
+	env.mustFail("folder", "create", "Daily")
+	assertContains(t, env.mustRun("move", news, "Daily"), "News moved to Daily.")
+	assertContains(t, env.mustRun("following"), "* Tech\nDaily/\n    * News\n")
+
+	assertContains(t, env.mustRun("folder", "rename", "Daily", "Morning"), "Folder Daily renamed to Morning.")
+	env.mustFail("folder", "rename", "Daily", "Evening")
+	assertContains(t, env.mustRun("following"), "Morning/\n    * News\n")
+
+	assertContains(t, env.mustRun("move", news, "--unfiled"), "News is no longer in a folder.")
+	env.mustRun("move", news, "Morning")
+	assertContains(t, env.mustRun("folder", "delete", "Morning"), "its feeds are still followed")
+	assertContains(t, env.mustRun("following"), "* News\n* Tech\n")
+	env.mustFail("folder", "delete", "Morning")
+
+	env.mustFail("move", news, "Nowhere")
+	env.mustRun("unfollow", news)
+	env.mustRun("folder", "create", "Later")
+	if err := env.mustFail("move", news, "Later"); !strings.Contains(err.Error(), "you don't follow News") {
+		t.Errorf("moving an unfollowed feed: got %v", err)
+	}
+
+	assertUsageError(t, env.mustFail("folder", "create"))
+	assertUsageError(t, env.mustFail("folder", "rename", "Later"))
+	assertUsageError(t, env.mustFail("folder", "paint", "Later"))
+	assertUsageError(t, env.mustFail("move", news))
+	assertUsageError(t, env.mustFail("move", news, "Later", "--unfiled"))
+}
+
+func TestRules(t *testing.T) {
+	env := newTestEnv(t)
+	env.mustRun("register", "alice")
+	env.mustRun("addfeed", "Tech", env.url(techFeedPath))
+	env.mustRun("addfeed", "News", env.url(newsFeedPath))
+
+	// Rules added before fetching act on new posts as they arrive.
+	assertContains(t, env.mustRun("rules", "add", "star", "author:gopher"), "It applies to new posts")
+	env.mustRun("rules", "add", "tag:Weekly", "category:rust", "OR", "feed:News")
+	env.scrapeAll()
+	assertTitles(t, env.browse("5", "--starred", "--feed", env.url(techFeedPath)), "Go 1.30 released")
+	assertTitles(t, env.browse("5", "--tag", "weekly"), "Rust borrow checker tips", "Go 1.30 is out")
+
+	// Rules added later apply to stored posts with rules apply.
+	env.mustRun("rules", "add", "hide", `title:"borrow checker"`)
+	env.mustRun("rules", "add", "highlight", "-title:/^go/")
+	assertTitles(t, env.browse("5"), "Rust borrow checker tips", "Go 1.30 is out", "Undated musings")
+	out := env.mustRun("rules", "apply")
+	assertContains(t, out, "Checked 4 posts:", `hide title:"borrow checker": 1 matched, 1 changed`, "highlight -title:/^go/: 2 matched, 2 changed")
+	assertTitles(t, env.browse("5"), "Go 1.30 is out", "Undated musings")
+	posts := env.browse("5", "--hidden")
+	assertTitles(t, posts, "Rust borrow checker tips", "Go 1.30 is out", "Undated musings")
+	if !posts[0].Highlighted || posts[1].Highlighted {
+		t.Errorf("highlights: got %+v", posts)
+	}
+	assertContains(t, env.mustRun("rules", "apply"), "1 matched, 0 changed")
+
+	var rules []ruleItem
+	env.runJSON(&rules, "rules", "list")
+	if len(rules) != 4 || rules[1].Action != "tag:weekly" || rules[1].Expression != "category:rust OR feed:News" {
+		t.Errorf("rules as JSON: got %+v", rules)
+	}
+	assertContains(t, env.mustRun("rules", "delete", shortID(rules[0].ID)), "deleted")
+	assertContains(t, env.mustRun("rules", "list"), "Rules for user alice:", "tag:weekly category:rust OR feed:News")
+	if err := env.mustFail("rules", "delete", "ffffffff"); !strings.Contains(err.Error(), "no rule with ID") {
+		t.Errorf("deleting an unknown rule: got %v", err)
+	}
+	if err := env.mustFail("rules", "delete", "f"); !strings.Contains(err.Error(), "must be between") {
+		t.Errorf("deleting a rule by a too short ID: got %v", err)
+	}
+	env.mustFail("rules", "delete", "not-hex!")
+
+	// A prefix shared by several rules lists them instead of picking one.
+	for _, id := range []string{"abcd0000-0000-0000-0000-000000000001", "abcd0000-0000-0000-0000-000000000002"} {
+		_, err := env.store.CreateRule(context.Background(), database.CreateRuleParams{
+			ID: uuid.MustParse(id), CreatedAt: time.Now().UTC(), UserID: env.currentUser().ID, Expression: "rust", Action: "star",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := env.mustFail("rules", "delete", "abcd")
+	assertContains(t, err.Error(), "is ambiguous", "abcd0000-0000-0000-0000-000000000001  star rust", "abcd0000-0000-0000-0000-000000000002  star rust")
+	assertContains(t, env.mustRun("rules", "delete", "abcd0000-0000-0000-0000-000000000002"), "Rule [abcd0000] deleted.")
+	assertContains(t, env.mustRun("rules", "delete", "ABCD"), "Rule [abcd0000] deleted.")
+
+	env.mustFail("rules", "add", "hide", "title:(")
+	env.mustFail("rules", "add", "tag:", "rust")
+	assertUsageError(t, env.mustFail("rules", "add", "explode", "rust"))
+	assertUsageError(t, env.mustFail("rules", "add", "hide"))
+	assertUsageError(t, env.mustFail("rules", "apply", "now"))
+	assertUsageError(t, env.mustFail("rules", "rewrite"))
+
+	env.mustRun("register", "bob")
+	assertContains(t, env.mustRun("rules", "list"), "No rules found")
+	assertContains(t, env.mustRun("rules", "apply"), "No rules found")
+}
+
+func TestSearch(t *testing.T) {
+	env := newFeedEnv(t)
+
+	out := env.mustRun("search", "generic")
+	assertContains(t, out, `Found 1 posts matching "generic":`, "--- Go 1.30 released ---", "*generic* methods")
+
+	var results []searchItem
+	env.runJSON(&results, "search", "rust", "OR", "generic")
+	if len(results) != 2 {
+		t.Errorf("search rust OR generic: got %+v", results)
+	}
+	env.runJSON(&results, "search", `"borrow checker"`)
+	if len(results) != 1 || results[0].Title != "Rust borrow checker tips" || results[0].Snippet == "" {
+		t.Errorf("phrase search: got %+v", results)
+	}
+	env.runJSON(&results, "search", "go", "-gopher")
+	if len(results) != 1 || results[0].Title != "Go 1.30 is out" {
+		t.Errorf("search with an excluded word: got %+v", results)
+	}
+	env.runJSON(&results, "search", "go", "--limit", "1")
+	if len(results) != 1 || results[0].Title != "Go 1.30 released" {
+		t.Errorf("search ranks title matches first: got %+v", results)
+	}
+
+	// Unfollowed feeds are only searched with --all-feeds.
+	env.mustRun("unfollow", env.url(newsFeedPath))
+	env.runJSON(&results, "search", "release")
+	if len(results) != 1 {
+		t.Errorf("search of followed feeds: got %+v", results)
+	}
+	env.runJSON(&results, "search", "release", "--all-feeds")
+	if len(results) != 2 {
+		t.Errorf("search of all feeds: got %+v", results)
+	}
+
+	assertContains(t, env.mustRun("search", "-nothing"), "Found 0 posts")
+	// After "--", --output is a search term rather than the global flag.
+	assertContains(t, env.mustRun("search", "--", "--output"), `Found 0 posts matching "--output":`)
+	if out := env.mustRun("search", "--output", "json", "--", "--output"); strings.TrimSpace(out) != "[]" {
+		t.Errorf("search --output json -- --output: got %q", out)
+	}
+	assertUsageError(t, env.mustFail("search"))
+	assertUsageError(t, env.mustFail("search", "go", "--limit", "0"))
+}
+
+func TestNormalizePosts(t *testing.T) {
+	env := newTestEnv(t)
+	env.mustRun("register", "alice")
+	env.mustRun("addfeed", "Tech", env.url(techFeedPath))
+	feed, err := env.store.GetFeedByURL(context.Background(), env.url(techFeedPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Posts stored verbatim by an older version: two links to the same article, and one
+	// link that only needs cleaning up.
+	now := time.Now().UTC()
+	create := func(title, link string, createdAt time.Time) {
+		id := uuid.New()
+		_, err := env.store.CreatePost(context.Background(), database.CreatePostParams{
+			ID: id, CreatedAt: createdAt, UpdatedAt: createdAt, Title: title, Url: link, FeedID: feed.ID, StoryID: id,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	create("Original", "https://example.com/a", now.Add(-2*time.Hour))
+	create("Tracked copy", "https://example.com/a?utm_source=rss", now.Add(-time.Hour))
+	create("Tracked only", "https://Example.com/b?utm_medium=x&id=1", now)
+
+	copyID := env.postID("Tracked copy")
+	env.mustRun("star", copyID)
+	env.mustRun("tag", copyID, "keep")
+	env.mustRun("read", copyID)
+	// Hides and highlights only come from rules, so set them directly.
+	alice := env.currentUser()
+	copyPost, err := env.store.GetFeedPostByURL(context.Background(), database.GetFeedPostByURLParams{
+		FeedID: feed.ID, Url: "https://example.com/a?utm_source=rss",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.store.HidePost(context.Background(), database.HidePostParams{UserID: alice.ID, PostID: copyPost.ID, HiddenAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.store.HighlightPost(context.Background(), database.HighlightPostParams{UserID: alice.ID, PostID: copyPost.ID, HighlightedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	out := env.mustRun("normalize-posts")
+	assertContains(t, out, "Checked 3 posts: 1 URLs normalised, 1 duplicates merged.")
+	assertTitles(t, env.browse("5", "--all"), "Tracked only")
+	posts := env.browse("5", "--all", "--hidden", "--sort", "fetched")
+	assertTitles(t, posts, "Tracked only", "Original")
+	if posts[0].URL != "https://example.com/b?id=1" {
+		t.Errorf("normalised URL: got %s", posts[0].URL)
+	}
+	if !posts[1].Starred || !posts[1].Read || !posts[1].Highlighted || !slices.Equal(posts[1].Tags, []string{"keep"}) {
+		t.Errorf("the merged post lost its state: %+v", posts[1])
+	}
+	assertContains(t, env.mustRun("normalize-posts"), "0 URLs normalised, 0 duplicates merged")
+}
+
+// failingDeleteStore is the in-memory store with DeletePost failing, to interrupt
+// multi-step writes part way through.
+type failingDeleteStore struct {
+	*memdb.Store
+}
+
+func (s failingDeleteStore) DeletePost(ctx context.Context, id uuid.UUID) error {
+	return errors.New("disk full")
+}
+
+func TestNormalizePostsIsAllOrNothing(t *testing.T) {
+	env := newTestEnv(t)
+	env.mustRun("register", "alice")
+	env.mustRun("addfeed", "Tech", env.url(techFeedPath))
+	feed, err := env.store.GetFeedByURL(context.Background(), env.url(techFeedPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	for i, link := range []string{"https://example.com/a", "https://example.com/a?utm_source=rss"} {
+		id := uuid.New()
+		createdAt := now.Add(time.Duration(i) * time.Hour)
+		_, err := env.store.CreatePost(context.Background(), database.CreatePostParams{
+			ID: id, CreatedAt: createdAt, UpdatedAt: createdAt, Title: fmt.Sprintf("Copy %d", i), Url: link, FeedID: feed.ID, StoryID: id,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	env.mustRun("star", env.postID("Copy 1"))
+
+	// The stars have moved by the time deleting the duplicate fails; they move back.
+	env.st.dbPtr = failingDeleteStore{env.store}
+	if err := env.mustFail("normalize-posts"); !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("normalize-posts with a failing delete: got %v", err)
+	}
+	env.st.dbPtr = env.store
+	assertTitles(t, env.browse("5", "--starred"), "Copy 1")
+
+	assertContains(t, env.mustRun("normalize-posts"), "1 duplicates merged")
+	assertTitles(t, env.browse("5", "--starred"), "Copy 0")
+}
+
+func TestWebSubCallback(t *testing.T) {
+	env := newTestEnv(t)
+	env.mustRun("register", "alice")
+	env.mustRun("addfeed", "Tech", env.url(techFeedPath))
+	env.mustRun("addfeed", "News", env.url(newsFeedPath))
+
+	assertUsageError(t, env.mustFail("serve", ":0", "example.com", "1m"))
+	assertUsageError(t, env.mustFail("serve", ":0", "https://example.com", "often"))
+	assertUsageError(t, env.mustFail("serve", ":0"))
+
+	feed, err := env.store.GetFeedByURL(context.Background(), env.url(techFeedPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscription, err := env.store.UpsertWebSubSubscription(context.Background(), database.UpsertWebSubSubscriptionParams{
+		ID: uuid.New(), FeedID: feed.ID, HubUrl: "https://hub.example/", TopicUrl: feed.Url, Secret: "s3cret",
+		CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC(), RequestedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://gator.example/")
+	env.st.webSubPtr = &webSubSubscriber{callbackBase: base, client: http.DefaultClient}
+	callback := env.st.webSubPtr.callbackURL(subscription.ID)
+	if callback != "https://gator.example/websub/"+subscription.ID.String() {
+		t.Errorf("callback URL: got %s", callback)
+	}
+
+	serve := func(method, target string, body string, header http.Header) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		for key, values := range header {
+			request.Header[key] = values
+		}
+		recorder := httptest.NewRecorder()
+		env.st.webSubPtr.handleCallback(env.st, recorder, request)
+		return recorder
+	}
+
+	// The hub verifies the subscription; the lease it grants is stored.
+	query := url.Values{"hub.mode": {"subscribe"}, "hub.topic": {feed.Url}, "hub.challenge": {"c4ll"}, "hub.lease_seconds": {"3600"}}
+	response := serve("GET", callback+"?"+query.Encode(), "", nil)
+	if response.Code != http.StatusOK || response.Body.String() != "c4ll" {
+		t.Errorf("verification: got %d %q", response.Code, response.Body.String())
+	}
+	subscription, _ = env.store.GetWebSubSubscription(context.Background(), subscription.ID)
+	if !subscription.LeaseExpiresAt.Valid || time.Until(subscription.LeaseExpiresAt.Time) > time.Hour {
+		t.Errorf("lease after verification: got %+v", subscription.LeaseExpiresAt)
+	}
+	query.Set("hub.topic", "https://elsewhere.example/feed")
+	if response := serve("GET", callback+"?"+query.Encode(), "", nil); response.Code != http.StatusNotFound {
+		t.Errorf("verification for another topic: got %d", response.Code)
+	}
+
+	// While the lease lasts, the poller skips the feed.
+	scrapeFeeds(env.st)
+	news, _ := env.store.GetFeedByURL(context.Background(), env.url(newsFeedPath))
+	feed, _ = env.store.GetFeedByID(context.Background(), feed.ID)
+	if !news.LastFetchedAt.Valid || feed.LastFetchedAt.Valid {
+		t.Errorf("polling with a WebSub lease: news fetched %v, tech fetched %v", news.LastFetchedAt.Valid, feed.LastFetchedAt.Valid)
+	}
+
+	// Pushed content is ingested only with a valid signature.
+	body, err := io.ReadAll(mustGet(t, env.url(techFeedPath)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = serve("POST", callback, string(body), http.Header{"X-Hub-Signature": {"sha256=00"}})
+	if response.Code != http.StatusAccepted || len(env.browse("10", "--feed", feed.Url)) != 0 {
+		t.Errorf("push with a bad signature: got %d", response.Code)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	response = serve("POST", callback, string(body), http.Header{"X-Hub-Signature": {signature}})
+	if response.Code != http.StatusAccepted {
+		t.Errorf("push: got %d", response.Code)
+	}
+	assertTitles(t, env.browse("10", "--feed", feed.Url), "Rust borrow checker tips", "Go 1.30 released", "Undated musings")
+
+	if response := serve("PUT", callback, "", nil); response.Code != http.StatusMethodNotAllowed {
+		t.Errorf("PUT: got %d", response.Code)
+	}
+	if response := serve("GET", "https://gator.example/websub/"+uuid.NewString(), "", nil); response.Code != http.StatusNotFound {
+		t.Errorf("unknown subscription: got %d", response.Code)
+	}
+	if response := serve("GET", "https://gator.example/websub/nope", "", nil); response.Code != http.StatusNotFound {
+		t.Errorf("invalid subscription ID: got %d", response.Code)
+	}
+
+	// Moving to another hub drops the lease and the secret the old hub signs with.
+	moved, err := env.store.UpsertWebSubSubscription(context.Background(), database.UpsertWebSubSubscriptionParams{
+		ID: uuid.New(), FeedID: feed.ID, HubUrl: "https://hub2.example/", TopicUrl: feed.Url, Secret: "n3w",
+		CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC(), RequestedAt: time.Now().UTC(),
+	})
+	if err != nil || moved.ID != subscription.ID || moved.LeaseExpiresAt.Valid || moved.Secret != "n3w" {
+		t.Errorf("subscription after moving hubs: got %+v, %v", moved, err)
+	}
+
+	// A denial removes the subscription, so the feed is polled again.
+	serve("GET", callback+"?hub.mode=denied&hub.reason=nope", "", nil)
+	if _, err := env.store.GetWebSubSubscription(context.Background(), subscription.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("subscription after denial: got %v", err)
+	}
+
+	// So does a fetch that finds the feed no longer advertises a hub.
+	_, err = env.store.UpsertWebSubSubscription(context.Background(), database.UpsertWebSubSubscriptionParams{
+		ID: uuid.New(), FeedID: feed.ID, HubUrl: "https://hub.example/", TopicUrl: feed.Url, Secret: "s3cret",
+		CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC(), RequestedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scrapeFeed(env.st, feed)
+	if _, err := env.store.GetWebSubSubscriptionForFeed(context.Background(), feed.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("subscription after the hub link went away: got %v", err)
+	}
+}
+
+// stubHub is a WebSub hub for tests. It verifies each subscription request with the
+// subscriber's callback before answering, and grants leaseSeconds.
+type stubHub struct {
+	mu           sync.Mutex
+	leaseSeconds int
+	requests     []url.Values
+	verified     int
+}
+
+func (h *stubHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.mu.Lock()
+	h.requests = append(h.requests, r.PostForm)
+	leaseSeconds := h.leaseSeconds
+	h.mu.Unlock()
+
+	challenge := uuid.NewString()
+	query := url.Values{
+		"hub.mode":          {r.PostForm.Get("hub.mode")},
+		"hub.topic":         {r.PostForm.Get("hub.topic")},
+		"hub.challenge":     {challenge},
+		"hub.lease_seconds": {strconv.Itoa(leaseSeconds)},
+	}
+	response, err := http.Get(r.PostForm.Get("hub.callback") + "?" + query.Encode())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer response.Body.Close()
+	if body, _ := io.ReadAll(response.Body); response.StatusCode == http.StatusOK && string(body) == challenge {
+		h.mu.Lock()
+		h.verified++
+		h.mu.Unlock()
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// received returns the subscription requests so far and how many were verified.
+func (h *stubHub) received() ([]url.Values, int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.requests), h.verified
+}
+
+// newHubFeed starts a server for a feed with one post that advertises the hub at
+// hubURL, and returns the feed's URL.
+func newHubFeed(t *testing.T, hubURL string) string {
+	t.Helper()
+	feeds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprintf(w, `<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+<title>Pushed</title>
+<atom:link rel="hub" href="%s"/>
+<atom:link rel="self" href="http://%s/feed.xml"/>
+<item><title>First push</title><link>http://%[2]s/articles/first</link></item>
+</channel>
+</rss>`, hubURL, r.Host)
+	}))
+	t.Cleanup(feeds.Close)
+	return feeds.URL + "/feed.xml"
+}
+
+// failingCreatePostStore is the in-memory store with CreatePost failing, so ingesting
+// a feed rolls back.
+type failingCreatePostStore struct {
+	*memdb.Store
+}
+
+func (s failingCreatePostStore) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	return database.Post{}, errors.New("disk full")
+}
+
+// failingStarStore is the in-memory store with StarPost failing, so a star rule can't
+// be applied.
+type failingStarStore struct {
+	*memdb.Store
+}
+
+func (s failingStarStore) StarPost(ctx context.Context, arg database.StarPostParams) (int64, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRuleFailureRollsBackIngest(t *testing.T) {
+	env := newTestEnv(t)
+	env.mustRun("register", "alice")
+	env.mustRun("addfeed", "Tech", env.url(techFeedPath))
+	env.mustRun("rules", "add", "star", "category:rust")
+	feed, err := env.store.GetFeedByURL(context.Background(), env.url(techFeedPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env.st.dbPtr = failingStarStore{env.store}
+	feedData, err := fetchFeed(context.Background(), feed.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ingestFeedItems(env.st, feed, feedData, true)
+	env.st.dbPtr = env.store
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("ingest with a failing rule: got %v", err)
+	}
+	assertTitles(t, env.browse("10"))
+
+	env.scrapeAll()
+	assertTitles(t, env.browse("10", "--starred"), "Rust borrow checker tips")
+}
+
+func TestWebSubSubscribesAfterIngest(t *testing.T) {
+	env := newTestEnv(t)
+	hub := &stubHub{leaseSeconds: 3600}
+	hubServer := httptest.NewServer(hub)
+	t.Cleanup(hubServer.Close)
+	base, _ := url.Parse("http://127.0.0.1:1/")
+	env.st.webSubPtr = &webSubSubscriber{callbackBase: base, client: http.DefaultClient}
+	feedURL := newHubFeed(t, hubServer.URL)
+	env.mustRun("register", "alice")
+	env.mustRun("addfeed", "Pushed", feedURL)
+	feed, err := env.store.GetFeedByURL(context.Background(), feedURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The posts couldn't be saved, so the feed must stay polled to retry them.
+	env.st.dbPtr = failingCreatePostStore{env.store}
+	scrapeFeed(env.st, feed)
+	env.st.dbPtr = env.store
+	if requests, _ := hub.received(); len(requests) != 0 {
+		t.Errorf("subscribed after a failed ingest: %v", requests)
+	}
+	if _, err := env.store.GetWebSubSubscriptionForFeed(context.Background(), feed.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("subscription after a failed ingest: got %v", err)
+	}
+
+	scrapeFeed(env.st, feed)
+	if requests, _ := hub.received(); len(requests) != 1 {
+		t.Errorf("subscription requests after a successful ingest: %v", requests)
+	}
+}
+
+func TestWebSubHub(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			env := newTestEnv(t)
+			useBackend(t, env, backend)
+			hub := &stubHub{leaseSeconds: 3600}
+			hubServer := httptest.NewServer(hub)
+			t.Cleanup(hubServer.Close)
+			callbacks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				env.st.webSubPtr.handleCallback(env.st, w, r)
+			}))
+			t.Cleanup(callbacks.Close)
+			base, _ := url.Parse(callbacks.URL)
+			env.st.webSubPtr = &webSubSubscriber{callbackBase: base, client: http.DefaultClient}
+
+			feedURL := newHubFeed(t, hubServer.URL)
+			env.mustRun("register", "alice")
+			env.mustRun("addfeed", "Pushed", feedURL)
+			ctx := context.Background()
+
+			// The first fetch subscribes at the advertised hub, which verifies our intent
+			// against the callback and grants a lease.
+			env.scrapeAll()
+			requests, verified := hub.received()
+			if len(requests) != 1 || verified != 1 {
+				t.Fatalf("hub after the first fetch: %d requests, %d verified", len(requests), verified)
+			}
+			feed, err := env.st.dbPtr.GetFeedByURL(ctx, feedURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			subscription, err := env.st.dbPtr.GetWebSubSubscriptionForFeed(ctx, feed.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			form := requests[0]
+			if form.Get("hub.mode") != "subscribe" || form.Get("hub.topic") != feedURL ||
+				form.Get("hub.callback") != env.st.webSubPtr.callbackURL(subscription.ID) ||
+				form.Get("hub.secret") != subscription.Secret || form.Get("hub.lease_seconds") != strconv.Itoa(webSubLeaseSeconds) {
+				t.Errorf("subscription request: got %v for %+v", form, subscription)
+			}
+			lease := subscription.LeaseExpiresAt
+			if !lease.Valid || time.Until(lease.Time) > time.Hour || time.Until(lease.Time) < 59*time.Minute {
+				t.Errorf("lease after verification: got %+v", lease)
+			}
+			assertTitles(t, env.browse("5"), "First push")
+
+			// While the lease lasts, the poller leaves the feed to the hub.
+			scrapeFeeds(env.st)
+			if polled, _ := env.st.dbPtr.GetFeedByURL(ctx, feedURL); !polled.LastFetchedAt.Time.Equal(feed.LastFetchedAt.Time) {
+				t.Errorf("the feed was polled under a lease")
+			}
+
+			// The lease runs out within a day, but was just requested: no renewal yet.
+			env.st.webSubPtr.renewExpiring(env.st, time.Now().UTC())
+			if requests, _ := hub.received(); len(requests) != 1 {
+				t.Errorf("renewal right after subscribing: %d requests", len(requests))
+			}
+
+			// A renewal interval later it is renewed at the same hub with the same callback
+			// and secret, and the new lease is stored.
+			hub.mu.Lock()
+			hub.leaseSeconds = 7200
+			hub.mu.Unlock()
+			env.st.webSubPtr.renewExpiring(env.st, time.Now().UTC().Add(webSubRenewInterval+time.Minute))
+			requests, verified = hub.received()
+			if len(requests) != 2 || verified != 2 {
+				t.Fatalf("hub after renewal: %d requests, %d verified", len(requests), verified)
+			}
+			if requests[1].Get("hub.callback") != form.Get("hub.callback") || requests[1].Get("hub.secret") != form.Get("hub.secret") {
+				t.Errorf("renewal request: got %v, first request %v", requests[1], form)
+			}
+			renewed, err := env.st.dbPtr.GetWebSubSubscriptionForFeed(ctx, feed.ID)
+			if err != nil || renewed.ID != subscription.ID || !renewed.LeaseExpiresAt.Time.After(lease.Time.Add(30*time.Minute)) {
+				t.Errorf("subscription after renewal: got %+v, %v", renewed, err)
+			}
+		})
+	}
+}
+
+// mustGet fetches a URL and returns its body.
+func mustGet(t *testing.T, target string) io.Reader {
+	t.Helper()
+	response, err := http.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+	return response.Body
+}
+
+// driveTUI delivers msg to the model and then runs the commands it returns, feeding
+// their messages back in, until there is nothing left to do.
+func driveTUI(t *testing.T, m *tuiModel, msg tea.Msg) {
+	t.Helper()
+	_, cmd := m.Update(msg)
+	runTUICmd(t, m, cmd)
+}
+
+func runTUICmd(t *testing.T, m *tuiModel, cmd tea.Cmd) {
+	t.Helper()
+	if cmd == nil {
+		return
+	}
+	switch msg := cmd().(type) {
+	case tea.BatchMsg:
+		for _, c := range msg {
+			runTUICmd(t, m, c)
+		}
+	case tea.QuitMsg, nil:
+	default:
+		driveTUI(t, m, msg)
+	}
+}
+
+func TestTUI(t *testing.T) {
+	env := newFeedEnv(t)
+	env.mustRun("folder", "create", "Daily")
+	env.mustRun("move", env.url(newsFeedPath), "Daily")
+
+	dates, err := env.st.dateDisplay("Mon Jan 2 2006 15:04")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &tuiModel{st: env.st, user: env.currentUser(), dates: dates, unreadOnly: true, status: tuiHelp}
+	runTUICmd(t, m, m.Init())
+	driveTUI(t, m, tea.WindowSizeMsg{Width: 120, Height: 30})
+
+	var labels []string
+	for _, source := range m.sources {
+		labels = append(labels, source.label)
+	}
+	if want := []string{"All posts", "Daily/", "  News", "Tech"}; !slices.Equal(labels, want) {
+		t.Errorf("sources: got %q, want %q", labels, want)
+	}
+	if len(m.posts) != 3 || m.posts[0].Title != "Rust borrow checker tips" {
+		t.Fatalf("posts: got %+v", m.posts)
+	}
+	if view := m.View(); !strings.Contains(view, "Rust borrow checker tips") || !strings.Contains(view, "Lifetimes without tears") {
+		t.Errorf("view doesn't show the selected post:\n%s", view)
+	}
+
+	// Opening a post marks it read; s stars it. Both go through to the database.
+	driveTUI(t, m, tea.KeyMsg{Type: tea.KeyTab})
+	driveTUI(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	driveTUI(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	if m.status != "Starred" {
+		t.Errorf("status after starring: %q", m.status)
+	}
+	posts := env.browse("5", "--all")
+	if !posts[0].Read || !posts[0].Starred {
+		t.Errorf("rust post after reading and starring in the TUI: %+v", posts[0])
+	}
+
+	// Picking a folder shows its feeds' posts; u toggles read posts.
+	m.focus = paneSources
+	driveTUI(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	driveTUI(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.posts) != 1 || m.posts[0].Title != "Go 1.30 is out" {
+		t.Errorf("posts of the Daily folder: got %+v", m.posts)
+	}
+	m.sourceCursor = 0
+	driveTUI(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("u")})
+	if len(m.posts) != 3 || m.status != "Showing all posts" {
+		t.Errorf("all posts: got %d posts, status %q", len(m.posts), m.status)
+	}
+
+	// / searches; esc goes back to the source.
+	driveTUI(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	for _, r := range "generic" {
+		driveTUI(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	driveTUI(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.posts) != 1 || m.posts[0].Title != "Go 1.30 released" {
+		t.Errorf("search results: got %+v", m.posts)
+	}
+	driveTUI(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.query != "" || len(m.posts) != 3 {
+		t.Errorf("after leaving search: query %q, %d posts", m.query, len(m.posts))
+	}
+}
+
+func TestShell(t *testing.T) {
+	for line, want := range map[string][]string{
+		`browse 5`:                     {"browse", "5"},
+		`search "borrow checker" -go`:  {"search", "borrow checker", "-go"},
+		`rules add hide 'title:"x y"'`: {"rules", "add", "hide", `title:"x y"`},
+		`tag a\ b`:                     {"tag", "a b"},
+		`   `:                          nil,
+	} {
+		got, err := splitWords(line)
+		if err != nil || !slices.Equal(got, want) {
+			t.Errorf("splitWords(%q) = %q, %v; want %q", line, got, err, want)
+		}
+	}
+	if _, err := splitWords(`search "open`); err == nil {
+		t.Error("splitWords accepted an unterminated quote")
+	}
+
+	env := newFeedEnv(t)
+	words, _ := splitWords(`search "borrow checker" --output json`)
+	var out string
+	err := captureStdout(t, &out, func() error { return env.cmds.runShellLine(env.st, words) })
+	if err != nil || !strings.HasPrefix(out, "[") || !strings.Contains(out, `"title": "Rust borrow checker tips"`) {
+		t.Errorf("shell line with --output: got %v\n%s", err, out)
+	}
+	if !env.st.outPtr.IsText() {
+		t.Error("--output on a shell line stuck for later lines")
+	}
+	if err := env.cmds.runShellLine(env.st, []string{"--output", "json"}); err == nil {
+		t.Error("a line without a command succeeded")
+	}
+}
+
+func TestHelpAndCompletion(t *testing.T) {
+	env := newFeedEnv(t)
+	env.mustRun("folder", "create", "Daily")
+	env.mustRun("tag", env.postID("Undated musings"), "later")
+
+	assertContains(t, env.mustRun("help"), "Feed commands", "browse", "Maintenance commands")
+	assertContains(t, env.mustRun("help", "browse"), "--cursor")
+	err := env.mustFail("brwose")
+	assertUsageError(t, err)
+	assertContains(t, err.Error(), "did you mean browse?")
+	err = env.mustFail("help", "brwose")
+	assertUsageError(t, err)
+	assertContains(t, err.Error(), "did you mean browse?")
+	// Hidden commands aren't described, let alone run.
+	if out, err := env.run("help", "__complete"); err == nil || out != "" {
+		t.Errorf("help for a hidden command: got %q, %v", out, err)
+	}
+
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		assertContains(t, env.mustRun("completion", shell), "__complete")
+	}
+	assertUsageError(t, env.mustFail("completion", "tcsh"))
+
+	for _, c := range []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{"fo"}, []string{"folder\t", "follow\t", "following\t"}},
+		{[]string{"follow", ""}, []string{env.url(techFeedPath) + "\tTech", env.url(newsFeedPath) + "\tNews"}},
+		{[]string{"browse", "--folder", ""}, []string{"Daily\t"}},
+		{[]string{"browse", "--tag="}, []string{"--tag=later\t1 posts"}},
+		{[]string{"login", ""}, []string{"alice\t"}},
+		{[]string{"browse", "--output", "j"}, []string{"json\t"}},
+		{[]string{"fullcontent", "x", ""}, []string{"on\t", "off\t"}},
+	} {
+		out := env.mustRun(append([]string{"__complete"}, c.words...)...)
+		assertContains(t, out, c.want...)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	env := newTestEnv(t)
+	connPtr, db, driver, err := openDatabase("sqlite:" + filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { connPtr.Close() })
+	env.st.connPtr, env.st.dbPtr, env.st.driver = connPtr, db, driver
+
+	// Checking the schema leaves a new database alone, and help doesn't need one.
+	if err := env.mustFail("users"); !strings.Contains(err.Error(), "no gator schema yet") {
+		t.Errorf("users before migrating: got %v", err)
+	}
+	var tables int
+	if err := connPtr.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table'").Scan(&tables); err != nil || tables != 0 {
+		t.Errorf("tables after checking the schema: got %d, %v", tables, err)
+	}
+	assertContains(t, env.mustRun("browse", "--help"), "Usage")
+	assertContains(t, env.mustRun("migrate"), "up 001_initial.sql")
+	assertContains(t, env.mustRun("migrate", "status"), "001_initial.sql", "applied")
+	env.mustRun("register", "alice")
+	assertContains(t, env.mustRun("users"), "* alice (current)")
+	assertUsageError(t, env.mustFail("migrate", "sideways"))
+
+	env.mustRun("migrate", "down")
+	if err := env.mustFail("users"); !strings.Contains(err.Error(), "run 'gator migrate' first") {
+		t.Errorf("users after rolling a migration back: got %v", err)
+	}
+	assertContains(t, env.mustRun("users", "-h"), "Usage")
+}
+
+// backends names the stores that tests looping over them run against: the in-memory
+// store, SQLite, and with the postgres build tag PostgreSQL (see postgres_test.go).
+var backends = []string{"memory", "sqlite"}
+
+// usePostgres is set by postgres_test.go when the tests are built with the postgres tag.
+var usePostgres func(t *testing.T, env *testEnv)
+
+// useBackend points env at a new, migrated database of the named backend.
+func useBackend(t *testing.T, env *testEnv, backend string) {
+	t.Helper()
+	switch backend {
+	case "sqlite":
+		useSQLite(t, env)
+	case "postgres":
+		usePostgres(t, env)
+	}
+}
+
+// useSQLite points env at a new, migrated SQLite database instead of the in-memory store.
+func useSQLite(t *testing.T, env *testEnv) {
+	t.Helper()
+	connPtr, db, driver, err := openDatabase("sqlite:" + filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { connPtr.Close() })
+	env.st.connPtr, env.st.dbPtr, env.st.driver = connPtr, db, driver
+	env.mustRun("migrate")
+}
+
+// TestBackendQueries runs the commands behind most queries against every backend, so
+// a query that only works in one SQL dialect fails here.
+func TestBackendQueries(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			env := newTestEnv(t)
+			useBackend(t, env, backend)
+			env.mustRun("register", "alice")
+			env.mustRun("addfeed", "Tech", env.url(techFeedPath))
+			env.mustRun("addfeed", "News", env.url(newsFeedPath))
+			env.scrapeAll()
+			env.scrapeAll()
+			tech, news := env.url(techFeedPath), env.url(newsFeedPath)
+
+			assertContains(t, env.mustRun("users"), "* alice (current)")
+			assertContains(t, env.mustRun("following"), "* News\n* Tech\n")
+			assertTitles(t, env.browse("10"), "Rust borrow checker tips", "Go 1.30 is out", "Undated musings")
+			assertTitles(t, env.browse("1", "--page", "2"), "Go 1.30 is out")
+			assertTitles(t, env.browse("10", "--feed", tech, "--sort", "fetched"), "Undated musings", "Rust borrow checker tips", "Go 1.30 released")
+
+			env.mustRun("feed-settings", tech, "--title", "Gadgets", "--priority", "5")
+			env.mustRun("folder", "create", "Daily")
+			env.mustRun("move", news, "Daily")
+			assertContains(t, env.mustRun("following"), "* Gadgets (priority 5)\nDaily/\n    * News\n")
+			assertTitles(t, env.browse("10", "--folder", "Daily"), "Go 1.30 is out")
+
+			env.mustRun("read", env.postID("Go 1.30 is out"))
+			assertTitles(t, env.browse("10"), "Rust borrow checker tips", "Undated musings")
+			env.mustRun("unread", env.postID("Go 1.30 released"))
+			assertContains(t, env.mustRun("mark-all-read", "--feed", news), "Marked 1 posts as read.")
+
+			rust := env.postID("Rust borrow checker tips")
+			env.mustRun("star", rust)
+			env.mustRun("tag", rust, "later")
+			assertTitles(t, env.browse("10", "--starred"), "Rust borrow checker tips")
+			assertTitles(t, env.browse("10", "--tag", "later"), "Rust borrow checker tips")
+			assertContains(t, env.mustRun("tags"), "* later (1)\n")
+			assertContains(t, env.mustRun("starred"), "Found 1 starred posts for user alice:")
+			env.mustRun("untag", rust, "later")
+			env.mustRun("unstar", rust)
+
+			env.mustRun("rules", "add", "hide", "title:/^undated/")
+			assertContains(t, env.mustRun("rules", "apply"), "1 matched, 1 changed")
+			assertTitles(t, env.browse("10"), "Rust borrow checker tips")
+			assertContains(t, env.mustRun("search", "borrow"), "Rust borrow checker tips")
+
+			env.mustRun("unfollow", news)
+			env.mustRun("follow", news)
+			env.mustRun("folder", "delete", "Daily")
+			assertContains(t, env.mustRun("following"), "* Gadgets (priority 5)\n* News\n")
+		})
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			env := newTestEnv(t)
+			useBackend(t, env, backend)
+			createUser := func(txPtr *state, name string) error {
+				_, err := txPtr.dbPtr.CreateUser(context.Background(), database.CreateUserParams{
+					ID: uuid.New(), CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC(),
+					Name: sql.NullString{String: name, Valid: true},
+				})
+				return err
+			}
+
+			failure := errors.New("failed")
+			err := env.st.withTx(context.Background(), func(txPtr *state) error {
+				if err := createUser(txPtr, "alice"); err != nil {
+					return err
+				}
+				return failure
+			})
+			if !errors.Is(err, failure) {
+				t.Errorf("withTx returned %v, want fn's error", err)
+			}
+			err = env.st.withTx(context.Background(), func(txPtr *state) error {
+				return createUser(txPtr, "bob")
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			users, err := env.st.dbPtr.GetUsers(context.Background())
+			if err != nil || len(users) != 1 || users[0].String != "bob" {
+				t.Errorf("users after a rolled back and a committed transaction: got %v, %v", users, err)
+			}
+		})
+	}
+}
+
+func TestSQLiteIngest(t *testing.T) {
+	env := newTestEnv(t)
+	useSQLite(t, env)
+	env.mustRun("register", "alice")
+	env.mustRun("addfeed", "Tech", env.url(techFeedPath))
+	env.mustRun("addfeed", "News", env.url(newsFeedPath))
+	env.mustRun("rules", "add", "star", "category:rust")
+
+	env.scrapeAll()
+	env.scrapeAll()
+	assertTitles(t, env.browse("10"), "Rust borrow checker tips", "Go 1.30 is out", "Undated musings")
+	assertTitles(t, env.browse("10", "--starred"), "Rust borrow checker tips")
+}
+
+func TestPrune(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			env := newTestEnv(t)
+			useBackend(t, env, backend)
+			env.mustRun("register", "alice")
+			env.mustRun("addfeed", "Tech", env.url(techFeedPath))
+			env.mustRun("addfeed", "News", env.url(newsFeedPath))
+			env.scrapeAll()
+			tech := env.url(techFeedPath)
+
+			feed, err := env.st.dbPtr.GetFeedByURL(context.Background(), tech)
+			if err != nil {
+				t.Fatal(err)
+			}
+			saved := time.Now().UTC().Add(-100 * 24 * time.Hour)
+			id := uuid.New()
+			_, err = env.st.dbPtr.CreatePost(context.Background(), database.CreatePostParams{
+				ID: id, CreatedAt: saved, UpdatedAt: saved, Title: "Ancient", Url: env.url("/articles/ancient"), FeedID: feed.ID, StoryID: id,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Nothing is pruned without a policy.
+			assertContains(t, env.mustRun("prune", "--dry-run"), "Would delete 0 posts.")
+
+			env.st.cfgPtr.RetainFor = "30d"
+			out := env.mustRun("prune", "--dry-run")
+			assertContains(t, out, "Would delete 1 posts.\nTech:\n    * Ancient (saved ")
+			env.mustRun("star", env.postID("Ancient"))
+			assertContains(t, env.mustRun("prune", "--dry-run"), "Would delete 0 posts.")
+			env.mustRun("unstar", env.postID("Ancient"))
+
+			// A feed's own limit adds to the default one; tagged posts are kept but count
+			// towards the most recent.
+			assertContains(t, env.mustRun("retention", tech, "--keep", "2"), "* Keep posts:    2\n* Max age:       default (30d)\n")
+			env.mustRun("tag", env.postID("Go 1.30 released"), "keep")
+			out = env.mustRun("prune", "--dry-run", "--feed", tech)
+			assertContains(t, out, "Would delete 1 posts.", "* Ancient")
+			assertContains(t, env.mustRun("prune"), "Deleted 1 posts.\n* Tech: 1\n")
+			assertTitles(t, env.browse("10", "--feed", tech), "Rust borrow checker tips", "Go 1.30 released", "Undated musings")
+
+			// Pruned posts the feed still lists don't come back.
+			env.mustRun("untag", env.postID("Go 1.30 released"), "keep")
+			env.mustRun("retention", tech, "--keep", "1", "--max-age", "none")
+			assertContains(t, env.mustRun("prune"), "Deleted 2 posts.")
+			env.scrapeAll()
+			if posts := env.browse("10", "--feed", tech); len(posts) != 1 {
+				t.Errorf("tech posts after pruning to 1 and fetching again: got %q", titles(posts))
+			}
+
+			// Those it no longer lists are forgotten.
+			pruned, err := env.st.dbPtr.GetFeedPrunedURLs(context.Background(), feed.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pruned) != 2 || slices.Contains(pruned, env.url("/articles/ancient")) {
+				t.Errorf("pruned URLs after fetching again: got %q", pruned)
+			}
+			assertTitles(t, env.browse("10", "--feed", env.url(newsFeedPath)), "Go 1.30 is out")
+
+			assertContains(t, env.mustRun("retention", tech, "--keep", "default", "--max-age", "default"),
+				"* Keep posts:    default (no limit)\n* Max age:       default (30d)\n")
+			assertContains(t, env.mustRun("retention", tech, "--max-age", "36h"), "* Max age:       36h0m0s\n")
+			assertUsageError(t, env.mustFail("retention", tech, "--keep", "0"))
+			assertUsageError(t, env.mustFail("retention", tech, "--max-age", "soon"))
+			env.mustFail("prune", "--feed", env.url("/missing.xml"))
+
+			// Only the user who added a feed can change its retention; others can look.
+			env.mustRun("register", "bob")
+			env.mustRun("follow", tech)
+			assertContains(t, env.mustRun("retention", tech), "* Max age:       36h0m0s\n")
+			if err := env.mustFail("retention", tech, "--keep", "1"); !strings.Contains(err.Error(), "only the user who added") {
+				t.Errorf("retention --keep by a follower: got %v", err)
+			}
+			env.mustRun("login", "alice")
+
+			env.st.cfgPtr.RetainFor = "forever"
+			if err := env.mustFail("prune"); !strings.Contains(err.Error(), "invalid retain_for") {
+				t.Errorf("prune with an invalid retain_for: got %v", err)
+			}
+		})
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
+
 	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/google/uuid"
 )
 
 // printFeedFollow displays formatted information about a feed follow relationship,
@@ -14,6 +18,26 @@ func printFeedFollow(username string, feedname string) {
 	fmt.Printf("* Feed:          %s\n", feedname)
 }
 
+// printFollowedFeed prints one line of the following list: the feed's name followed by
+// any non-default settings.
+func printFollowedFeed(feedFollow database.GetFeedFollowsForUserRow, prefix string) {
+	var settings []string
+	if feedFollow.Muted {
+		settings = append(settings, "muted")
+	}
+	if feedFollow.Notify {
+		settings = append(settings, "notify")
+	}
+	if feedFollow.Priority != 0 {
+		settings = append(settings, fmt.Sprintf("priority %d", feedFollow.Priority))
+	}
+	if len(settings) > 0 {
+		fmt.Printf("%s* %s (%s)\n", prefix, feedFollow.FeedName, strings.Join(settings, ", "))
+	} else {
+		fmt.Printf("%s* %s\n", prefix, feedFollow.FeedName)
+	}
+}
+
 // printFeed displays detailed information about a feed and its associated user,
 // including ID, timestamps, name, URL, and creator.
 func printFeed(feed database.Feed, user database.User) {
@@ -52,3 +76,118 @@ func middlewareLoggedIn(handler func(stPtr *state, cmd command, user database.Us
 		return handler(stPtr, cmd, user)
 	}
 }
+
+// parseDate parses a date given on the command line, either as a plain date
+// ("2006-01-02", midnight in the configured time zone) or as a full RFC 3339 timestamp.
+func (stPtr *state) parseDate(value string) (time.Time, error) {
+	loc, err := stPtr.location()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or RFC 3339", value)
+	}
+	return t, nil
+}
+
+// location returns the time zone dates are shown and read in: the config file's
+// timezone, an IANA name such as "Europe/Stockholm", or local time when it is unset.
+func (stPtr *state) location() (*time.Location, error) {
+	if stPtr.cfgPtr.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(stPtr.cfgPtr.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone in the config file: %w", err)
+	}
+	return loc, nil
+}
+
+// dateDisplay formats stored times, which are in UTC, for people to read.
+type dateDisplay struct {
+	loc    *time.Location
+	layout string
+}
+
+// dateDisplay returns how to show dates: in the configured time zone and with the
+// config file's date_format, a Go time layout, or defaultLayout when it is unset.
+func (stPtr *state) dateDisplay(defaultLayout string) (dateDisplay, error) {
+	loc, err := stPtr.location()
+	if err != nil {
+		return dateDisplay{}, err
+	}
+	layout := stPtr.cfgPtr.DateFormat
+	if layout == "" {
+		layout = defaultLayout
+	}
+	return dateDisplay{loc: loc, layout: layout}, nil
+}
+
+// format returns t in the display's time zone and layout.
+func (d dateDisplay) format(t time.Time) string {
+	return t.In(d.loc).Format(d.layout)
+}
+
+// formatNull is format for an optional time, returning "undated" when it is unset.
+func (d dateDisplay) formatNull(t sql.NullTime) string {
+	if !t.Valid {
+		return "undated"
+	}
+	return d.format(t.Time)
+}
+
+// minIDPrefix is the shortest post or rule ID prefix accepted on the command line.
+const minIDPrefix = 4
+
+// shortID is the handle shown for a post or rule: the first 8 hex digits of its UUID.
+// Commands accept it (or any longer or slightly shorter prefix) instead of the full ID.
+func shortID(id uuid.UUID) string {
+	return id.String()[:8]
+}
+
+// idPrefixDigits returns the hex digits of an ID prefix given on the command line
+// (hyphens optional), lower-cased, or an error naming the kind of ID if it is too short,
+// too long or not hexadecimal.
+func idPrefixDigits(kind, prefix string) (string, error) {
+	digits := strings.ToLower(strings.ReplaceAll(prefix, "-", ""))
+	if len(digits) < minIDPrefix || len(digits) > 32 {
+		return "", fmt.Errorf("%s ID %q must be between %d and 32 hex digits", kind, prefix, minIDPrefix)
+	}
+	if strings.Trim(digits, "0123456789abcdef") != "" {
+		return "", fmt.Errorf("%s ID %q is not hexadecimal", kind, prefix)
+	}
+	return digits, nil
+}
+
+// postIDRange turns a post ID prefix (hex digits, hyphens optional) into the lowest and
+// highest UUIDs starting with it. A full UUID yields a range of exactly that ID.
+func postIDRange(prefix string) (uuid.UUID, uuid.UUID, error) {
+	digits, err := idPrefixDigits("post", prefix)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	low, err := uuid.Parse(digits + strings.Repeat("0", 32-len(digits)))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	high, err := uuid.Parse(digits + strings.Repeat("f", 32-len(digits)))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	return low, high, nil
+}
+
+// nullTime converts a nullable timestamp for structured output, where a missing time is
+// nil (JSON null, an empty cell) and others are in UTC whatever zone the driver used.
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	utc := t.Time.UTC()
+	return &utc
+}
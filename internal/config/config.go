@@ -19,6 +19,19 @@ const configFileName = ".gatorconfig.json"
 type Config struct {
 	DbURL           string `json:"db_url"`
 	CurrentUserName string `json:"current_user_name"`
+	// TrackingParams lists query parameters stripped from post URLs; a trailing "*"
+	// matches a prefix (e.g. "utm_*"). When empty, urlnorm.DefaultTrackingParams is used.
+	TrackingParams []string `json:"tracking_params,omitempty"`
+	// RetainPosts and RetainFor are the retention policy of feeds without their own: each
+	// feed keeps its RetainPosts most recently saved posts and the posts saved within
+	// RetainFor, a duration such as "720h" or "90d". Zero or empty means no limit.
+	RetainPosts int    `json:"retain_posts,omitempty"`
+	RetainFor   string `json:"retain_for,omitempty"`
+	// Timezone and DateFormat set how dates are shown: in the IANA time zone Timezone
+	// (e.g. "Europe/Stockholm") and with the Go time layout DateFormat (e.g.
+	// "2006-01-02 15:04"). Empty means local time and each command's own layout.
+	Timezone   string `json:"timezone,omitempty"`
+	DateFormat string `json:"date_format,omitempty"`
 }
 
 // SetUser updates CurrentUserName and writes the new config to disk.
@@ -15,12 +15,12 @@ import (
 
 const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
-    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
-    VALUES ($1, $2, $3, $4, $5)
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, folder_id)
+    VALUES ($1, $2, $3, $4, $5, $6)
+    RETURNING id, created_at, updated_at, user_id, feed_id, folder_id, title, muted, notify, priority
 )
 SELECT
-    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id,
+    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.folder_id, inserted_feed_follow.title, inserted_feed_follow.muted, inserted_feed_follow.notify, inserted_feed_follow.priority,
     feeds.name AS feed_name,
     users.name AS user_name
 FROM inserted_feed_follow -- Uses the newly inserted record/row from CTE above!
@@ -34,6 +34,7 @@ type CreateFeedFollowParams struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
 }
 
 type CreateFeedFollowRow struct {
@@ -42,6 +43,11 @@ type CreateFeedFollowRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Title     sql.NullString
+	Muted     bool
+	Notify    bool
+	Priority  int32
 	FeedName  string
 	UserName  sql.NullString
 }
@@ -55,6 +61,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		arg.UpdatedAt,
 		arg.UserID,
 		arg.FeedID,
+		arg.FolderID,
 	)
 	var i CreateFeedFollowRow
 	err := row.Scan(
@@ -63,6 +70,11 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.FolderID,
+		&i.Title,
+		&i.Muted,
+		&i.Notify,
+		&i.Priority,
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -86,23 +98,34 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 
-SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feeds.name AS feed_name, users.name AS user_name
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.folder_id, feed_follows.title, feed_follows.muted, feed_follows.notify, feed_follows.priority, COALESCE(feed_follows.title, feeds.name)::TEXT AS feed_name,
+    users.name AS user_name, folders.name AS folder_name
 FROM feed_follows
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
 INNER JOIN users ON feed_follows.user_id = users.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
 WHERE feed_follows.user_id = $1
+ORDER BY folders.name NULLS FIRST, feed_follows.priority DESC, feed_name
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	FeedName  string
-	UserName  sql.NullString
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	FeedID     uuid.UUID
+	FolderID   uuid.NullUUID
+	Title      sql.NullString
+	Muted      bool
+	Notify     bool
+	Priority   int32
+	FeedName   string
+	UserName   sql.NullString
+	FolderName sql.NullString
 }
 
+// feed_name is the user's own title for the feed when they set one. Ordered by folder
+// (feeds outside any folder first), then priority (highest first) and name.
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
 	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUser, userID)
 	if err != nil {
@@ -118,8 +141,14 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.FolderID,
+			&i.Title,
+			&i.Muted,
+			&i.Notify,
+			&i.Priority,
 			&i.FeedName,
 			&i.UserName,
+			&i.FolderName,
 		); err != nil {
 			return nil, err
 		}
@@ -133,3 +162,83 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const setFeedFollowFolder = `-- name: SetFeedFollowFolder :execrows
+
+UPDATE feed_follows
+SET folder_id = $1, updated_at = $2
+WHERE user_id = $3 AND feed_id = $4
+`
+
+type SetFeedFollowFolderParams struct {
+	FolderID  uuid.NullUUID
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+}
+
+// folder_id NULL takes the feed out of its folder.
+func (q *Queries) SetFeedFollowFolder(ctx context.Context, arg SetFeedFollowFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowFolder,
+		arg.FolderID,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateFeedFollowSettings = `-- name: UpdateFeedFollowSettings :one
+
+UPDATE feed_follows
+SET title = CASE WHEN $1::boolean THEN NULL ELSE COALESCE($2, title) END,
+    muted = COALESCE($3, muted),
+    notify = COALESCE($4, notify),
+    priority = COALESCE($5, priority),
+    updated_at = $6
+WHERE user_id = $7 AND feed_id = $8
+RETURNING id, created_at, updated_at, user_id, feed_id, folder_id, title, muted, notify, priority
+`
+
+type UpdateFeedFollowSettingsParams struct {
+	ClearTitle bool
+	Title      sql.NullString
+	Muted      sql.NullBool
+	Notify     sql.NullBool
+	Priority   sql.NullInt32
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	FeedID     uuid.UUID
+}
+
+// Changes the user's settings for a followed feed. NULL arguments keep the current value;
+// clear_title removes the custom title so the feed's own name is used again.
+func (q *Queries) UpdateFeedFollowSettings(ctx context.Context, arg UpdateFeedFollowSettingsParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedFollowSettings,
+		arg.ClearTitle,
+		arg.Title,
+		arg.Muted,
+		arg.Notify,
+		arg.Priority,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+	)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.FolderID,
+		&i.Title,
+		&i.Muted,
+		&i.Notify,
+		&i.Priority,
+	)
+	return i, err
+}
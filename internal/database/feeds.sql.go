@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,7 +23,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retain_posts, retain_seconds
 `
 
 type CreateFeedParams struct {
@@ -52,12 +53,38 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetainPosts,
+		&i.RetainSeconds,
+	)
+	return i, err
+}
+
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retain_posts, retain_seconds FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetainPosts,
+		&i.RetainSeconds,
 	)
 	return i, err
 }
 
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retain_posts, retain_seconds FROM feeds
 WHERE url = $1
 `
 
@@ -72,12 +99,15 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetainPosts,
+		&i.RetainSeconds,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retain_posts, retain_seconds FROM feeds
 `
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
@@ -97,6 +127,9 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.FetchFullContent,
+			&i.RetainPosts,
+			&i.RetainSeconds,
 		); err != nil {
 			return nil, err
 		}
@@ -112,7 +145,7 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retain_posts, retain_seconds FROM feeds
 ORDER BY last_fetched_at ASC NULLS FIRST
 LIMIT 1
 `
@@ -128,20 +161,127 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetainPosts,
+		&i.RetainSeconds,
+	)
+	return i, err
+}
+
+const getNextPolledFeedToFetch = `-- name: GetNextPolledFeedToFetch :one
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.fetch_full_content, feeds.retain_posts, feeds.retain_seconds FROM feeds
+LEFT JOIN websub_subscriptions ON websub_subscriptions.feed_id = feeds.id
+    AND websub_subscriptions.lease_expires_at > $1::timestamptz
+WHERE websub_subscriptions.id IS NULL
+ORDER BY feeds.last_fetched_at ASC NULLS FIRST
+LIMIT 1
+`
+
+// Same as GetNextFeedToFetch, but skips feeds that currently receive WebSub pushes.
+func (q *Queries) GetNextPolledFeedToFetch(ctx context.Context, now time.Time) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getNextPolledFeedToFetch, now)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetainPosts,
+		&i.RetainSeconds,
 	)
 	return i, err
 }
 
 const markFeedFetched = `-- name: MarkFeedFetched :one
 UPDATE feeds
-SET last_fetched_at = NOW(),
-updated_at = NOW()
+SET last_fetched_at = $1::timestamptz,
+updated_at = $1::timestamptz
+WHERE id = $2
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retain_posts, retain_seconds
+`
+
+type MarkFeedFetchedParams struct {
+	Now time.Time
+	ID  uuid.UUID
+}
+
+func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, markFeedFetched, arg.Now, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetainPosts,
+		&i.RetainSeconds,
+	)
+	return i, err
+}
+
+const setFeedFetchFullContent = `-- name: SetFeedFetchFullContent :one
+UPDATE feeds
+SET fetch_full_content = $2,
+updated_at = $3
 WHERE id = $1
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retain_posts, retain_seconds
 `
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, markFeedFetched, id)
+type SetFeedFetchFullContentParams struct {
+	ID               uuid.UUID
+	FetchFullContent bool
+	UpdatedAt        time.Time
+}
+
+func (q *Queries) SetFeedFetchFullContent(ctx context.Context, arg SetFeedFetchFullContentParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedFetchFullContent, arg.ID, arg.FetchFullContent, arg.UpdatedAt)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetainPosts,
+		&i.RetainSeconds,
+	)
+	return i, err
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :one
+UPDATE feeds
+SET retain_posts = $2,
+retain_seconds = $3,
+updated_at = $4
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retain_posts, retain_seconds
+`
+
+type SetFeedRetentionParams struct {
+	ID            uuid.UUID
+	RetainPosts   sql.NullInt32
+	RetainSeconds sql.NullInt64
+	UpdatedAt     time.Time
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedRetention,
+		arg.ID,
+		arg.RetainPosts,
+		arg.RetainSeconds,
+		arg.UpdatedAt,
+	)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -151,6 +291,9 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) (Feed, erro
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetainPosts,
+		&i.RetainSeconds,
 	)
 	return i, err
 }
@@ -12,13 +12,16 @@ import (
 )
 
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.NullUUID
-	LastFetchedAt sql.NullTime
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Name             string
+	Url              string
+	UserID           uuid.NullUUID
+	LastFetchedAt    sql.NullTime
+	FetchFullContent bool
+	RetainPosts      sql.NullInt32
+	RetainSeconds    sql.NullInt64
 }
 
 type FeedFollow struct {
@@ -27,17 +30,90 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Title     sql.NullString
+	Muted     bool
+	Notify    bool
+	Priority  int32
+}
+
+type Folder struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
 }
 
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        string
+	Url          string
+	Description  sql.NullString
+	PublishedAt  sql.NullTime
+	FeedID       uuid.UUID
+	Content      sql.NullString
+	BaseUrl      sql.NullString
+	OriginalUrl  sql.NullString
+	Fingerprint  sql.NullInt64
+	StoryID      uuid.UUID
+	SearchVector interface{}
+	Author       sql.NullString
+	Categories   []string
+}
+
+type PostHide struct {
+	UserID   uuid.UUID
+	PostID   uuid.UUID
+	HiddenAt time.Time
+}
+
+type PostHighlight struct {
+	UserID        uuid.UUID
+	PostID        uuid.UUID
+	HighlightedAt time.Time
+}
+
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type PostStar struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	StarredAt time.Time
+}
+
+type PostTag struct {
+	TagID    uuid.UUID
+	PostID   uuid.UUID
+	TaggedAt time.Time
+}
+
+type PrunedPost struct {
+	FeedID   uuid.UUID
+	Url      string
+	PrunedAt time.Time
+}
+
+type Rule struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Expression string
+	Action     string
+	Tag        sql.NullString
+}
+
+type Tag struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
 }
 
 type User struct {
@@ -46,3 +122,15 @@ type User struct {
 	UpdatedAt time.Time
 	Name      sql.NullString
 }
+
+type WebsubSubscription struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	FeedID         uuid.UUID
+	HubUrl         string
+	TopicUrl       string
+	Secret         string
+	RequestedAt    time.Time
+	LeaseExpiresAt sql.NullTime
+}
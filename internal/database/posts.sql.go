@@ -11,12 +11,14 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, base_url, original_url, fingerprint, story_id, author, categories)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+ON CONFLICT (feed_id, url) DO NOTHING
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, content, base_url, original_url, fingerprint, story_id, search_vector, author, categories
 `
 
 type CreatePostParams struct {
@@ -28,8 +30,15 @@ type CreatePostParams struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	BaseUrl     sql.NullString
+	OriginalUrl sql.NullString
+	Fingerprint sql.NullInt64
+	StoryID     uuid.UUID
+	Author      sql.NullString
+	Categories  []string
 }
 
+// Inserts nothing, and so returns no row, when the feed already has a post with the URL.
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
 	row := q.db.QueryRowContext(ctx, createPost,
 		arg.ID,
@@ -40,6 +49,12 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.BaseUrl,
+		arg.OriginalUrl,
+		arg.Fingerprint,
+		arg.StoryID,
+		arg.Author,
+		pq.Array(arg.Categories),
 	)
 	var i Post
 	err := row.Scan(
@@ -51,39 +66,341 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Content,
+		&i.BaseUrl,
+		&i.OriginalUrl,
+		&i.Fingerprint,
+		&i.StoryID,
+		&i.SearchVector,
+		&i.Author,
+		pq.Array(&i.Categories),
 	)
 	return i, err
 }
 
-const getPostsForUser = `-- name: GetPostsForUser :many
+const deletePost = `-- name: DeletePost :exec
 
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, feeds.name AS feed_name FROM posts
-JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
-JOIN feeds ON posts.feed_id = feeds.id
-WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2
+DELETE FROM posts WHERE id = $1
 `
 
-type GetPostsForUserParams struct {
+func (q *Queries) DeletePost(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deletePost, id)
+	return err
+}
+
+const findUserPostsByIDRange = `-- name: FindUserPostsByIDRange :many
+
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.base_url, posts.original_url, posts.fingerprint, posts.story_id, posts.search_vector, posts.author, posts.categories, feeds.name AS feed_name FROM posts
+JOIN feeds ON feeds.id = posts.feed_id
+WHERE posts.id BETWEEN $1::uuid AND $2::uuid
+AND (
+    EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = $3::uuid
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id AND post_stars.user_id = $3::uuid
+    )
+)
+ORDER BY posts.id
+LIMIT 10
+`
+
+type FindUserPostsByIDRangeParams struct {
+	LowID  uuid.UUID
+	HighID uuid.UUID
 	UserID uuid.UUID
-	Limit  int32
 }
 
-type GetPostsForUserRow struct {
+type FindUserPostsByIDRangeRow struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        string
+	Url          string
+	Description  sql.NullString
+	PublishedAt  sql.NullTime
+	FeedID       uuid.UUID
+	Content      sql.NullString
+	BaseUrl      sql.NullString
+	OriginalUrl  sql.NullString
+	Fingerprint  sql.NullInt64
+	StoryID      uuid.UUID
+	SearchVector interface{}
+	Author       sql.NullString
+	Categories   []string
+	FeedName     string
+}
+
+// Posts the user can see (followed feeds or starred) whose ID lies in a range; a short
+// ID prefix is turned into the range of all UUIDs starting with it so the primary key
+// index can be used.
+func (q *Queries) FindUserPostsByIDRange(ctx context.Context, arg FindUserPostsByIDRangeParams) ([]FindUserPostsByIDRangeRow, error) {
+	rows, err := q.db.QueryContext(ctx, findUserPostsByIDRange, arg.LowID, arg.HighID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindUserPostsByIDRangeRow
+	for rows.Next() {
+		var i FindUserPostsByIDRangeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.BaseUrl,
+			&i.OriginalUrl,
+			&i.Fingerprint,
+			&i.StoryID,
+			&i.SearchVector,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedPostByURL = `-- name: GetFeedPostByURL :one
+
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, base_url, original_url, fingerprint, story_id, search_vector, author, categories FROM posts
+WHERE feed_id = $1 AND url = $2
+`
+
+type GetFeedPostByURLParams struct {
+	FeedID uuid.UUID
+	Url    string
+}
+
+func (q *Queries) GetFeedPostByURL(ctx context.Context, arg GetFeedPostByURLParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getFeedPostByURL, arg.FeedID, arg.Url)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.BaseUrl,
+		&i.OriginalUrl,
+		&i.Fingerprint,
+		&i.StoryID,
+		&i.SearchVector,
+		&i.Author,
+		pq.Array(&i.Categories),
+	)
+	return i, err
+}
+
+const getPostURLs = `-- name: GetPostURLs :many
+
+SELECT id, feed_id, url, original_url FROM posts
+ORDER BY created_at ASC
+`
+
+type GetPostURLsRow struct {
 	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
-	FeedName    string
+	Url         string
+	OriginalUrl sql.NullString
+}
+
+// Oldest first, so when two posts normalise to the same URL the older one keeps it.
+func (q *Queries) GetPostURLs(ctx context.Context) ([]GetPostURLsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostURLs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostURLsRow
+	for rows.Next() {
+		var i GetPostURLsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FeedID,
+			&i.Url,
+			&i.OriginalUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsForUser = `-- name: GetPostsForUser :many
+
+WITH visible AS (
+    SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.base_url, posts.original_url, posts.fingerprint, posts.story_id, posts.search_vector, posts.author, posts.categories, COALESCE(feed_follows.title, feeds.name)::TEXT AS feed_name, feed_follows.folder_id,
+        EXISTS (
+            SELECT 1 FROM post_reads
+            JOIN posts AS read_posts ON read_posts.id = post_reads.post_id
+            WHERE post_reads.user_id = feed_follows.user_id AND read_posts.story_id = posts.story_id
+        ) AS is_read,
+        EXISTS (
+            SELECT 1 FROM post_stars
+            WHERE post_stars.user_id = feed_follows.user_id AND post_stars.post_id = posts.id
+        ) AS is_starred,
+        EXISTS (
+            SELECT 1 FROM post_highlights
+            WHERE post_highlights.user_id = feed_follows.user_id AND post_highlights.post_id = posts.id
+        ) AS is_highlighted
+    FROM posts
+    JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+    JOIN feeds ON posts.feed_id = feeds.id
+    WHERE feed_follows.user_id = $1
+    AND (NOT feed_follows.muted OR feed_follows.feed_id = $7::uuid)
+    AND ($8::boolean OR NOT EXISTS (
+        SELECT 1 FROM post_hides
+        WHERE post_hides.user_id = feed_follows.user_id AND post_hides.post_id = posts.id
+    ))
+), stories AS (
+    SELECT DISTINCT ON (visible.story_id) visible.id, visible.created_at, visible.updated_at, visible.title, visible.url, visible.description, visible.published_at, visible.feed_id, visible.content, visible.base_url, visible.original_url, visible.fingerprint, visible.story_id, visible.search_vector, visible.author, visible.categories, visible.feed_name, visible.folder_id, visible.is_read, visible.is_starred, visible.is_highlighted FROM visible
+    WHERE NOT ($9::boolean AND visible.is_read)
+    AND (NOT $10::boolean OR visible.is_starred)
+    AND ($11::text IS NULL OR EXISTS (
+        SELECT 1 FROM post_tags
+        JOIN tags ON tags.id = post_tags.tag_id
+        WHERE post_tags.post_id = visible.id AND tags.user_id = $1 AND tags.name = $11::text
+    ))
+    AND ($7::uuid IS NULL OR visible.feed_id = $7::uuid)
+    AND ($12::uuid IS NULL OR visible.folder_id = $12::uuid)
+    AND ($13::timestamptz IS NULL OR COALESCE(visible.published_at, visible.created_at) >= $13::timestamptz)
+    AND ($14::timestamptz IS NULL OR COALESCE(visible.published_at, visible.created_at) < $14::timestamptz)
+    ORDER BY visible.story_id, visible.published_at ASC NULLS LAST, visible.created_at ASC
+), keyed AS (
+    SELECT stories.id, stories.created_at, stories.updated_at, stories.title, stories.url, stories.description, stories.published_at, stories.feed_id, stories.content, stories.base_url, stories.original_url, stories.fingerprint, stories.story_id, stories.search_vector, stories.author, stories.categories, stories.feed_name, stories.folder_id, stories.is_read, stories.is_starred, stories.is_highlighted,
+        (CASE WHEN $15::text = 'feed' THEN stories.feed_name ELSE '' END)::TEXT AS sort_group,
+        COALESCE(
+            CASE WHEN $15::text = 'fetched' THEN stories.created_at ELSE stories.published_at END,
+            '0001-01-01 00:00:00+00'::timestamptz
+        )::TIMESTAMPTZ AS sort_time
+    FROM stories
+)
+SELECT keyed.id, keyed.created_at, keyed.updated_at, keyed.title, keyed.url, keyed.description, keyed.published_at, keyed.feed_id, keyed.content, keyed.base_url, keyed.original_url, keyed.fingerprint, keyed.story_id, keyed.search_vector, keyed.author, keyed.categories, keyed.feed_name, keyed.folder_id, keyed.is_read, keyed.is_starred, keyed.is_highlighted, keyed.sort_group, keyed.sort_time,
+    ARRAY(
+        SELECT DISTINCT visible.feed_name FROM visible
+        WHERE visible.story_id = keyed.story_id AND visible.feed_id <> keyed.feed_id
+        ORDER BY visible.feed_name
+    )::TEXT[] AS also_in,
+    ARRAY(
+        SELECT tags.name FROM post_tags
+        JOIN tags ON tags.id = post_tags.tag_id
+        WHERE post_tags.post_id = keyed.id AND tags.user_id = $1
+        ORDER BY tags.name
+    )::TEXT[] AS tags
+FROM keyed
+WHERE $2::uuid IS NULL
+OR keyed.sort_group > $3::text
+OR (keyed.sort_group = $3::text AND keyed.sort_time < $4::timestamptz)
+OR (keyed.sort_group = $3::text AND keyed.sort_time = $4::timestamptz AND keyed.id < $2::uuid)
+ORDER BY keyed.sort_group ASC, keyed.sort_time DESC, keyed.id DESC
+OFFSET $5
+LIMIT $6
+`
+
+type GetPostsForUserParams struct {
+	UserID      uuid.UUID
+	CursorID    uuid.NullUUID
+	CursorGroup sql.NullString
+	CursorTime  sql.NullTime
+	Offset      int32
+	Limit       int32
+	FeedID      uuid.NullUUID
+	ShowHidden  bool
+	UnreadOnly  bool
+	StarredOnly bool
+	Tag         sql.NullString
+	FolderID    uuid.NullUUID
+	Since       sql.NullTime
+	Until       sql.NullTime
+	Sort        string
+}
+
+type GetPostsForUserRow struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Title         string
+	Url           string
+	Description   sql.NullString
+	PublishedAt   sql.NullTime
+	FeedID        uuid.UUID
+	Content       sql.NullString
+	BaseUrl       sql.NullString
+	OriginalUrl   sql.NullString
+	Fingerprint   sql.NullInt64
+	StoryID       uuid.UUID
+	SearchVector  interface{}
+	Author        sql.NullString
+	Categories    []string
+	FeedName      string
+	FolderID      uuid.NullUUID
+	IsRead        bool
+	IsStarred     bool
+	IsHighlighted bool
+	SortGroup     string
+	SortTime      time.Time
+	AlsoIn        []string
+	Tags          []string
 }
 
+// Returns one row per story: when the same story arrived through several followed feeds,
+// the earliest published copy is shown and the other feeds are listed in also_in. Feeds
+// are named by the user's own title when they set one, and muted feeds are left out
+// unless they are asked for with feed_id, as are posts a rule hid unless show_hidden.
+// Optional filters narrow the stories by read/starred state, tag, feed, folder and date
+// range (the publication date, or the fetch date for posts without one). A story is read
+// once any copy of it is, so a copy arriving later through another feed isn't new again.
+//
+// Rows are ordered by (sort_group ASC, sort_time DESC, id DESC): sort_group is the feed
+// name when sorting by feed and ” otherwise, sort_time the publication or fetch time
+// with missing dates mapped to year 1 so they come last. Passing the last row's keys as
+// the cursor continues after it, which stays stable while new posts arrive.
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.CursorID,
+		arg.CursorGroup,
+		arg.CursorTime,
+		arg.Offset,
+		arg.Limit,
+		arg.FeedID,
+		arg.ShowHidden,
+		arg.UnreadOnly,
+		arg.StarredOnly,
+		arg.Tag,
+		arg.FolderID,
+		arg.Since,
+		arg.Until,
+		arg.Sort,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -100,7 +417,189 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Content,
+			&i.BaseUrl,
+			&i.OriginalUrl,
+			&i.Fingerprint,
+			&i.StoryID,
+			&i.SearchVector,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.FeedName,
+			&i.FolderID,
+			&i.IsRead,
+			&i.IsStarred,
+			&i.IsHighlighted,
+			&i.SortGroup,
+			&i.SortTime,
+			pq.Array(&i.AlsoIn),
+			pq.Array(&i.Tags),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentPostFingerprints = `-- name: GetRecentPostFingerprints :many
+
+SELECT id, story_id, fingerprint FROM posts
+WHERE fingerprint IS NOT NULL AND feed_id <> $1 AND created_at > $2
+`
+
+type GetRecentPostFingerprintsParams struct {
+	FeedID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type GetRecentPostFingerprintsRow struct {
+	ID          uuid.UUID
+	StoryID     uuid.UUID
+	Fingerprint sql.NullInt64
+}
+
+// Fingerprinted posts from other feeds since a cutoff, to match near-duplicate titles against.
+func (q *Queries) GetRecentPostFingerprints(ctx context.Context, arg GetRecentPostFingerprintsParams) ([]GetRecentPostFingerprintsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentPostFingerprints, arg.FeedID, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRecentPostFingerprintsRow
+	for rows.Next() {
+		var i GetRecentPostFingerprintsRow
+		if err := rows.Scan(&i.ID, &i.StoryID, &i.Fingerprint); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStoryIDByURL = `-- name: GetStoryIDByURL :one
+
+SELECT story_id FROM posts
+WHERE url = $1 AND feed_id <> $2
+ORDER BY created_at ASC
+LIMIT 1
+`
+
+type GetStoryIDByURLParams struct {
+	Url    string
+	FeedID uuid.UUID
+}
+
+// Story of a post from another feed linking to the same (normalised) URL, if any.
+func (q *Queries) GetStoryIDByURL(ctx context.Context, arg GetStoryIDByURLParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getStoryIDByURL, arg.Url, arg.FeedID)
+	var story_id uuid.UUID
+	err := row.Scan(&story_id)
+	return story_id, err
+}
+
+const searchPosts = `-- name: SearchPosts :many
+
+WITH search AS (
+    SELECT websearch_to_tsquery('english', $4::text) AS query
+)
+SELECT posts.id, posts.title, posts.url, posts.published_at, feeds.name AS feed_name,
+    posts.description, posts.content, posts.base_url,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        JOIN posts AS read_posts ON read_posts.id = post_reads.post_id
+        WHERE post_reads.user_id = $1 AND read_posts.story_id = posts.story_id
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.user_id = $1 AND post_stars.post_id = posts.id
+    ) AS is_starred,
+    ts_rank_cd(posts.search_vector, search.query)::REAL AS rank,
+    ts_headline(
+        'english',
+        coalesce(posts.title, '') || ' — ' ||
+            regexp_replace(coalesce(posts.content, posts.description, ''), '<[^>]*>', ' ', 'g'),
+        search.query,
+        'StartSel=<b>, StopSel=</b>, MaxFragments=2, MaxWords=20, MinWords=8'
+    )::TEXT AS snippet
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+CROSS JOIN search
+WHERE posts.search_vector @@ search.query
+AND ($2::boolean OR EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = $1
+))
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST
+LIMIT $3
+`
+
+type SearchPostsParams struct {
+	UserID   uuid.UUID
+	AllFeeds bool
+	Limit    int32
+	Query    string
+}
+
+type SearchPostsRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	PublishedAt sql.NullTime
+	FeedName    string
+	Description sql.NullString
+	Content     sql.NullString
+	BaseUrl     sql.NullString
+	IsRead      bool
+	IsStarred   bool
+	Rank        float32
+	Snippet     string
+}
+
+// query uses web search syntax: "quoted phrases", OR, and -excluded words. Results are
+// ranked by cover density (title matches weigh most) and carry a snippet of the title
+// and text with the matches wrapped in <b></b>, plus the post body and the user's read
+// (of any copy of the story) and star state for readers that show the whole post. Unless all_feeds is set, only
+// posts of feeds the user follows are searched.
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPosts,
+		arg.UserID,
+		arg.AllFeeds,
+		arg.Limit,
+		arg.Query,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsRow
+	for rows.Next() {
+		var i SearchPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.PublishedAt,
 			&i.FeedName,
+			&i.Description,
+			&i.Content,
+			&i.BaseUrl,
+			&i.IsRead,
+			&i.IsStarred,
+			&i.Rank,
+			&i.Snippet,
 		); err != nil {
 			return nil, err
 		}
@@ -114,3 +613,64 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const setPostStory = `-- name: SetPostStory :exec
+
+UPDATE posts
+SET story_id = $2
+WHERE id = $1
+`
+
+type SetPostStoryParams struct {
+	ID      uuid.UUID
+	StoryID uuid.UUID
+}
+
+func (q *Queries) SetPostStory(ctx context.Context, arg SetPostStoryParams) error {
+	_, err := q.db.ExecContext(ctx, setPostStory, arg.ID, arg.StoryID)
+	return err
+}
+
+const updatePostContent = `-- name: UpdatePostContent :exec
+UPDATE posts
+SET content = $2,
+updated_at = $3
+WHERE id = $1
+`
+
+type UpdatePostContentParams struct {
+	ID        uuid.UUID
+	Content   sql.NullString
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdatePostContent(ctx context.Context, arg UpdatePostContentParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostContent, arg.ID, arg.Content, arg.UpdatedAt)
+	return err
+}
+
+const updatePostURL = `-- name: UpdatePostURL :exec
+
+UPDATE posts
+SET url = $2,
+original_url = $3,
+updated_at = $4
+WHERE id = $1
+`
+
+type UpdatePostURLParams struct {
+	ID          uuid.UUID
+	Url         string
+	OriginalUrl sql.NullString
+	UpdatedAt   time.Time
+}
+
+func (q *Queries) UpdatePostURL(ctx context.Context, arg UpdatePostURLParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostURL,
+		arg.ID,
+		arg.Url,
+		arg.OriginalUrl,
+		arg.UpdatedAt,
+	)
+	return err
+}
@@ -0,0 +1,166 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: pruned_posts.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createPrunedPost = `-- name: CreatePrunedPost :exec
+INSERT INTO pruned_posts (feed_id, url, pruned_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (feed_id, url) DO NOTHING
+`
+
+type CreatePrunedPostParams struct {
+	FeedID   uuid.UUID
+	Url      string
+	PrunedAt time.Time
+}
+
+func (q *Queries) CreatePrunedPost(ctx context.Context, arg CreatePrunedPostParams) error {
+	_, err := q.db.ExecContext(ctx, createPrunedPost, arg.FeedID, arg.Url, arg.PrunedAt)
+	return err
+}
+
+const deletePrunedPost = `-- name: DeletePrunedPost :exec
+DELETE FROM pruned_posts WHERE feed_id = $1 AND url = $2
+`
+
+type DeletePrunedPostParams struct {
+	FeedID uuid.UUID
+	Url    string
+}
+
+// Forgets a pruned post once its feed no longer lists it, so tombstones don't pile up.
+func (q *Queries) DeletePrunedPost(ctx context.Context, arg DeletePrunedPostParams) error {
+	_, err := q.db.ExecContext(ctx, deletePrunedPost, arg.FeedID, arg.Url)
+	return err
+}
+
+const getFeedPrunedURLs = `-- name: GetFeedPrunedURLs :many
+SELECT url FROM pruned_posts WHERE feed_id = $1 ORDER BY url
+`
+
+func (q *Queries) GetFeedPrunedURLs(ctx context.Context, feedID uuid.UUID) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedPrunedURLs, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		items = append(items, url)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPrunablePosts = `-- name: GetPrunablePosts :many
+WITH ranked AS (
+    SELECT posts.id, posts.feed_id, feeds.name AS feed_name, posts.title, posts.url, posts.created_at,
+        ROW_NUMBER() OVER (PARTITION BY posts.feed_id ORDER BY posts.created_at DESC, posts.id DESC) AS position,
+        COALESCE(feeds.retain_posts, $2::integer) AS keep_posts,
+        COALESCE(feeds.retain_seconds, $3::bigint) AS keep_seconds
+    FROM posts
+    JOIN feeds ON feeds.id = posts.feed_id
+    WHERE $4::uuid IS NULL OR posts.feed_id = $4::uuid
+)
+SELECT ranked.id, ranked.feed_id, ranked.feed_name, ranked.title, ranked.url, ranked.created_at
+FROM ranked
+WHERE ((ranked.keep_posts > 0 AND ranked.position > ranked.keep_posts)
+    OR (ranked.keep_seconds > 0 AND ranked.created_at < $1::timestamptz - ranked.keep_seconds * INTERVAL '1 second'))
+    AND NOT EXISTS (SELECT 1 FROM post_stars WHERE post_stars.post_id = ranked.id)
+    AND NOT EXISTS (SELECT 1 FROM post_tags WHERE post_tags.post_id = ranked.id)
+ORDER BY ranked.feed_name, ranked.feed_id, ranked.created_at DESC, ranked.id DESC
+`
+
+type GetPrunablePostsParams struct {
+	Now            time.Time
+	DefaultPosts   int32
+	DefaultSeconds int64
+	FeedID         uuid.NullUUID
+}
+
+type GetPrunablePostsRow struct {
+	ID        uuid.UUID
+	FeedID    uuid.UUID
+	FeedName  string
+	Title     string
+	Url       string
+	CreatedAt time.Time
+}
+
+// Posts beyond their feed's retention: past its retain_posts most recent posts, or saved
+// more than retain_seconds before now, both by when the post was saved. default_posts
+// and default_seconds stand in for a feed's unset (NULL) settings, and 0 means no
+// limit. Posts anyone starred or tagged are never returned, though they count towards
+// the most recent. feed_id, if given, limits the check to one feed.
+func (q *Queries) GetPrunablePosts(ctx context.Context, arg GetPrunablePostsParams) ([]GetPrunablePostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPrunablePosts,
+		arg.Now,
+		arg.DefaultPosts,
+		arg.DefaultSeconds,
+		arg.FeedID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPrunablePostsRow
+	for rows.Next() {
+		var i GetPrunablePostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FeedID,
+			&i.FeedName,
+			&i.Title,
+			&i.Url,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const isPostPruned = `-- name: IsPostPruned :one
+SELECT EXISTS (
+    SELECT 1 FROM pruned_posts WHERE feed_id = $1 AND url = $2
+)
+`
+
+type IsPostPrunedParams struct {
+	FeedID uuid.UUID
+	Url    string
+}
+
+// Whether a post with the URL was pruned from the feed, and so shouldn't be saved again.
+func (q *Queries) IsPostPruned(ctx context.Context, arg IsPostPrunedParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isPostPruned, arg.FeedID, arg.Url)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
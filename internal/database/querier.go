@@ -0,0 +1,189 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Querier interface {
+	//
+	ConfirmWebSubSubscription(ctx context.Context, arg ConfirmWebSubSubscriptionParams) (WebsubSubscription, error)
+	CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error)
+	// This query creates a new feed follow record and returns detailed information about it
+	// Uses a CTE (Common Table Expression) to first insert a new row and save it as a special variable/name, then join with related tables
+	CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error)
+	CreateFolder(ctx context.Context, arg CreateFolderParams) (Folder, error)
+	// Inserts nothing, and so returns no row, when the feed already has a post with the URL.
+	CreatePost(ctx context.Context, arg CreatePostParams) (Post, error)
+	CreatePrunedPost(ctx context.Context, arg CreatePrunedPostParams) error
+	CreateRule(ctx context.Context, arg CreateRuleParams) (Rule, error)
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	//
+	DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowParams) error
+	//
+	DeleteFolder(ctx context.Context, arg DeleteFolderParams) (int64, error)
+	//
+	DeletePost(ctx context.Context, id uuid.UUID) error
+	// Forgets a pruned post once its feed no longer lists it, so tombstones don't pile up.
+	DeletePrunedPost(ctx context.Context, arg DeletePrunedPostParams) error
+	//
+	DeleteRule(ctx context.Context, arg DeleteRuleParams) (int64, error)
+	//
+	// Removes the user's tags that no longer label any post.
+	DeleteUnusedTags(ctx context.Context, userID uuid.UUID) error
+	DeleteUsers(ctx context.Context) error
+	//
+	DeleteWebSubSubscription(ctx context.Context, id uuid.UUID) error
+	//
+	// Posts the user can see (followed feeds or starred) whose ID lies in a range; a short
+	// ID prefix is turned into the range of all UUIDs starting with it so the primary key
+	// index can be used.
+	FindUserPostsByIDRange(ctx context.Context, arg FindUserPostsByIDRangeParams) ([]FindUserPostsByIDRangeRow, error)
+	GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error)
+	GetFeedByURL(ctx context.Context, url string) (Feed, error)
+	//
+	// feed_name is the user's own title for the feed when they set one. Ordered by folder
+	// (feeds outside any folder first), then priority (highest first) and name.
+	GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error)
+	//
+	GetFeedPostByURL(ctx context.Context, arg GetFeedPostByURLParams) (Post, error)
+	GetFeedPrunedURLs(ctx context.Context, feedID uuid.UUID) ([]string, error)
+	GetFeeds(ctx context.Context) ([]Feed, error)
+	//
+	GetFolderByName(ctx context.Context, arg GetFolderByNameParams) (Folder, error)
+	//
+	GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]Folder, error)
+	GetNextFeedToFetch(ctx context.Context) (Feed, error)
+	// Same as GetNextFeedToFetch, but skips feeds that currently receive WebSub pushes.
+	GetNextPolledFeedToFetch(ctx context.Context, now time.Time) (Feed, error)
+	//
+	// Oldest first, so when two posts normalise to the same URL the older one keeps it.
+	GetPostURLs(ctx context.Context) ([]GetPostURLsRow, error)
+	//
+	// Returns one row per story: when the same story arrived through several followed feeds,
+	// the earliest published copy is shown and the other feeds are listed in also_in. Feeds
+	// are named by the user's own title when they set one, and muted feeds are left out
+	// unless they are asked for with feed_id, as are posts a rule hid unless show_hidden.
+	// Optional filters narrow the stories by read/starred state, tag, feed, folder and date
+	// range (the publication date, or the fetch date for posts without one). A story is read
+	// once any copy of it is, so a copy arriving later through another feed isn't new again.
+	//
+	// Rows are ordered by (sort_group ASC, sort_time DESC, id DESC): sort_group is the feed
+	// name when sorting by feed and '' otherwise, sort_time the publication or fetch time
+	// with missing dates mapped to year 1 so they come last. Passing the last row's keys as
+	// the cursor continues after it, which stays stable while new posts arrive.
+	GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error)
+	// Posts beyond their feed's retention: past its retain_posts most recent posts, or saved
+	// more than retain_seconds before now, both by when the post was saved. default_posts
+	// and default_seconds stand in for a feed's unset (NULL) settings, and 0 means no
+	// limit. Posts anyone starred or tagged are never returned, though they count towards
+	// the most recent. feed_id, if given, limits the check to one feed.
+	GetPrunablePosts(ctx context.Context, arg GetPrunablePostsParams) ([]GetPrunablePostsRow, error)
+	//
+	// Fingerprinted posts from other feeds since a cutoff, to match near-duplicate titles against.
+	GetRecentPostFingerprints(ctx context.Context, arg GetRecentPostFingerprintsParams) ([]GetRecentPostFingerprintsRow, error)
+	//
+	// Everything a rule can match on, for each post in the feeds the user follows.
+	GetRuleCandidatePosts(ctx context.Context, userID uuid.UUID) ([]GetRuleCandidatePostsRow, error)
+	//
+	// The rules of every user following the feed, with that user's own title for it.
+	GetRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]GetRulesForFeedRow, error)
+	//
+	GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]Rule, error)
+	//
+	// Starred posts are listed whether or not the user still follows their feed.
+	GetStarredPostsForUser(ctx context.Context, arg GetStarredPostsForUserParams) ([]GetStarredPostsForUserRow, error)
+	//
+	// Story of a post from another feed linking to the same (normalised) URL, if any.
+	GetStoryIDByURL(ctx context.Context, arg GetStoryIDByURLParams) (uuid.UUID, error)
+	//
+	GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]GetTagsForUserRow, error)
+	GetUser(ctx context.Context, name sql.NullString) (User, error)
+	GetUserByUUID(ctx context.Context, id uuid.UUID) (User, error)
+	GetUsers(ctx context.Context) ([]sql.NullString, error)
+	//
+	GetWebSubSubscription(ctx context.Context, id uuid.UUID) (WebsubSubscription, error)
+	//
+	GetWebSubSubscriptionForFeed(ctx context.Context, feedID uuid.UUID) (WebsubSubscription, error)
+	//
+	// Subscriptions whose lease runs out soon and that were not already re-requested recently.
+	GetWebSubSubscriptionsToRenew(ctx context.Context, arg GetWebSubSubscriptionsToRenewParams) ([]WebsubSubscription, error)
+	//
+	HidePost(ctx context.Context, arg HidePostParams) (int64, error)
+	//
+	HighlightPost(ctx context.Context, arg HighlightPostParams) (int64, error)
+	// Whether a post with the URL was pruned from the feed, and so shouldn't be saved again.
+	IsPostPruned(ctx context.Context, arg IsPostPrunedParams) (bool, error)
+	//
+	// Marks every post in the user's followed feeds read, optionally only for one feed and/or
+	// only posts published (or, lacking a date, fetched) before a cutoff.
+	MarkAllPostsRead(ctx context.Context, arg MarkAllPostsReadParams) (int64, error)
+	MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) (Feed, error)
+	// Marks a post read together with the other copies of its story, since browse shows a
+	// story only once.
+	MarkPostRead(ctx context.Context, arg MarkPostReadParams) (int64, error)
+	//
+	MarkPostUnread(ctx context.Context, arg MarkPostUnreadParams) (int64, error)
+	//
+	// Copies hides from a post that is about to be merged into another one.
+	MovePostHides(ctx context.Context, arg MovePostHidesParams) error
+	//
+	// Copies highlights from a post that is about to be merged into another one.
+	MovePostHighlights(ctx context.Context, arg MovePostHighlightsParams) error
+	//
+	// Copies read state from a post that is about to be merged into another one.
+	MovePostReads(ctx context.Context, arg MovePostReadsParams) error
+	//
+	// Copies stars from a post that is about to be merged into another one.
+	MovePostStars(ctx context.Context, arg MovePostStarsParams) error
+	//
+	// Copies tags from a post that is about to be merged into another one.
+	MovePostTags(ctx context.Context, arg MovePostTagsParams) error
+	//
+	RenameFolder(ctx context.Context, arg RenameFolderParams) (int64, error)
+	//
+	// query uses web search syntax: "quoted phrases", OR, and -excluded words. Results are
+	// ranked by cover density (title matches weigh most) and carry a snippet of the title
+	// and text with the matches wrapped in <b></b>, plus the post body and the user's read
+	// (of any copy of the story) and star state for readers that show the whole post. Unless all_feeds is set, only
+	// posts of feeds the user follows are searched.
+	SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error)
+	SetFeedFetchFullContent(ctx context.Context, arg SetFeedFetchFullContentParams) (Feed, error)
+	//
+	// folder_id NULL takes the feed out of its folder.
+	SetFeedFollowFolder(ctx context.Context, arg SetFeedFollowFolderParams) (int64, error)
+	SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) (Feed, error)
+	//
+	SetPostStory(ctx context.Context, arg SetPostStoryParams) error
+	StarPost(ctx context.Context, arg StarPostParams) (int64, error)
+	//
+	TagPost(ctx context.Context, arg TagPostParams) (int64, error)
+	//
+	UnstarPost(ctx context.Context, arg UnstarPostParams) (int64, error)
+	//
+	UntagPost(ctx context.Context, arg UntagPostParams) (int64, error)
+	//
+	// Changes the user's settings for a followed feed. NULL arguments keep the current value;
+	// clear_title removes the custom title so the feed's own name is used again.
+	UpdateFeedFollowSettings(ctx context.Context, arg UpdateFeedFollowSettingsParams) (FeedFollow, error)
+	//
+	UpdatePostContent(ctx context.Context, arg UpdatePostContentParams) error
+	//
+	UpdatePostURL(ctx context.Context, arg UpdatePostURLParams) error
+	// Returns the user's tag with this name, creating it first if needed.
+	UpsertTag(ctx context.Context, arg UpsertTagParams) (Tag, error)
+	// Creates the subscription for a feed, or points an existing one at a (possibly new) hub.
+	// Renewing at the same hub and topic keeps the secret, so pushes signed during the renewal
+	// still verify, and the lease. A new hub or topic gets the new secret and no lease until
+	// it verifies, so the feed is polled in the meantime and the old hub's pushes are refused.
+	UpsertWebSubSubscription(ctx context.Context, arg UpsertWebSubSubscriptionParams) (WebsubSubscription, error)
+}
+
+var _ Querier = (*Queries)(nil)
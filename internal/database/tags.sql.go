@@ -0,0 +1,158 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: tags.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const deleteUnusedTags = `-- name: DeleteUnusedTags :exec
+
+DELETE FROM tags
+WHERE tags.user_id = $1
+AND NOT EXISTS (SELECT 1 FROM post_tags WHERE post_tags.tag_id = tags.id)
+`
+
+// Removes the user's tags that no longer label any post.
+func (q *Queries) DeleteUnusedTags(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUnusedTags, userID)
+	return err
+}
+
+const getTagsForUser = `-- name: GetTagsForUser :many
+
+SELECT tags.name, COUNT(post_tags.post_id) AS post_count
+FROM tags
+JOIN post_tags ON post_tags.tag_id = tags.id
+WHERE tags.user_id = $1
+GROUP BY tags.id, tags.name
+ORDER BY tags.name
+`
+
+type GetTagsForUserRow struct {
+	Name      string
+	PostCount int64
+}
+
+func (q *Queries) GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]GetTagsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTagsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTagsForUserRow
+	for rows.Next() {
+		var i GetTagsForUserRow
+		if err := rows.Scan(&i.Name, &i.PostCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const movePostTags = `-- name: MovePostTags :exec
+
+INSERT INTO post_tags (tag_id, post_id, tagged_at)
+SELECT post_tags.tag_id, $1::uuid, post_tags.tagged_at FROM post_tags
+WHERE post_tags.post_id = $2::uuid
+ON CONFLICT (tag_id, post_id) DO NOTHING
+`
+
+type MovePostTagsParams struct {
+	ToPostID   uuid.UUID
+	FromPostID uuid.UUID
+}
+
+// Copies tags from a post that is about to be merged into another one.
+func (q *Queries) MovePostTags(ctx context.Context, arg MovePostTagsParams) error {
+	_, err := q.db.ExecContext(ctx, movePostTags, arg.ToPostID, arg.FromPostID)
+	return err
+}
+
+const tagPost = `-- name: TagPost :execrows
+
+INSERT INTO post_tags (tag_id, post_id, tagged_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (tag_id, post_id) DO NOTHING
+`
+
+type TagPostParams struct {
+	TagID    uuid.UUID
+	PostID   uuid.UUID
+	TaggedAt time.Time
+}
+
+func (q *Queries) TagPost(ctx context.Context, arg TagPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, tagPost, arg.TagID, arg.PostID, arg.TaggedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const untagPost = `-- name: UntagPost :execrows
+
+DELETE FROM post_tags
+USING tags
+WHERE post_tags.tag_id = tags.id
+AND tags.user_id = $1 AND tags.name = $2 AND post_tags.post_id = $3
+`
+
+type UntagPostParams struct {
+	UserID uuid.UUID
+	Name   string
+	PostID uuid.UUID
+}
+
+func (q *Queries) UntagPost(ctx context.Context, arg UntagPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, untagPost, arg.UserID, arg.Name, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const upsertTag = `-- name: UpsertTag :one
+INSERT INTO tags (id, created_at, user_id, name)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING id, created_at, user_id, name
+`
+
+type UpsertTagParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+}
+
+// Returns the user's tag with this name, creating it first if needed.
+func (q *Queries) UpsertTag(ctx context.Context, arg UpsertTagParams) (Tag, error) {
+	row := q.db.QueryRowContext(ctx, upsertTag,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UserID,
+		arg.Name,
+	)
+	var i Tag
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.Name,
+	)
+	return i, err
+}
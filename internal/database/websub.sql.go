@@ -0,0 +1,205 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: websub.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const confirmWebSubSubscription = `-- name: ConfirmWebSubSubscription :one
+
+UPDATE websub_subscriptions
+SET lease_expires_at = $2,
+updated_at = $3
+WHERE id = $1
+RETURNING id, created_at, updated_at, feed_id, hub_url, topic_url, secret, requested_at, lease_expires_at
+`
+
+type ConfirmWebSubSubscriptionParams struct {
+	ID             uuid.UUID
+	LeaseExpiresAt sql.NullTime
+	UpdatedAt      time.Time
+}
+
+func (q *Queries) ConfirmWebSubSubscription(ctx context.Context, arg ConfirmWebSubSubscriptionParams) (WebsubSubscription, error) {
+	row := q.db.QueryRowContext(ctx, confirmWebSubSubscription, arg.ID, arg.LeaseExpiresAt, arg.UpdatedAt)
+	var i WebsubSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.Secret,
+		&i.RequestedAt,
+		&i.LeaseExpiresAt,
+	)
+	return i, err
+}
+
+const deleteWebSubSubscription = `-- name: DeleteWebSubSubscription :exec
+
+DELETE FROM websub_subscriptions WHERE id = $1
+`
+
+func (q *Queries) DeleteWebSubSubscription(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteWebSubSubscription, id)
+	return err
+}
+
+const getWebSubSubscription = `-- name: GetWebSubSubscription :one
+
+SELECT id, created_at, updated_at, feed_id, hub_url, topic_url, secret, requested_at, lease_expires_at FROM websub_subscriptions
+WHERE id = $1
+`
+
+func (q *Queries) GetWebSubSubscription(ctx context.Context, id uuid.UUID) (WebsubSubscription, error) {
+	row := q.db.QueryRowContext(ctx, getWebSubSubscription, id)
+	var i WebsubSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.Secret,
+		&i.RequestedAt,
+		&i.LeaseExpiresAt,
+	)
+	return i, err
+}
+
+const getWebSubSubscriptionForFeed = `-- name: GetWebSubSubscriptionForFeed :one
+
+SELECT id, created_at, updated_at, feed_id, hub_url, topic_url, secret, requested_at, lease_expires_at FROM websub_subscriptions
+WHERE feed_id = $1
+`
+
+func (q *Queries) GetWebSubSubscriptionForFeed(ctx context.Context, feedID uuid.UUID) (WebsubSubscription, error) {
+	row := q.db.QueryRowContext(ctx, getWebSubSubscriptionForFeed, feedID)
+	var i WebsubSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.Secret,
+		&i.RequestedAt,
+		&i.LeaseExpiresAt,
+	)
+	return i, err
+}
+
+const getWebSubSubscriptionsToRenew = `-- name: GetWebSubSubscriptionsToRenew :many
+
+SELECT id, created_at, updated_at, feed_id, hub_url, topic_url, secret, requested_at, lease_expires_at FROM websub_subscriptions
+WHERE lease_expires_at IS NOT NULL
+AND lease_expires_at < $1::timestamptz
+AND requested_at < $2::timestamptz
+`
+
+type GetWebSubSubscriptionsToRenewParams struct {
+	ExpiresBefore   time.Time
+	RequestedBefore time.Time
+}
+
+// Subscriptions whose lease runs out soon and that were not already re-requested recently.
+func (q *Queries) GetWebSubSubscriptionsToRenew(ctx context.Context, arg GetWebSubSubscriptionsToRenewParams) ([]WebsubSubscription, error) {
+	rows, err := q.db.QueryContext(ctx, getWebSubSubscriptionsToRenew, arg.ExpiresBefore, arg.RequestedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebsubSubscription
+	for rows.Next() {
+		var i WebsubSubscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FeedID,
+			&i.HubUrl,
+			&i.TopicUrl,
+			&i.Secret,
+			&i.RequestedAt,
+			&i.LeaseExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertWebSubSubscription = `-- name: UpsertWebSubSubscription :one
+INSERT INTO websub_subscriptions (id, created_at, updated_at, feed_id, hub_url, topic_url, secret, requested_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (feed_id) DO UPDATE
+SET hub_url = EXCLUDED.hub_url,
+    topic_url = EXCLUDED.topic_url,
+    secret = CASE WHEN websub_subscriptions.hub_url = EXCLUDED.hub_url AND websub_subscriptions.topic_url = EXCLUDED.topic_url
+        THEN websub_subscriptions.secret ELSE EXCLUDED.secret END,
+    lease_expires_at = CASE WHEN websub_subscriptions.hub_url = EXCLUDED.hub_url AND websub_subscriptions.topic_url = EXCLUDED.topic_url
+        THEN websub_subscriptions.lease_expires_at ELSE NULL END,
+    updated_at = EXCLUDED.updated_at,
+    requested_at = EXCLUDED.requested_at
+RETURNING id, created_at, updated_at, feed_id, hub_url, topic_url, secret, requested_at, lease_expires_at
+`
+
+type UpsertWebSubSubscriptionParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	FeedID      uuid.UUID
+	HubUrl      string
+	TopicUrl    string
+	Secret      string
+	RequestedAt time.Time
+}
+
+// Creates the subscription for a feed, or points an existing one at a (possibly new) hub.
+// Renewing at the same hub and topic keeps the secret, so pushes signed during the renewal
+// still verify, and the lease. A new hub or topic gets the new secret and no lease until
+// it verifies, so the feed is polled in the meantime and the old hub's pushes are refused.
+func (q *Queries) UpsertWebSubSubscription(ctx context.Context, arg UpsertWebSubSubscriptionParams) (WebsubSubscription, error) {
+	row := q.db.QueryRowContext(ctx, upsertWebSubSubscription,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.FeedID,
+		arg.HubUrl,
+		arg.TopicUrl,
+		arg.Secret,
+		arg.RequestedAt,
+	)
+	var i WebsubSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.Secret,
+		&i.RequestedAt,
+		&i.LeaseExpiresAt,
+	)
+	return i, err
+}
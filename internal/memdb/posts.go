@@ -0,0 +1,766 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Posts
+
+func (s *Store) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, post := range s.posts {
+		if post.ID == arg.ID {
+			return database.Post{}, uniqueViolation("posts_pkey")
+		}
+		if post.FeedID == arg.FeedID && post.Url == arg.Url {
+			return database.Post{}, sql.ErrNoRows // ON CONFLICT DO NOTHING
+		}
+	}
+	if s.feedIndex(arg.FeedID) < 0 {
+		return database.Post{}, foreignKeyViolation("posts_feed_id_fkey")
+	}
+	post := database.Post{
+		ID:          arg.ID,
+		CreatedAt:   arg.CreatedAt,
+		UpdatedAt:   arg.UpdatedAt,
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		PublishedAt: arg.PublishedAt,
+		FeedID:      arg.FeedID,
+		BaseUrl:     arg.BaseUrl,
+		OriginalUrl: arg.OriginalUrl,
+		Fingerprint: arg.Fingerprint,
+		StoryID:     arg.StoryID,
+		Author:      arg.Author,
+		Categories:  slices.Clone(arg.Categories),
+	}
+	s.posts = append(s.posts, post)
+	return post, nil
+}
+
+func (s *Store) GetFeedPostByURL(ctx context.Context, arg database.GetFeedPostByURLParams) (database.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, post := range s.posts {
+		if post.FeedID == arg.FeedID && post.Url == arg.Url {
+			return post, nil
+		}
+	}
+	return database.Post{}, sql.ErrNoRows
+}
+
+// GetPostURLs lists every post's URLs, oldest post first.
+func (s *Store) GetPostURLs(ctx context.Context) ([]database.GetPostURLsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rows []database.GetPostURLsRow
+	for _, post := range s.postsByCreation() {
+		rows = append(rows, database.GetPostURLsRow{
+			ID:          post.ID,
+			FeedID:      post.FeedID,
+			Url:         post.Url,
+			OriginalUrl: post.OriginalUrl,
+		})
+	}
+	return rows, nil
+}
+
+func (s *Store) UpdatePostContent(ctx context.Context, arg database.UpdatePostContentParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.postIndex(arg.ID); i >= 0 {
+		s.posts[i].Content = arg.Content
+		s.posts[i].UpdatedAt = arg.UpdatedAt
+	}
+	return nil
+}
+
+func (s *Store) UpdatePostURL(ctx context.Context, arg database.UpdatePostURLParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.postIndex(arg.ID)
+	if i < 0 {
+		return nil
+	}
+	for _, post := range s.posts {
+		if post.ID != arg.ID && post.FeedID == s.posts[i].FeedID && post.Url == arg.Url {
+			return uniqueViolation("posts_feed_id_url_key")
+		}
+	}
+	s.posts[i].Url = arg.Url
+	s.posts[i].OriginalUrl = arg.OriginalUrl
+	s.posts[i].UpdatedAt = arg.UpdatedAt
+	return nil
+}
+
+func (s *Store) DeletePost(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deletePosts(func(post database.Post) bool { return post.ID == id })
+	return nil
+}
+
+// deletePosts deletes the matching posts along with every user's state for them.
+func (s *Store) deletePosts(match func(database.Post) bool) {
+	deleted := map[uuid.UUID]bool{}
+	s.posts = filter(s.posts, func(post database.Post) bool {
+		if match(post) {
+			deleted[post.ID] = true
+			return false
+		}
+		return true
+	})
+	for _, m := range []map[userPost]time.Time{s.reads, s.stars, s.hides, s.highlights} {
+		deleteMapKeys(m, func(key userPost) bool { return deleted[key.postID] })
+	}
+	deleteMapKeys(s.postTags, func(key tagPost) bool { return deleted[key.postID] })
+}
+
+// GetStoryIDByURL returns the story of the oldest post from another feed with the URL.
+func (s *Store) GetStoryIDByURL(ctx context.Context, arg database.GetStoryIDByURLParams) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, post := range s.postsByCreation() {
+		if post.Url == arg.Url && post.FeedID != arg.FeedID {
+			return post.StoryID, nil
+		}
+	}
+	return uuid.Nil, sql.ErrNoRows
+}
+
+func (s *Store) GetRecentPostFingerprints(ctx context.Context, arg database.GetRecentPostFingerprintsParams) ([]database.GetRecentPostFingerprintsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rows []database.GetRecentPostFingerprintsRow
+	for _, post := range s.posts {
+		if post.Fingerprint.Valid && post.FeedID != arg.FeedID && post.CreatedAt.After(arg.CreatedAt) {
+			rows = append(rows, database.GetRecentPostFingerprintsRow{
+				ID:          post.ID,
+				StoryID:     post.StoryID,
+				Fingerprint: post.Fingerprint,
+			})
+		}
+	}
+	return rows, nil
+}
+
+func (s *Store) SetPostStory(ctx context.Context, arg database.SetPostStoryParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.postIndex(arg.ID); i >= 0 {
+		s.posts[i].StoryID = arg.StoryID
+	}
+	return nil
+}
+
+// FindUserPostsByIDRange returns up to 10 posts with an ID in the range that the user
+// follows or has starred, ordered by ID.
+func (s *Store) FindUserPostsByIDRange(ctx context.Context, arg database.FindUserPostsByIDRangeParams) ([]database.FindUserPostsByIDRangeRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rows []database.FindUserPostsByIDRangeRow
+	for _, post := range s.posts {
+		if lessUUID(post.ID, arg.LowID) || lessUUID(arg.HighID, post.ID) {
+			continue
+		}
+		_, starred := s.stars[userPost{arg.UserID, post.ID}]
+		if s.followIndex(arg.UserID, post.FeedID) < 0 && !starred {
+			continue
+		}
+		rows = append(rows, database.FindUserPostsByIDRangeRow{
+			ID:           post.ID,
+			CreatedAt:    post.CreatedAt,
+			UpdatedAt:    post.UpdatedAt,
+			Title:        post.Title,
+			Url:          post.Url,
+			Description:  post.Description,
+			PublishedAt:  post.PublishedAt,
+			FeedID:       post.FeedID,
+			Content:      post.Content,
+			BaseUrl:      post.BaseUrl,
+			OriginalUrl:  post.OriginalUrl,
+			Fingerprint:  post.Fingerprint,
+			StoryID:      post.StoryID,
+			SearchVector: post.SearchVector,
+			Author:       post.Author,
+			Categories:   post.Categories,
+			FeedName:     s.feeds[s.feedIndex(post.FeedID)].Name,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool { return lessUUID(rows[i].ID, rows[j].ID) })
+	if len(rows) > 10 {
+		rows = rows[:10]
+	}
+	return rows, nil
+}
+
+// GetPostsForUser returns a page of stories from the user's followed feeds, following
+// the PostgreSQL query step by step: the visible posts, one post per story after the
+// filters, the sort keys, then the cursor, order, offset and limit.
+func (s *Store) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var visible []database.GetPostsForUserRow
+	for _, follow := range s.follows {
+		if follow.UserID != arg.UserID {
+			continue
+		}
+		if follow.Muted && !(arg.FeedID.Valid && arg.FeedID.UUID == follow.FeedID) {
+			continue
+		}
+		for _, post := range s.posts {
+			if post.FeedID != follow.FeedID {
+				continue
+			}
+			key := userPost{arg.UserID, post.ID}
+			if _, hidden := s.hides[key]; hidden && !arg.ShowHidden {
+				continue
+			}
+			isRead := s.storyRead(arg.UserID, post.StoryID)
+			_, isStarred := s.stars[key]
+			_, isHighlighted := s.highlights[key]
+			visible = append(visible, database.GetPostsForUserRow{
+				ID:            post.ID,
+				CreatedAt:     post.CreatedAt,
+				UpdatedAt:     post.UpdatedAt,
+				Title:         post.Title,
+				Url:           post.Url,
+				Description:   post.Description,
+				PublishedAt:   post.PublishedAt,
+				FeedID:        post.FeedID,
+				Content:       post.Content,
+				BaseUrl:       post.BaseUrl,
+				OriginalUrl:   post.OriginalUrl,
+				Fingerprint:   post.Fingerprint,
+				StoryID:       post.StoryID,
+				SearchVector:  post.SearchVector,
+				Author:        post.Author,
+				Categories:    post.Categories,
+				FeedName:      s.followName(follow),
+				FolderID:      follow.FolderID,
+				IsRead:        isRead,
+				IsStarred:     isStarred,
+				IsHighlighted: isHighlighted,
+			})
+		}
+	}
+
+	// One post per story: the earliest published, then the earliest fetched.
+	stories := map[uuid.UUID]database.GetPostsForUserRow{}
+	for _, row := range visible {
+		if arg.UnreadOnly && row.IsRead || arg.StarredOnly && !row.IsStarred {
+			continue
+		}
+		if arg.Tag.Valid && !s.hasTag(arg.UserID, row.ID, arg.Tag.String) {
+			continue
+		}
+		if arg.FeedID.Valid && row.FeedID != arg.FeedID.UUID {
+			continue
+		}
+		if arg.FolderID.Valid && row.FolderID != arg.FolderID {
+			continue
+		}
+		date := row.CreatedAt
+		if row.PublishedAt.Valid {
+			date = row.PublishedAt.Time
+		}
+		if arg.Since.Valid && date.Before(arg.Since.Time) || arg.Until.Valid && !date.Before(arg.Until.Time) {
+			continue
+		}
+		if first, ok := stories[row.StoryID]; !ok || earlierCopy(row, first) {
+			stories[row.StoryID] = row
+		}
+	}
+
+	var rows []database.GetPostsForUserRow
+	for _, row := range stories {
+		if arg.Sort == "feed" {
+			row.SortGroup = row.FeedName
+		}
+		switch {
+		case arg.Sort == "fetched":
+			row.SortTime = row.CreatedAt
+		case row.PublishedAt.Valid:
+			row.SortTime = row.PublishedAt.Time
+		default:
+			row.SortTime = time.Time{}
+		}
+		if arg.CursorID.Valid && !afterCursor(row, arg) {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.SortGroup != b.SortGroup {
+			return a.SortGroup < b.SortGroup
+		}
+		if !a.SortTime.Equal(b.SortTime) {
+			return a.SortTime.After(b.SortTime)
+		}
+		return lessUUID(b.ID, a.ID)
+	})
+
+	rows = rows[min(int(arg.Offset), len(rows)):]
+	rows = rows[:min(int(arg.Limit), len(rows))]
+	for i, row := range rows {
+		alsoIn := []string{}
+		for _, other := range visible {
+			if other.StoryID == row.StoryID && other.FeedID != row.FeedID && !slices.Contains(alsoIn, other.FeedName) {
+				alsoIn = append(alsoIn, other.FeedName)
+			}
+		}
+		sort.Strings(alsoIn)
+		rows[i].AlsoIn = alsoIn
+		rows[i].Tags = s.postTagNames(arg.UserID, row.ID)
+	}
+	return rows, nil
+}
+
+// earlierCopy reports whether a comes before b within a story: published earlier (posts
+// without a date last), then fetched earlier.
+func earlierCopy(a, b database.GetPostsForUserRow) bool {
+	if a.PublishedAt.Valid != b.PublishedAt.Valid {
+		return a.PublishedAt.Valid
+	}
+	if a.PublishedAt.Valid && !a.PublishedAt.Time.Equal(b.PublishedAt.Time) {
+		return a.PublishedAt.Time.Before(b.PublishedAt.Time)
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+// afterCursor reports whether row sorts after the cursor position in arg.
+func afterCursor(row database.GetPostsForUserRow, arg database.GetPostsForUserParams) bool {
+	if row.SortGroup != arg.CursorGroup.String {
+		return row.SortGroup > arg.CursorGroup.String
+	}
+	if !row.SortTime.Equal(arg.CursorTime.Time) {
+		return row.SortTime.Before(arg.CursorTime.Time)
+	}
+	return lessUUID(row.ID, arg.CursorID.UUID)
+}
+
+// postsByCreation returns the posts ordered by when they were fetched.
+func (s *Store) postsByCreation() []database.Post {
+	posts := slices.Clone(s.posts)
+	sort.SliceStable(posts, func(i, j int) bool { return posts[i].CreatedAt.Before(posts[j].CreatedAt) })
+	return posts
+}
+
+// Read state
+
+// MarkPostRead marks the post and the other copies of its story read.
+func (s *Store) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var marked int64
+	for _, postID := range s.storyPostIDs(arg.PostID) {
+		key := userPost{arg.UserID, postID}
+		if _, ok := s.reads[key]; !ok {
+			s.reads[key] = arg.ReadAt
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+// MarkPostUnread marks the post and the other copies of its story unread.
+func (s *Store) MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var unmarked int64
+	for _, postID := range s.storyPostIDs(arg.PostID) {
+		key := userPost{arg.UserID, postID}
+		if _, ok := s.reads[key]; ok {
+			delete(s.reads, key)
+			unmarked++
+		}
+	}
+	return unmarked, nil
+}
+
+// storyPostIDs returns the IDs of every post in the same story as the given one.
+func (s *Store) storyPostIDs(postID uuid.UUID) []uuid.UUID {
+	i := s.postIndex(postID)
+	if i < 0 {
+		return nil
+	}
+	var ids []uuid.UUID
+	for _, post := range s.posts {
+		if post.StoryID == s.posts[i].StoryID {
+			ids = append(ids, post.ID)
+		}
+	}
+	return ids
+}
+
+// MarkAllPostsRead marks the posts of the user's followed feeds read, optionally only
+// one feed's and only those published (or fetched) before a cutoff.
+func (s *Store) MarkAllPostsRead(ctx context.Context, arg database.MarkAllPostsReadParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var marked int64
+	for _, post := range s.posts {
+		if s.followIndex(arg.UserID, post.FeedID) < 0 || arg.FeedID.Valid && post.FeedID != arg.FeedID.UUID {
+			continue
+		}
+		date := post.CreatedAt
+		if post.PublishedAt.Valid {
+			date = post.PublishedAt.Time
+		}
+		if arg.Before.Valid && !date.Before(arg.Before.Time) {
+			continue
+		}
+		key := userPost{arg.UserID, post.ID}
+		if _, ok := s.reads[key]; !ok {
+			s.reads[key] = arg.ReadAt
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+func (s *Store) MovePostReads(ctx context.Context, arg database.MovePostReadsParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	moveUserPosts(s.reads, arg.FromPostID, arg.ToPostID)
+	return nil
+}
+
+// moveUserPosts copies the entries for one post to another, keeping any the other post
+// already has.
+func moveUserPosts(m map[userPost]time.Time, from, to uuid.UUID) {
+	for key, at := range m {
+		if key.postID != from {
+			continue
+		}
+		if _, ok := m[userPost{key.userID, to}]; !ok {
+			m[userPost{key.userID, to}] = at
+		}
+	}
+}
+
+// Stars, hides and highlights
+
+func (s *Store) StarPost(ctx context.Context, arg database.StarPostParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.insertUserPost(s.stars, arg.UserID, arg.PostID, arg.StarredAt)
+}
+
+func (s *Store) UnstarPost(ctx context.Context, arg database.UnstarPostParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := userPost{arg.UserID, arg.PostID}
+	if _, ok := s.stars[key]; !ok {
+		return 0, nil
+	}
+	delete(s.stars, key)
+	return 1, nil
+}
+
+// GetStarredPostsForUser lists the user's starred posts, most recently starred first.
+func (s *Store) GetStarredPostsForUser(ctx context.Context, arg database.GetStarredPostsForUserParams) ([]database.GetStarredPostsForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rows []database.GetStarredPostsForUserRow
+	for key, starredAt := range s.stars {
+		if key.userID != arg.UserID {
+			continue
+		}
+		post := s.posts[s.postIndex(key.postID)]
+		rows = append(rows, database.GetStarredPostsForUserRow{
+			ID:           post.ID,
+			CreatedAt:    post.CreatedAt,
+			UpdatedAt:    post.UpdatedAt,
+			Title:        post.Title,
+			Url:          post.Url,
+			Description:  post.Description,
+			PublishedAt:  post.PublishedAt,
+			FeedID:       post.FeedID,
+			Content:      post.Content,
+			BaseUrl:      post.BaseUrl,
+			OriginalUrl:  post.OriginalUrl,
+			Fingerprint:  post.Fingerprint,
+			StoryID:      post.StoryID,
+			SearchVector: post.SearchVector,
+			Author:       post.Author,
+			Categories:   post.Categories,
+			FeedName:     s.feeds[s.feedIndex(post.FeedID)].Name,
+			StarredAt:    starredAt,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].StarredAt.After(rows[j].StarredAt) })
+	rows = rows[:min(int(arg.Limit), len(rows))]
+	return rows, nil
+}
+
+func (s *Store) MovePostStars(ctx context.Context, arg database.MovePostStarsParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	moveUserPosts(s.stars, arg.FromPostID, arg.ToPostID)
+	return nil
+}
+
+func (s *Store) HidePost(ctx context.Context, arg database.HidePostParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.insertUserPost(s.hides, arg.UserID, arg.PostID, arg.HiddenAt)
+}
+
+func (s *Store) MovePostHides(ctx context.Context, arg database.MovePostHidesParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	moveUserPosts(s.hides, arg.FromPostID, arg.ToPostID)
+	return nil
+}
+
+func (s *Store) MovePostHighlights(ctx context.Context, arg database.MovePostHighlightsParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	moveUserPosts(s.highlights, arg.FromPostID, arg.ToPostID)
+	return nil
+}
+
+func (s *Store) HighlightPost(ctx context.Context, arg database.HighlightPostParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.insertUserPost(s.highlights, arg.UserID, arg.PostID, arg.HighlightedAt)
+}
+
+// insertUserPost adds an entry to one of the per-user post tables unless it is already
+// there, and returns how many rows were inserted.
+func (s *Store) insertUserPost(m map[userPost]time.Time, userID, postID uuid.UUID, at time.Time) (int64, error) {
+	if s.userIndex(userID) < 0 || s.postIndex(postID) < 0 {
+		return 0, foreignKeyViolation("user_id or post_id")
+	}
+	key := userPost{userID, postID}
+	if _, ok := m[key]; ok {
+		return 0, nil
+	}
+	m[key] = at
+	return 1, nil
+}
+
+// Tags
+
+// UpsertTag returns the user's tag with the name, creating it first if needed.
+func (s *Store) UpsertTag(ctx context.Context, arg database.UpsertTagParams) (database.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tag := range s.tags {
+		if tag.UserID == arg.UserID && tag.Name == arg.Name {
+			return tag, nil
+		}
+	}
+	if s.userIndex(arg.UserID) < 0 {
+		return database.Tag{}, foreignKeyViolation("tags_user_id_fkey")
+	}
+	tag := database.Tag(arg)
+	s.tags = append(s.tags, tag)
+	return tag, nil
+}
+
+func (s *Store) TagPost(ctx context.Context, arg database.TagPostParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.postIndex(arg.PostID) < 0 {
+		return 0, foreignKeyViolation("post_tags_post_id_fkey")
+	}
+	key := tagPost{arg.TagID, arg.PostID}
+	if _, ok := s.postTags[key]; ok {
+		return 0, nil
+	}
+	s.postTags[key] = arg.TaggedAt
+	return 1, nil
+}
+
+func (s *Store) UntagPost(ctx context.Context, arg database.UntagPostParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tag := range s.tags {
+		key := tagPost{tag.ID, arg.PostID}
+		if _, ok := s.postTags[key]; ok && tag.UserID == arg.UserID && tag.Name == arg.Name {
+			delete(s.postTags, key)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// DeleteUnusedTags deletes the user's tags that no longer label any post.
+func (s *Store) DeleteUnusedTags(ctx context.Context, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = filter(s.tags, func(tag database.Tag) bool {
+		return tag.UserID != userID || s.tagPostCount(tag.ID) > 0
+	})
+	return nil
+}
+
+// GetTagsForUser lists the user's tags that label at least one post, by name.
+func (s *Store) GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetTagsForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rows []database.GetTagsForUserRow
+	for _, tag := range s.tags {
+		if count := s.tagPostCount(tag.ID); tag.UserID == userID && count > 0 {
+			rows = append(rows, database.GetTagsForUserRow{Name: tag.Name, PostCount: count})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Name < rows[j].Name })
+	return rows, nil
+}
+
+func (s *Store) MovePostTags(ctx context.Context, arg database.MovePostTagsParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, at := range s.postTags {
+		if key.postID != arg.FromPostID {
+			continue
+		}
+		if _, ok := s.postTags[tagPost{key.tagID, arg.ToPostID}]; !ok {
+			s.postTags[tagPost{key.tagID, arg.ToPostID}] = at
+		}
+	}
+	return nil
+}
+
+// tagPostCount returns how many posts the tag labels.
+func (s *Store) tagPostCount(tagID uuid.UUID) int64 {
+	var count int64
+	for key := range s.postTags {
+		if key.tagID == tagID {
+			count++
+		}
+	}
+	return count
+}
+
+// storyRead reports whether the user read any copy of the story.
+func (s *Store) storyRead(userID, storyID uuid.UUID) bool {
+	for key := range s.reads {
+		if key.userID == userID {
+			if i := s.postIndex(key.postID); i >= 0 && s.posts[i].StoryID == storyID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasTag reports whether the user labelled the post with the named tag.
+func (s *Store) hasTag(userID, postID uuid.UUID, name string) bool {
+	return slices.Contains(s.postTagNames(userID, postID), name)
+}
+
+// postTagNames returns the names of the user's tags on the post, sorted.
+func (s *Store) postTagNames(userID, postID uuid.UUID) []string {
+	names := []string{}
+	for _, tag := range s.tags {
+		if _, ok := s.postTags[tagPost{tag.ID, postID}]; ok && tag.UserID == userID {
+			names = append(names, tag.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Rules
+
+func (s *Store) CreateRule(ctx context.Context, arg database.CreateRuleParams) (database.Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.userIndex(arg.UserID) < 0 {
+		return database.Rule{}, foreignKeyViolation("rules_user_id_fkey")
+	}
+	rule := database.Rule(arg)
+	s.rules = append(s.rules, rule)
+	return rule, nil
+}
+
+// GetRulesForUser lists the user's rules, oldest first.
+func (s *Store) GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rules := filter(s.rules, func(rule database.Rule) bool { return rule.UserID == userID })
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].CreatedAt.Before(rules[j].CreatedAt) })
+	return rules, nil
+}
+
+func (s *Store) DeleteRule(ctx context.Context, arg database.DeleteRuleParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before := len(s.rules)
+	s.rules = filter(s.rules, func(rule database.Rule) bool { return rule.ID != arg.ID || rule.UserID != arg.UserID })
+	return int64(before - len(s.rules)), nil
+}
+
+// GetRulesForFeed returns the rules of every user following the feed with that user's
+// own title for it, by user and then oldest first.
+func (s *Store) GetRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.GetRulesForFeedRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rows []database.GetRulesForFeedRow
+	for _, follow := range s.follows {
+		if follow.FeedID != feedID {
+			continue
+		}
+		for _, rule := range s.rules {
+			if rule.UserID == follow.UserID {
+				rows = append(rows, database.GetRulesForFeedRow{
+					ID:         rule.ID,
+					CreatedAt:  rule.CreatedAt,
+					UserID:     rule.UserID,
+					Expression: rule.Expression,
+					Action:     rule.Action,
+					Tag:        rule.Tag,
+					FeedTitle:  follow.Title,
+				})
+			}
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].UserID != rows[j].UserID {
+			return lessUUID(rows[i].UserID, rows[j].UserID)
+		}
+		return rows[i].CreatedAt.Before(rows[j].CreatedAt)
+	})
+	return rows, nil
+}
+
+// GetRuleCandidatePosts returns what rules match on for each post of the feeds the user
+// follows, oldest post first.
+func (s *Store) GetRuleCandidatePosts(ctx context.Context, userID uuid.UUID) ([]database.GetRuleCandidatePostsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rows []database.GetRuleCandidatePostsRow
+	for _, post := range s.postsByCreation() {
+		i := s.followIndex(userID, post.FeedID)
+		if i < 0 {
+			continue
+		}
+		feed := s.feeds[s.feedIndex(post.FeedID)]
+		rows = append(rows, database.GetRuleCandidatePostsRow{
+			ID:          post.ID,
+			Title:       post.Title,
+			Description: post.Description,
+			Author:      post.Author,
+			Categories:  post.Categories,
+			FeedName:    feed.Name,
+			FeedUrl:     feed.Url,
+			FeedTitle:   s.follows[i].Title,
+		})
+	}
+	return rows, nil
+}
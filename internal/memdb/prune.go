@@ -0,0 +1,110 @@
+package memdb
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Pruning
+
+func (s *Store) CreatePrunedPost(ctx context.Context, arg database.CreatePrunedPostParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := feedURL{arg.FeedID, arg.Url}
+	if _, ok := s.pruned[key]; !ok {
+		s.pruned[key] = arg.PrunedAt
+	}
+	return nil
+}
+
+func (s *Store) DeletePrunedPost(ctx context.Context, arg database.DeletePrunedPostParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pruned, feedURL{arg.FeedID, arg.Url})
+	return nil
+}
+
+func (s *Store) GetFeedPrunedURLs(ctx context.Context, feedID uuid.UUID) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var urls []string
+	for key := range s.pruned {
+		if key.feedID == feedID {
+			urls = append(urls, key.url)
+		}
+	}
+	sort.Strings(urls)
+	return urls, nil
+}
+
+func (s *Store) IsPostPruned(ctx context.Context, arg database.IsPostPrunedParams) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.pruned[feedURL{arg.FeedID, arg.Url}]
+	return ok, nil
+}
+
+// GetPrunablePosts ranks each feed's posts by when they were saved and returns those
+// past the feed's retention limits, leaving out starred and tagged posts.
+func (s *Store) GetPrunablePosts(ctx context.Context, arg database.GetPrunablePostsParams) ([]database.GetPrunablePostsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	protected := map[uuid.UUID]bool{}
+	for key := range s.stars {
+		protected[key.postID] = true
+	}
+	for key := range s.postTags {
+		protected[key.postID] = true
+	}
+
+	var rows []database.GetPrunablePostsRow
+	for _, feed := range s.feeds {
+		if arg.FeedID.Valid && feed.ID != arg.FeedID.UUID {
+			continue
+		}
+		keepPosts, keepSeconds := int64(arg.DefaultPosts), arg.DefaultSeconds
+		if feed.RetainPosts.Valid {
+			keepPosts = int64(feed.RetainPosts.Int32)
+		}
+		if feed.RetainSeconds.Valid {
+			keepSeconds = feed.RetainSeconds.Int64
+		}
+		cutoff := arg.Now.Add(-time.Duration(keepSeconds) * time.Second)
+
+		posts := filter(s.posts, func(post database.Post) bool { return post.FeedID == feed.ID })
+		sort.SliceStable(posts, func(i, j int) bool {
+			if !posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+				return posts[i].CreatedAt.After(posts[j].CreatedAt)
+			}
+			return lessUUID(posts[j].ID, posts[i].ID)
+		})
+		for i, post := range posts {
+			tooMany := keepPosts > 0 && int64(i+1) > keepPosts
+			tooOld := keepSeconds > 0 && post.CreatedAt.Before(cutoff)
+			if (tooMany || tooOld) && !protected[post.ID] {
+				rows = append(rows, database.GetPrunablePostsRow{
+					ID:        post.ID,
+					FeedID:    feed.ID,
+					FeedName:  feed.Name,
+					Title:     post.Title,
+					Url:       post.Url,
+					CreatedAt: post.CreatedAt,
+				})
+			}
+		}
+	}
+
+	// Each feed's posts are already newest first, so a stable sort by feed suffices.
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].FeedName != rows[j].FeedName {
+			return rows[i].FeedName < rows[j].FeedName
+		}
+		return lessUUID(rows[i].FeedID, rows[j].FeedID)
+	})
+	return rows, nil
+}
@@ -0,0 +1,173 @@
+package memdb
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/Marcus-Gustafsson/gator/internal/render"
+)
+
+// searchQuery is a query in web search syntax split into the groups of alternatives
+// that must all match and the words that must not.
+type searchQuery struct {
+	included [][]string
+	excluded []string
+}
+
+// parseSearchQuery reads "quoted phrases", OR and -excluded words the way
+// websearch_to_tsquery does, lowercasing every term.
+func parseSearchQuery(query string) searchQuery {
+	var parsed searchQuery
+	or := false
+	for {
+		query = strings.TrimLeft(query, " \t\n")
+		if query == "" {
+			return parsed
+		}
+		exclude := query[0] == '-'
+		if exclude {
+			query = query[1:]
+		}
+
+		var term string
+		if rest, ok := strings.CutPrefix(query, `"`); ok {
+			term, query, _ = strings.Cut(rest, `"`)
+		} else {
+			end := strings.IndexAny(query, " \t\n")
+			if end < 0 {
+				end = len(query)
+			}
+			term, query = query[:end], query[end:]
+			if !exclude && strings.EqualFold(term, "or") {
+				or = len(parsed.included) > 0
+				continue
+			}
+		}
+		term = strings.ToLower(strings.TrimSpace(term))
+		switch {
+		case term == "":
+		case exclude:
+			parsed.excluded = append(parsed.excluded, term)
+		case or:
+			last := len(parsed.included) - 1
+			parsed.included[last] = append(parsed.included[last], term)
+			or = false
+		default:
+			parsed.included = append(parsed.included, []string{term})
+		}
+	}
+}
+
+// SearchPosts matches terms as case-insensitive substrings of a post's title, author and
+// text instead of stemmed words. Title matches weigh ten times as much as text matches
+// in the rank, and the snippet is the text around the first match.
+func (s *Store) SearchPosts(ctx context.Context, arg database.SearchPostsParams) ([]database.SearchPostsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := parseSearchQuery(arg.Query)
+	if len(query.included) == 0 {
+		return nil, nil
+	}
+
+	var rows []database.SearchPostsRow
+	for _, post := range s.posts {
+		if !arg.AllFeeds && s.followIndex(arg.UserID, post.FeedID) < 0 {
+			continue
+		}
+		body := post.Description.String
+		if post.Content.Valid {
+			body = post.Content.String
+		}
+		title := strings.ToLower(post.Title)
+		text := render.PlainText(body)
+		document := title + " " + strings.ToLower(post.Author.String+" "+text)
+
+		rank, matched, first := float32(0), true, ""
+		for _, alternatives := range query.included {
+			found := false
+			for _, term := range alternatives {
+				if strings.Contains(document, term) {
+					found = true
+					rank += float32(10*strings.Count(title, term) + strings.Count(strings.ToLower(text), term))
+					if first == "" {
+						first = term
+					}
+				}
+			}
+			matched = matched && found
+		}
+		for _, term := range query.excluded {
+			matched = matched && !strings.Contains(document, term)
+		}
+		if !matched {
+			continue
+		}
+
+		key := userPost{arg.UserID, post.ID}
+		isRead := s.storyRead(arg.UserID, post.StoryID)
+		_, isStarred := s.stars[key]
+		rows = append(rows, database.SearchPostsRow{
+			ID:          post.ID,
+			Title:       post.Title,
+			Url:         post.Url,
+			PublishedAt: post.PublishedAt,
+			FeedName:    s.feeds[s.feedIndex(post.FeedID)].Name,
+			Description: post.Description,
+			Content:     post.Content,
+			BaseUrl:     post.BaseUrl,
+			IsRead:      isRead,
+			IsStarred:   isStarred,
+			Rank:        rank,
+			Snippet:     snippet(post.Title+" — "+text, first),
+		})
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.Rank != b.Rank {
+			return a.Rank > b.Rank
+		}
+		if a.PublishedAt.Valid != b.PublishedAt.Valid {
+			return a.PublishedAt.Valid
+		}
+		return a.PublishedAt.Time.After(b.PublishedAt.Time)
+	})
+	rows = rows[:min(int(arg.Limit), len(rows))]
+	return rows, nil
+}
+
+// snippetRadius is how many bytes of text a snippet keeps on either side of the match.
+const snippetRadius = 60
+
+// snippet returns the text around the first occurrence of term, with the match wrapped
+// in <b></b>.
+func snippet(text, term string) string {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		lower = text // lowercasing changed byte offsets; match case-sensitively instead
+	}
+	start := strings.Index(lower, term)
+	if start < 0 || term == "" {
+		start, term = 0, "" // matched the author only
+	}
+	end := start + len(term)
+	from, to := max(0, start-snippetRadius), min(len(text), end+snippetRadius)
+	for from > 0 && !isRuneStart(text[from]) {
+		from--
+	}
+	for to < len(text) && !isRuneStart(text[to]) {
+		to++
+	}
+	if start == end {
+		return text[from:to]
+	}
+	return text[from:start] + "<b>" + text[start:end] + "</b>" + text[end:to]
+}
+
+// isRuneStart reports whether b begins a UTF-8 encoded rune.
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,726 @@
+// Package memdb keeps gator's data in memory. Store implements database.Querier with
+// the same semantics as the PostgreSQL queries (unique constraints, cascading deletes,
+// ordering), so the command handlers can be exercised without a database server.
+// Nothing is persisted.
+package memdb
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Store holds every table as a slice in insertion order, which stands in for the
+// unspecified order PostgreSQL returns rows in when a query doesn't sort them. The
+// per-user post state tables are maps keyed by user and post.
+type Store struct {
+	mu sync.Mutex
+
+	users         []database.User
+	feeds         []database.Feed
+	folders       []database.Folder
+	follows       []database.FeedFollow
+	posts         []database.Post
+	tags          []database.Tag
+	rules         []database.Rule
+	subscriptions []database.WebsubSubscription
+
+	reads      map[userPost]time.Time
+	stars      map[userPost]time.Time
+	hides      map[userPost]time.Time
+	highlights map[userPost]time.Time
+	postTags   map[tagPost]time.Time
+	pruned     map[feedURL]time.Time
+}
+
+var _ database.Querier = (*Store)(nil)
+
+// userPost is the key of the post_reads, post_stars, post_hides and post_highlights
+// tables.
+type userPost struct {
+	userID, postID uuid.UUID
+}
+
+// tagPost is the key of the post_tags table.
+type tagPost struct {
+	tagID, postID uuid.UUID
+}
+
+// feedURL is the key of the pruned_posts table.
+type feedURL struct {
+	feedID uuid.UUID
+	url    string
+}
+
+// New returns an empty store.
+func New() *Store {
+	return &Store{
+		reads:      map[userPost]time.Time{},
+		stars:      map[userPost]time.Time{},
+		hides:      map[userPost]time.Time{},
+		highlights: map[userPost]time.Time{},
+		postTags:   map[tagPost]time.Time{},
+		pruned:     map[feedURL]time.Time{},
+	}
+}
+
+// InTx runs fn as a transaction: if fn returns an error, every table is put back the
+// way it was before fn ran. Other callers aren't kept out while fn runs, so this is only
+// all-or-nothing for one goroutine at a time, which is all the tests need.
+func (s *Store) InTx(fn func() error) error {
+	s.mu.Lock()
+	saved := Store{
+		users:         slices.Clone(s.users),
+		feeds:         slices.Clone(s.feeds),
+		folders:       slices.Clone(s.folders),
+		follows:       slices.Clone(s.follows),
+		posts:         slices.Clone(s.posts),
+		tags:          slices.Clone(s.tags),
+		rules:         slices.Clone(s.rules),
+		subscriptions: slices.Clone(s.subscriptions),
+		reads:         maps.Clone(s.reads),
+		stars:         maps.Clone(s.stars),
+		hides:         maps.Clone(s.hides),
+		highlights:    maps.Clone(s.highlights),
+		postTags:      maps.Clone(s.postTags),
+		pruned:        maps.Clone(s.pruned),
+	}
+	s.mu.Unlock()
+
+	err := fn()
+	if err != nil {
+		s.mu.Lock()
+		s.users, s.feeds, s.folders, s.follows = saved.users, saved.feeds, saved.folders, saved.follows
+		s.posts, s.tags, s.rules, s.subscriptions = saved.posts, saved.tags, saved.rules, saved.subscriptions
+		s.reads, s.stars, s.hides, s.highlights, s.postTags = saved.reads, saved.stars, saved.hides, saved.highlights, saved.postTags
+		s.pruned = saved.pruned
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// uniqueViolation returns the error PostgreSQL reports for a duplicate key, so callers
+// that check for it behave the same as against a real database.
+func uniqueViolation(constraint string) error {
+	return fmt.Errorf("pq: duplicate key value violates unique constraint %q", constraint)
+}
+
+// foreignKeyViolation returns the error PostgreSQL reports for a missing referenced row.
+func foreignKeyViolation(constraint string) error {
+	return fmt.Errorf("pq: insert or update on table violates foreign key constraint %q", constraint)
+}
+
+// lessUUID orders UUIDs bytewise, like PostgreSQL's uuid type.
+func lessUUID(a, b uuid.UUID) bool {
+	return bytes.Compare(a[:], b[:]) < 0
+}
+
+// lessNullTimeFirst orders times ascending with NULLs first.
+func lessNullTimeFirst(a, b sql.NullTime) bool {
+	if !a.Valid || !b.Valid {
+		return !a.Valid && b.Valid
+	}
+	return a.Time.Before(b.Time)
+}
+
+// deleteMapKeys removes the entries of m whose key matches.
+func deleteMapKeys[K comparable](m map[K]time.Time, match func(K) bool) {
+	for key := range m {
+		if match(key) {
+			delete(m, key)
+		}
+	}
+}
+
+// Users
+
+func (s *Store) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.users {
+		if user.ID == arg.ID {
+			return database.User{}, uniqueViolation("users_pkey")
+		}
+		if arg.Name.Valid && user.Name.Valid && user.Name.String == arg.Name.String {
+			return database.User{}, uniqueViolation("users_name_key")
+		}
+	}
+	user := database.User{ID: arg.ID, CreatedAt: arg.CreatedAt, UpdatedAt: arg.UpdatedAt, Name: arg.Name}
+	s.users = append(s.users, user)
+	return user, nil
+}
+
+func (s *Store) GetUser(ctx context.Context, name sql.NullString) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.users {
+		if name.Valid && user.Name.Valid && user.Name.String == name.String {
+			return user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (s *Store) GetUserByUUID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (s *Store) GetUsers(ctx context.Context) ([]sql.NullString, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []sql.NullString
+	for _, user := range s.users {
+		names = append(names, user.Name)
+	}
+	return names, nil
+}
+
+// DeleteUsers deletes every user and, through the cascades of the schema, everything
+// that belongs to them: the feeds they added (with those feeds' posts), follows,
+// folders, tags, rules and per-post state.
+func (s *Store) DeleteUsers(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteFeeds(func(feed database.Feed) bool { return feed.UserID.Valid })
+	s.users = nil
+	s.folders = nil
+	s.follows = nil
+	s.tags = nil
+	s.rules = nil
+	clear(s.reads)
+	clear(s.stars)
+	clear(s.hides)
+	clear(s.highlights)
+	clear(s.postTags)
+	return nil
+}
+
+// Feeds
+
+func (s *Store) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, feed := range s.feeds {
+		if feed.ID == arg.ID {
+			return database.Feed{}, uniqueViolation("feeds_pkey")
+		}
+		if feed.Url == arg.Url {
+			return database.Feed{}, uniqueViolation("feeds_url_key")
+		}
+	}
+	if arg.UserID.Valid && s.userIndex(arg.UserID.UUID) < 0 {
+		return database.Feed{}, foreignKeyViolation("feeds_user_id_fkey")
+	}
+	feed := database.Feed{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UserID:    arg.UserID,
+	}
+	s.feeds = append(s.feeds, feed)
+	return feed, nil
+}
+
+func (s *Store) GetFeeds(ctx context.Context) ([]database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]database.Feed(nil), s.feeds...), nil
+}
+
+func (s *Store) GetFeedByID(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.feedIndex(id); i >= 0 {
+		return s.feeds[i], nil
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (s *Store) GetFeedByURL(ctx context.Context, url string) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, feed := range s.feeds {
+		if feed.Url == url {
+			return feed, nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (s *Store) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.feedIndex(arg.ID)
+	if i < 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	s.feeds[i].LastFetchedAt = sql.NullTime{Time: arg.Now, Valid: true}
+	s.feeds[i].UpdatedAt = arg.Now
+	return s.feeds[i], nil
+}
+
+func (s *Store) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextFeedToFetch(func(database.Feed) bool { return true })
+}
+
+func (s *Store) GetNextPolledFeedToFetch(ctx context.Context, now time.Time) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextFeedToFetch(func(feed database.Feed) bool {
+		for _, subscription := range s.subscriptions {
+			if subscription.FeedID == feed.ID && subscription.LeaseExpiresAt.Valid && subscription.LeaseExpiresAt.Time.After(now) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// nextFeedToFetch returns the least recently fetched feed that include accepts, never
+// fetched ones first.
+func (s *Store) nextFeedToFetch(include func(database.Feed) bool) (database.Feed, error) {
+	var next *database.Feed
+	for i, feed := range s.feeds {
+		if include(feed) && (next == nil || lessNullTimeFirst(feed.LastFetchedAt, next.LastFetchedAt)) {
+			next = &s.feeds[i]
+		}
+	}
+	if next == nil {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	return *next, nil
+}
+
+func (s *Store) SetFeedFetchFullContent(ctx context.Context, arg database.SetFeedFetchFullContentParams) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.feedIndex(arg.ID)
+	if i < 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	s.feeds[i].FetchFullContent = arg.FetchFullContent
+	s.feeds[i].UpdatedAt = arg.UpdatedAt
+	return s.feeds[i], nil
+}
+
+func (s *Store) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.feedIndex(arg.ID)
+	if i < 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	s.feeds[i].RetainPosts = arg.RetainPosts
+	s.feeds[i].RetainSeconds = arg.RetainSeconds
+	s.feeds[i].UpdatedAt = arg.UpdatedAt
+	return s.feeds[i], nil
+}
+
+// deleteFeeds deletes the matching feeds with their follows, posts, subscriptions and
+// pruned post URLs.
+func (s *Store) deleteFeeds(match func(database.Feed) bool) {
+	deleted := map[uuid.UUID]bool{}
+	s.feeds = filter(s.feeds, func(feed database.Feed) bool {
+		if match(feed) {
+			deleted[feed.ID] = true
+			return false
+		}
+		return true
+	})
+	s.follows = filter(s.follows, func(follow database.FeedFollow) bool { return !deleted[follow.FeedID] })
+	s.subscriptions = filter(s.subscriptions, func(subscription database.WebsubSubscription) bool {
+		return !deleted[subscription.FeedID]
+	})
+	s.deletePosts(func(post database.Post) bool { return deleted[post.FeedID] })
+	deleteMapKeys(s.pruned, func(key feedURL) bool { return deleted[key.feedID] })
+}
+
+// Folders
+
+func (s *Store) CreateFolder(ctx context.Context, arg database.CreateFolderParams) (database.Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, folder := range s.folders {
+		if folder.UserID == arg.UserID && folder.Name == arg.Name {
+			return database.Folder{}, uniqueViolation("folders_user_id_name_key")
+		}
+	}
+	if s.userIndex(arg.UserID) < 0 {
+		return database.Folder{}, foreignKeyViolation("folders_user_id_fkey")
+	}
+	folder := database.Folder(arg)
+	s.folders = append(s.folders, folder)
+	return folder, nil
+}
+
+func (s *Store) GetFolderByName(ctx context.Context, arg database.GetFolderByNameParams) (database.Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, folder := range s.folders {
+		if folder.UserID == arg.UserID && folder.Name == arg.Name {
+			return folder, nil
+		}
+	}
+	return database.Folder{}, sql.ErrNoRows
+}
+
+func (s *Store) GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]database.Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	folders := filter(s.folders, func(folder database.Folder) bool { return folder.UserID == userID })
+	sort.SliceStable(folders, func(i, j int) bool { return folders[i].Name < folders[j].Name })
+	return folders, nil
+}
+
+func (s *Store) RenameFolder(ctx context.Context, arg database.RenameFolderParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, folder := range s.folders {
+		if folder.UserID == arg.UserID && folder.Name == arg.NewName && arg.NewName != arg.Name {
+			return 0, uniqueViolation("folders_user_id_name_key")
+		}
+	}
+	var renamed int64
+	for i, folder := range s.folders {
+		if folder.UserID == arg.UserID && folder.Name == arg.Name {
+			s.folders[i].Name = arg.NewName
+			s.folders[i].UpdatedAt = arg.UpdatedAt
+			renamed++
+		}
+	}
+	return renamed, nil
+}
+
+// DeleteFolder deletes a folder; the feeds in it stay followed, outside any folder.
+func (s *Store) DeleteFolder(ctx context.Context, arg database.DeleteFolderParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deleted int64
+	s.folders = filter(s.folders, func(folder database.Folder) bool {
+		if folder.UserID != arg.UserID || folder.Name != arg.Name {
+			return true
+		}
+		for i, follow := range s.follows {
+			if follow.FolderID.Valid && follow.FolderID.UUID == folder.ID {
+				s.follows[i].FolderID = uuid.NullUUID{}
+			}
+		}
+		deleted++
+		return false
+	})
+	return deleted, nil
+}
+
+// Feed follows
+
+func (s *Store) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, follow := range s.follows {
+		if follow.UserID == arg.UserID && follow.FeedID == arg.FeedID {
+			return database.CreateFeedFollowRow{}, uniqueViolation("feed_follows_user_id_feed_id_key")
+		}
+	}
+	userIndex, feedIndex := s.userIndex(arg.UserID), s.feedIndex(arg.FeedID)
+	if userIndex < 0 {
+		return database.CreateFeedFollowRow{}, foreignKeyViolation("feed_follows_user_id_fkey")
+	}
+	if feedIndex < 0 {
+		return database.CreateFeedFollowRow{}, foreignKeyViolation("feed_follows_feed_id_fkey")
+	}
+	follow := database.FeedFollow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+		FolderID:  arg.FolderID,
+	}
+	s.follows = append(s.follows, follow)
+	return database.CreateFeedFollowRow{
+		ID:        follow.ID,
+		CreatedAt: follow.CreatedAt,
+		UpdatedAt: follow.UpdatedAt,
+		UserID:    follow.UserID,
+		FeedID:    follow.FeedID,
+		FolderID:  follow.FolderID,
+		Title:     follow.Title,
+		Muted:     follow.Muted,
+		Notify:    follow.Notify,
+		Priority:  follow.Priority,
+		FeedName:  s.feeds[feedIndex].Name,
+		UserName:  s.users[userIndex].Name,
+	}, nil
+}
+
+// GetFeedFollowsForUser lists the user's follows ordered by folder (none first),
+// priority (highest first) and name.
+func (s *Store) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rows []database.GetFeedFollowsForUserRow
+	for _, follow := range s.follows {
+		if follow.UserID != userID {
+			continue
+		}
+		row := database.GetFeedFollowsForUserRow{
+			ID:        follow.ID,
+			CreatedAt: follow.CreatedAt,
+			UpdatedAt: follow.UpdatedAt,
+			UserID:    follow.UserID,
+			FeedID:    follow.FeedID,
+			FolderID:  follow.FolderID,
+			Title:     follow.Title,
+			Muted:     follow.Muted,
+			Notify:    follow.Notify,
+			Priority:  follow.Priority,
+			FeedName:  s.followName(follow),
+			UserName:  s.users[s.userIndex(follow.UserID)].Name,
+		}
+		if follow.FolderID.Valid {
+			if i := s.folderIndex(follow.FolderID.UUID); i >= 0 {
+				row.FolderName = sql.NullString{String: s.folders[i].Name, Valid: true}
+			}
+		}
+		rows = append(rows, row)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.FolderName != b.FolderName {
+			return !a.FolderName.Valid || (b.FolderName.Valid && a.FolderName.String < b.FolderName.String)
+		}
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.FeedName < b.FeedName
+	})
+	return rows, nil
+}
+
+func (s *Store) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.follows = filter(s.follows, func(follow database.FeedFollow) bool {
+		return follow.UserID != arg.UserID || follow.FeedID != arg.FeedID
+	})
+	return nil
+}
+
+func (s *Store) SetFeedFollowFolder(ctx context.Context, arg database.SetFeedFollowFolderParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.followIndex(arg.UserID, arg.FeedID)
+	if i < 0 {
+		return 0, nil
+	}
+	s.follows[i].FolderID = arg.FolderID
+	s.follows[i].UpdatedAt = arg.UpdatedAt
+	return 1, nil
+}
+
+// UpdateFeedFollowSettings changes the settings given as valid arguments and keeps the
+// others; ClearTitle removes the custom title.
+func (s *Store) UpdateFeedFollowSettings(ctx context.Context, arg database.UpdateFeedFollowSettingsParams) (database.FeedFollow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.followIndex(arg.UserID, arg.FeedID)
+	if i < 0 {
+		return database.FeedFollow{}, sql.ErrNoRows
+	}
+	follow := &s.follows[i]
+	switch {
+	case arg.ClearTitle:
+		follow.Title = sql.NullString{}
+	case arg.Title.Valid:
+		follow.Title = arg.Title
+	}
+	if arg.Muted.Valid {
+		follow.Muted = arg.Muted.Bool
+	}
+	if arg.Notify.Valid {
+		follow.Notify = arg.Notify.Bool
+	}
+	if arg.Priority.Valid {
+		follow.Priority = arg.Priority.Int32
+	}
+	follow.UpdatedAt = arg.UpdatedAt
+	return *follow, nil
+}
+
+// WebSub subscriptions
+
+// UpsertWebSubSubscription creates the feed's subscription or points the existing one
+// at the given hub, keeping its ID. A new hub or topic also gets the new secret and
+// drops the old lease; renewing at the same ones keeps both.
+func (s *Store) UpsertWebSubSubscription(ctx context.Context, arg database.UpsertWebSubSubscriptionParams) (database.WebsubSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, subscription := range s.subscriptions {
+		if subscription.FeedID == arg.FeedID {
+			if subscription.HubUrl != arg.HubUrl || subscription.TopicUrl != arg.TopicUrl {
+				s.subscriptions[i].Secret = arg.Secret
+				s.subscriptions[i].LeaseExpiresAt = sql.NullTime{}
+			}
+			s.subscriptions[i].HubUrl = arg.HubUrl
+			s.subscriptions[i].TopicUrl = arg.TopicUrl
+			s.subscriptions[i].UpdatedAt = arg.UpdatedAt
+			s.subscriptions[i].RequestedAt = arg.RequestedAt
+			return s.subscriptions[i], nil
+		}
+	}
+	if s.feedIndex(arg.FeedID) < 0 {
+		return database.WebsubSubscription{}, foreignKeyViolation("websub_subscriptions_feed_id_fkey")
+	}
+	subscription := database.WebsubSubscription{
+		ID:          arg.ID,
+		CreatedAt:   arg.CreatedAt,
+		UpdatedAt:   arg.UpdatedAt,
+		FeedID:      arg.FeedID,
+		HubUrl:      arg.HubUrl,
+		TopicUrl:    arg.TopicUrl,
+		Secret:      arg.Secret,
+		RequestedAt: arg.RequestedAt,
+	}
+	s.subscriptions = append(s.subscriptions, subscription)
+	return subscription, nil
+}
+
+func (s *Store) GetWebSubSubscription(ctx context.Context, id uuid.UUID) (database.WebsubSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, subscription := range s.subscriptions {
+		if subscription.ID == id {
+			return subscription, nil
+		}
+	}
+	return database.WebsubSubscription{}, sql.ErrNoRows
+}
+
+func (s *Store) GetWebSubSubscriptionForFeed(ctx context.Context, feedID uuid.UUID) (database.WebsubSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, subscription := range s.subscriptions {
+		if subscription.FeedID == feedID {
+			return subscription, nil
+		}
+	}
+	return database.WebsubSubscription{}, sql.ErrNoRows
+}
+
+func (s *Store) ConfirmWebSubSubscription(ctx context.Context, arg database.ConfirmWebSubSubscriptionParams) (database.WebsubSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, subscription := range s.subscriptions {
+		if subscription.ID == arg.ID {
+			s.subscriptions[i].LeaseExpiresAt = arg.LeaseExpiresAt
+			s.subscriptions[i].UpdatedAt = arg.UpdatedAt
+			return s.subscriptions[i], nil
+		}
+	}
+	return database.WebsubSubscription{}, sql.ErrNoRows
+}
+
+func (s *Store) GetWebSubSubscriptionsToRenew(ctx context.Context, arg database.GetWebSubSubscriptionsToRenewParams) ([]database.WebsubSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return filter(s.subscriptions, func(subscription database.WebsubSubscription) bool {
+		return subscription.LeaseExpiresAt.Valid &&
+			subscription.LeaseExpiresAt.Time.Before(arg.ExpiresBefore) &&
+			subscription.RequestedAt.Before(arg.RequestedBefore)
+	}), nil
+}
+
+func (s *Store) DeleteWebSubSubscription(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscriptions = filter(s.subscriptions, func(subscription database.WebsubSubscription) bool {
+		return subscription.ID != id
+	})
+	return nil
+}
+
+// Lookups; callers hold the lock.
+
+func (s *Store) userIndex(id uuid.UUID) int {
+	for i, user := range s.users {
+		if user.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Store) feedIndex(id uuid.UUID) int {
+	for i, feed := range s.feeds {
+		if feed.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Store) folderIndex(id uuid.UUID) int {
+	for i, folder := range s.folders {
+		if folder.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Store) followIndex(userID, feedID uuid.UUID) int {
+	for i, follow := range s.follows {
+		if follow.UserID == userID && follow.FeedID == feedID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Store) postIndex(id uuid.UUID) int {
+	for i, post := range s.posts {
+		if post.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// followName is the name a follower sees for the feed: their own title if they set one.
+func (s *Store) followName(follow database.FeedFollow) string {
+	if follow.Title.Valid {
+		return follow.Title.String
+	}
+	return s.feeds[s.feedIndex(follow.FeedID)].Name
+}
+
+// filter returns a new slice of the rows that keep accepts.
+func filter[T any](rows []T, keep func(T) bool) []T {
+	var kept []T
+	for _, row := range rows {
+		if keep(row) {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,238 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+var (
+	ctx       = context.Background()
+	now       = time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	missingID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+)
+
+// seed returns a store holding one user who added one feed.
+func seed(t *testing.T) (*Store, database.User, database.Feed) {
+	t.Helper()
+	s := New()
+	user, err := s.CreateUser(ctx, database.CreateUserParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: sql.NullString{String: "alice", Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := s.CreateFeed(ctx, database.CreateFeedParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "Tech", Url: "https://example.com/feed",
+		UserID: uuid.NullUUID{UUID: user.ID, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, user, feed
+}
+
+func createPost(s *Store, feed database.Feed, url string) (database.Post, error) {
+	id := uuid.New()
+	return s.CreatePost(ctx, database.CreatePostParams{
+		ID: id, CreatedAt: now, UpdatedAt: now, Title: url, Url: url, FeedID: feed.ID, StoryID: id,
+	})
+}
+
+func TestConstraints(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(s *Store, user database.User, feed database.Feed) error
+		want string
+	}{
+		{"duplicate user name", func(s *Store, user database.User, feed database.Feed) error {
+			_, err := s.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), Name: user.Name})
+			return err
+		}, "users_name_key"},
+		{"duplicate feed URL", func(s *Store, user database.User, feed database.Feed) error {
+			_, err := s.CreateFeed(ctx, database.CreateFeedParams{ID: uuid.New(), Name: "Again", Url: feed.Url})
+			return err
+		}, "feeds_url_key"},
+		{"feed of a missing user", func(s *Store, user database.User, feed database.Feed) error {
+			_, err := s.CreateFeed(ctx, database.CreateFeedParams{
+				ID: uuid.New(), Url: "https://example.com/other", UserID: uuid.NullUUID{UUID: missingID, Valid: true},
+			})
+			return err
+		}, "feeds_user_id_fkey"},
+		{"duplicate follow", func(s *Store, user database.User, feed database.Feed) error {
+			arg := database.CreateFeedFollowParams{ID: uuid.New(), UserID: user.ID, FeedID: feed.ID}
+			if _, err := s.CreateFeedFollow(ctx, arg); err != nil {
+				return err
+			}
+			arg.ID = uuid.New()
+			_, err := s.CreateFeedFollow(ctx, arg)
+			return err
+		}, "feed_follows_user_id_feed_id_key"},
+		{"follow of a missing feed", func(s *Store, user database.User, feed database.Feed) error {
+			_, err := s.CreateFeedFollow(ctx, database.CreateFeedFollowParams{ID: uuid.New(), UserID: user.ID, FeedID: missingID})
+			return err
+		}, "feed_follows_feed_id_fkey"},
+		{"post of a missing feed", func(s *Store, user database.User, feed database.Feed) error {
+			_, err := createPost(s, database.Feed{ID: missingID}, "https://example.com/a")
+			return err
+		}, "posts_feed_id_fkey"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, user, feed := seed(t)
+			if err := tt.run(s, user, feed); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v; want a violation of %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreatePostSkipsDuplicateURL(t *testing.T) {
+	s, _, feed := seed(t)
+	if _, err := createPost(s, feed, "https://example.com/a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createPost(s, feed, "https://example.com/a"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("second post with the same URL: got %v; want sql.ErrNoRows", err)
+	}
+	if urls, _ := s.GetPostURLs(ctx); len(urls) != 1 {
+		t.Errorf("got %d posts; want 1", len(urls))
+	}
+}
+
+func TestDeleteUsersCascades(t *testing.T) {
+	s, user, feed := seed(t)
+	post, err := createPost(s, feed, "https://example.com/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.MarkPostRead(ctx, database.MarkPostReadParams{UserID: user.ID, PostID: post.ID, ReadAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreatePrunedPost(ctx, database.CreatePrunedPostParams{FeedID: feed.ID, Url: "https://example.com/b", PrunedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteUsers(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if feeds, _ := s.GetFeeds(ctx); len(feeds) != 0 {
+		t.Errorf("feeds after deleting users: got %d", len(feeds))
+	}
+	if urls, _ := s.GetPostURLs(ctx); len(urls) != 0 {
+		t.Errorf("posts after deleting users: got %d", len(urls))
+	}
+	if len(s.reads) != 0 || len(s.pruned) != 0 {
+		t.Errorf("reads and pruned posts after deleting users: got %d and %d", len(s.reads), len(s.pruned))
+	}
+}
+
+func TestInTx(t *testing.T) {
+	tests := []struct {
+		name  string
+		fail  bool
+		posts int
+	}{
+		{"commit", false, 2},
+		{"rollback", true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, feed := seed(t)
+			errFail := errors.New("fail")
+			err := s.InTx(func() error {
+				for _, url := range []string{"https://example.com/a", "https://example.com/b"} {
+					if _, err := createPost(s, feed, url); err != nil {
+						return err
+					}
+				}
+				if tt.fail {
+					return errFail
+				}
+				return nil
+			})
+			if tt.fail != errors.Is(err, errFail) {
+				t.Errorf("InTx: got %v", err)
+			}
+			if urls, _ := s.GetPostURLs(ctx); len(urls) != tt.posts {
+				t.Errorf("got %d posts; want %d", len(urls), tt.posts)
+			}
+		})
+	}
+}
+
+func TestUpsertWebSubSubscription(t *testing.T) {
+	lease := sql.NullTime{Time: now.Add(time.Hour), Valid: true}
+	tests := []struct {
+		name, hub, topic string
+		keepSecret       bool
+	}{
+		{"renewal", "https://hub.example.com/", "https://example.com/feed", true},
+		{"new hub", "https://other-hub.example.com/", "https://example.com/feed", false},
+		{"new topic", "https://hub.example.com/", "https://example.com/feed.atom", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, feed := seed(t)
+			first, err := s.UpsertWebSubSubscription(ctx, database.UpsertWebSubSubscriptionParams{
+				ID: uuid.New(), FeedID: feed.ID, HubUrl: "https://hub.example.com/", TopicUrl: "https://example.com/feed",
+				Secret: "old", RequestedAt: now,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.ConfirmWebSubSubscription(ctx, database.ConfirmWebSubSubscriptionParams{ID: first.ID, LeaseExpiresAt: lease}); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := s.UpsertWebSubSubscription(ctx, database.UpsertWebSubSubscriptionParams{
+				ID: uuid.New(), FeedID: feed.ID, HubUrl: tt.hub, TopicUrl: tt.topic, Secret: "new", RequestedAt: now.Add(time.Minute),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != first.ID || got.HubUrl != tt.hub || got.TopicUrl != tt.topic || !got.RequestedAt.Equal(now.Add(time.Minute)) {
+				t.Errorf("got %+v", got)
+			}
+			if tt.keepSecret != (got.Secret == "old") || tt.keepSecret != got.LeaseExpiresAt.Valid {
+				t.Errorf("secret %q and lease %v; want them kept: %v", got.Secret, got.LeaseExpiresAt, tt.keepSecret)
+			}
+		})
+	}
+}
+
+func TestPrunedPosts(t *testing.T) {
+	s, _, feed := seed(t)
+	for _, url := range []string{"https://example.com/b", "https://example.com/a", "https://example.com/a"} {
+		if err := s.CreatePrunedPost(ctx, database.CreatePrunedPostParams{FeedID: feed.ID, Url: url, PrunedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	urls, err := s.GetFeedPrunedURLs(ctx, feed.ID)
+	if err != nil || strings.Join(urls, " ") != "https://example.com/a https://example.com/b" {
+		t.Errorf("GetFeedPrunedURLs = %q, %v", urls, err)
+	}
+
+	if err := s.DeletePrunedPost(ctx, database.DeletePrunedPostParams{FeedID: feed.ID, Url: "https://example.com/a"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url    string
+		pruned bool
+	}{
+		{"https://example.com/a", false},
+		{"https://example.com/b", true},
+		{"https://example.com/c", false},
+	}
+	for _, tt := range tests {
+		if pruned, err := s.IsPostPruned(ctx, database.IsPostPrunedParams{FeedID: feed.ID, Url: tt.url}); err != nil || pruned != tt.pruned {
+			t.Errorf("IsPostPruned(%s) = %v, %v; want %v", tt.url, pruned, err, tt.pruned)
+		}
+	}
+}
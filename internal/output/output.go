@@ -0,0 +1,193 @@
+// Package output renders the results of listing commands in the format chosen with the
+// global --output flag: the commands' own human-readable text (the default), an aligned
+// table, JSON, newline-delimited JSON, CSV, or a Go text/template.
+//
+// Commands describe each listed item with a struct whose exported fields carry json
+// tags; the tags name the JSON keys, the table and CSV columns, and the template fields
+// are the Go field names (e.g. {{.Title}}).
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+)
+
+// Formats accepted by New, besides "template=<text>".
+const (
+	Text   = "text"
+	Table  = "table"
+	JSON   = "json"
+	NDJSON = "ndjson"
+	CSV    = "csv"
+)
+
+// templatePrefix introduces a template format spec.
+const templatePrefix = "template="
+
+// Printer writes listings in one format.
+type Printer struct {
+	format string
+	tmpl   *template.Template
+	w      io.Writer
+}
+
+// New returns a Printer for a format spec: "" or "text", "table", "json", "ndjson",
+// "csv", or "template=<Go template>" executed once per item.
+func New(spec string, w io.Writer) (*Printer, error) {
+	switch spec {
+	case "", Text:
+		return &Printer{format: Text, w: w}, nil
+	case Table, JSON, NDJSON, CSV:
+		return &Printer{format: spec, w: w}, nil
+	}
+
+	if text, ok := strings.CutPrefix(spec, templatePrefix); ok {
+		if !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		tmpl, err := template.New("output").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("output: invalid template: %w", err)
+		}
+		return &Printer{format: "template", tmpl: tmpl, w: w}, nil
+	}
+	return nil, fmt.Errorf("output: unknown format %q (want text, table, json, ndjson, csv or template=...)", spec)
+}
+
+// IsText reports whether commands should print their usual human-readable text.
+func (p *Printer) IsText() bool {
+	return p.format == Text
+}
+
+// Print renders items, a slice of structs, in the printer's format. In text format it
+// calls text instead, which prints the command's usual output.
+func (p *Printer) Print(items any, text func()) error {
+	if p.IsText() {
+		text()
+		return nil
+	}
+
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("output: Print needs a slice, got %T", items)
+	}
+	elem := v.Type().Elem()
+	if elem.Kind() != reflect.Struct {
+		return fmt.Errorf("output: Print needs a slice of structs, got %T", items)
+	}
+
+	switch p.format {
+	case JSON:
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		if v.Len() == 0 {
+			items = []struct{}{} // [] rather than null
+		}
+		return enc.Encode(items)
+
+	case NDJSON:
+		enc := json.NewEncoder(p.w)
+		for i := 0; i < v.Len(); i++ {
+			if err := enc.Encode(v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case CSV:
+		cw := csv.NewWriter(p.w)
+		cw.Write(columns(elem))
+		for i := 0; i < v.Len(); i++ {
+			cw.Write(cells(v.Index(i)))
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case Table:
+		tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+		var header []string
+		for _, column := range columns(elem) {
+			header = append(header, strings.ToUpper(column))
+		}
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for i := 0; i < v.Len(); i++ {
+			row := cells(v.Index(i))
+			for j, cell := range row {
+				// Keep each item on one line so the columns stay aligned.
+				row[j] = strings.Join(strings.Fields(cell), " ")
+			}
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+
+	default:
+		for i := 0; i < v.Len(); i++ {
+			if err := p.tmpl.Execute(p.w, v.Index(i).Interface()); err != nil {
+				return fmt.Errorf("output: %w", err)
+			}
+		}
+		return nil
+	}
+}
+
+// columns returns the json tag names of a struct type's exported fields.
+func columns(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := columnName(t.Field(i)); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// columnName returns the field's json name, or false if the field isn't output.
+func columnName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		return field.Name, true
+	}
+	return name, true
+}
+
+// cells formats a struct value's output fields for a table or CSV row.
+func cells(v reflect.Value) []string {
+	var row []string
+	for i := 0; i < v.NumField(); i++ {
+		if _, ok := columnName(v.Type().Field(i)); ok {
+			row = append(row, cell(v.Field(i)))
+		}
+	}
+	return row
+}
+
+// cell formats one value: times as RFC 3339, lists joined with commas, nil pointers as
+// empty cells, and everything else with fmt.
+func cell(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch value := v.Interface().(type) {
+	case time.Time:
+		return value.Format(time.RFC3339)
+	case []string:
+		return strings.Join(value, ", ")
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,136 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type item struct {
+	Title    string     `json:"title"`
+	Tags     []string   `json:"tags"`
+	Read     *time.Time `json:"read_at,omitempty"`
+	Count    int
+	Internal string `json:"-"`
+	hidden   string
+}
+
+func testItems() []item {
+	read := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	return []item{
+		{Title: "Go 1.30\nreleased", Tags: []string{"go", "news"}, Read: &read, Count: 3, Internal: "x", hidden: "y"},
+		{Title: `Say "hi", please`, Count: 0},
+	}
+}
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		spec, want string
+	}{
+		{"json", `[
+  {
+    "title": "Go 1.30\nreleased",
+    "tags": [
+      "go",
+      "news"
+    ],
+    "read_at": "2026-01-02T10:00:00Z",
+    "Count": 3
+  },
+  {
+    "title": "Say \"hi\", please",
+    "tags": null,
+    "Count": 0
+  }
+]
+`},
+		{"ndjson", `{"title":"Go 1.30\nreleased","tags":["go","news"],"read_at":"2026-01-02T10:00:00Z","Count":3}
+{"title":"Say \"hi\", please","tags":null,"Count":0}
+`},
+		{"csv", `title,tags,read_at,Count
+"Go 1.30
+released","go, news",2026-01-02T10:00:00Z,3
+"Say ""hi"", please",,,0
+`},
+		{"table", `TITLE             TAGS      READ_AT               COUNT
+Go 1.30 released  go, news  2026-01-02T10:00:00Z  3
+Say "hi", please                                  0
+`},
+		{"template={{.Title | printf \"%q\"}} {{.Count}}", `"Go 1.30\nreleased" 3
+"Say \"hi\", please" 0
+`},
+		{"template={{.Count}}\n", "3\n0\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			var buf bytes.Buffer
+			p, err := New(tt.spec, &buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.IsText() {
+				t.Errorf("%q is text", tt.spec)
+			}
+			if err := p.Print(testItems(), func() { t.Error("text called") }); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintEmpty(t *testing.T) {
+	tests := []struct {
+		spec, want string
+	}{
+		{"json", "[]\n"},
+		{"ndjson", ""},
+		{"csv", "title,tags,read_at,Count\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		p, err := New(tt.spec, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Print([]item{}, nil); err != nil || buf.String() != tt.want {
+			t.Errorf("%s: got %q, %v; want %q", tt.spec, buf.String(), err, tt.want)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	for _, spec := range []string{"", "text"} {
+		p, err := New(spec, nil)
+		if err != nil || !p.IsText() {
+			t.Fatalf("New(%q) = %v, %v; want a text printer", spec, p, err)
+		}
+		called := false
+		if err := p.Print(42, func() { called = true }); err != nil || !called {
+			t.Errorf("New(%q).Print: err %v, text called %v", spec, err, called)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, spec := range []string{"xml", "JSON", "template={{.Title"} {
+		if _, err := New(spec, nil); err == nil {
+			t.Errorf("New(%q) succeeded", spec)
+		}
+	}
+
+	p, _ := New("json", &bytes.Buffer{})
+	for _, items := range []any{item{}, []int{1}} {
+		if err := p.Print(items, nil); err == nil {
+			t.Errorf("Print(%T) succeeded", items)
+		}
+	}
+
+	p, _ = New("template={{.Missing}}", &bytes.Buffer{})
+	if err := p.Print(testItems(), nil); err == nil || !strings.HasPrefix(err.Error(), "output: ") {
+		t.Errorf("Print with a missing template field: got %v", err)
+	}
+}
@@ -0,0 +1,421 @@
+// Package readability extracts the main article body from a web page, in the spirit
+// of Arc90's Readability: paragraphs score the elements that contain them, the best
+// scoring element is taken as the article, and related siblings are pulled in with it.
+// The result is cleaned down to a small set of structural tags with absolute links.
+package readability
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ErrNoContent is returned when no element on the page looks like an article body.
+var ErrNoContent = errors.New("readability: no article content found")
+
+// Article is the extracted main content of a page.
+type Article struct {
+	Title string // contents of the page's <title>
+	HTML  string // cleaned article markup
+	Text  string // plain text of the article, paragraphs separated by blank lines
+}
+
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)banner|breadcrumbs|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|nav|pager|popup|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|ad-break|agegate|pagination|promo`)
+	maybeCandidate     = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveWeight     = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story`)
+	negativeWeight     = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|footer|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|widget`)
+	whitespaceRun      = regexp.MustCompile(`\s+`)
+)
+
+// removedTags are dropped from the document before scoring; they never hold article text.
+var removedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true,
+	atom.Form: true, atom.Button: true, atom.Input: true, atom.Select: true,
+	atom.Textarea: true, atom.Svg: true, atom.Canvas: true, atom.Object: true,
+	atom.Embed: true, atom.Nav: true, atom.Aside: true, atom.Footer: true,
+	atom.Link: true, atom.Meta: true,
+}
+
+// keptTags is the allow-list of elements that survive in Article.HTML. Other elements
+// are unwrapped, keeping their children.
+var keptTags = map[atom.Atom]bool{
+	atom.P: true, atom.Br: true, atom.Hr: true, atom.H1: true, atom.H2: true,
+	atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true, atom.A: true,
+	atom.Img: true, atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Blockquote: true,
+	atom.Pre: true, atom.Code: true, atom.Em: true, atom.Strong: true, atom.B: true,
+	atom.I: true, atom.Figure: true, atom.Figcaption: true, atom.Table: true,
+	atom.Thead: true, atom.Tbody: true, atom.Tr: true, atom.Th: true, atom.Td: true,
+	atom.Dl: true, atom.Dt: true, atom.Dd: true, atom.Sup: true, atom.Sub: true,
+}
+
+// keptAttrs lists the attributes kept on each allowed element.
+var keptAttrs = map[atom.Atom][]string{
+	atom.A:   {"href", "title"},
+	atom.Img: {"src", "alt", "title"},
+	atom.Td:  {"colspan", "rowspan"},
+	atom.Th:  {"colspan", "rowspan"},
+}
+
+// blockTags separate paragraphs when flattening the article to text.
+var blockTags = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.H1: true, atom.H2: true, atom.H3: true,
+	atom.H4: true, atom.H5: true, atom.H6: true, atom.Li: true, atom.Blockquote: true,
+	atom.Pre: true, atom.Tr: true, atom.Figure: true, atom.Figcaption: true,
+	atom.Section: true, atom.Article: true, atom.Dt: true, atom.Dd: true, atom.Br: true,
+}
+
+// Extract parses the page read from r and returns its main article. pageURL is used to
+// make links and image sources absolute; it may be nil. Returns ErrNoContent if the page
+// has no element with enough paragraph text to be considered an article.
+func Extract(r io.Reader, pageURL *url.URL) (Article, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return Article{}, err
+	}
+
+	article := Article{Title: collapse(textContent(findFirst(doc, atom.Title)))}
+
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		return Article{}, ErrNoContent
+	}
+	prune(body)
+
+	top := topCandidate(body)
+	if top == nil {
+		return Article{}, ErrNoContent
+	}
+
+	content := gatherSiblings(top)
+	clean(content, pageURL)
+
+	var buf bytes.Buffer
+	for child := content.FirstChild; child != nil; child = child.NextSibling {
+		if err := html.Render(&buf, child); err != nil {
+			return Article{}, err
+		}
+	}
+	article.HTML = strings.TrimSpace(buf.String())
+	article.Text = blockText(content)
+	if article.Text == "" {
+		return Article{}, ErrNoContent
+	}
+	return article, nil
+}
+
+// prune removes elements that can't be part of the article: non-content tags, hidden
+// elements, and containers whose class or id mark them as page chrome.
+func prune(n *html.Node) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.CommentNode {
+			n.RemoveChild(child)
+		} else if child.Type == html.ElementNode {
+			match := attr(child, "class") + " " + attr(child, "id")
+			unlikely := unlikelyCandidates.MatchString(match) && !maybeCandidate.MatchString(match) &&
+				child.DataAtom != atom.Body && child.DataAtom != atom.A && child.DataAtom != atom.Article
+			hidden := hasAttr(child, "hidden") || strings.Contains(strings.ReplaceAll(attr(child, "style"), " ", ""), "display:none")
+			if removedTags[child.DataAtom] || unlikely || hidden {
+				n.RemoveChild(child)
+			} else {
+				prune(child)
+			}
+		}
+		child = next
+	}
+}
+
+// topCandidate scores every element that contains paragraph-like text and returns the
+// one with the highest score after penalising link-heavy elements. Of equal scores the
+// element scored first wins, so the same page always gives the same article.
+func topCandidate(body *html.Node) *html.Node {
+	scores := map[*html.Node]float64{}
+	var order []*html.Node // scored elements, in the order they were first scored
+
+	initScore := func(n *html.Node) {
+		if _, ok := scores[n]; ok {
+			return
+		}
+		score := classWeight(n)
+		switch n.DataAtom {
+		case atom.Div, atom.Article, atom.Section, atom.Main:
+			score += 5
+		case atom.Pre, atom.Td, atom.Blockquote:
+			score += 3
+		case atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+			score -= 3
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+			score -= 5
+		}
+		scores[n] = score
+		order = append(order, n)
+	}
+
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			if child.DataAtom == atom.P || child.DataAtom == atom.Pre || child.DataAtom == atom.Td || child.DataAtom == atom.Blockquote {
+				text := collapse(textContent(child))
+				if len(text) >= 25 && child.Parent != nil {
+					score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+
+					parent := child.Parent
+					initScore(parent)
+					scores[parent] += score
+					if grandparent := parent.Parent; grandparent != nil && grandparent.Type == html.ElementNode {
+						initScore(grandparent)
+						scores[grandparent] += score / 2
+					}
+				}
+			}
+			visit(child)
+		}
+	}
+	visit(body)
+
+	var best *html.Node
+	bestScore := 0.0
+	for _, n := range order {
+		score := scores[n] * (1 - linkDensity(n))
+		scores[n] = score
+		if best == nil || score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	return best
+}
+
+// gatherSiblings returns a new container holding the top candidate plus any siblings
+// that scored well or look like standalone paragraphs of the same article.
+func gatherSiblings(top *html.Node) *html.Node {
+	container := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	if top.Parent == nil || top.DataAtom == atom.Body {
+		moveChildren(top, container)
+		return container
+	}
+
+	topClass := attr(top, "class")
+	for sibling := top.Parent.FirstChild; sibling != nil; {
+		next := sibling.NextSibling
+		include := sibling == top
+		if !include && sibling.Type == html.ElementNode {
+			if topClass != "" && attr(sibling, "class") == topClass {
+				include = true
+			} else if sibling.DataAtom == atom.P {
+				text := collapse(textContent(sibling))
+				density := linkDensity(sibling)
+				include = (len(text) > 80 && density < 0.25) ||
+					(len(text) > 0 && density == 0 && strings.HasSuffix(text, "."))
+			}
+		}
+		if include {
+			sibling.Parent.RemoveChild(sibling)
+			container.AppendChild(sibling)
+		}
+		sibling = next
+	}
+	return container
+}
+
+// clean reduces the subtree to the allow-listed tags and attributes, drops empty
+// paragraphs, and resolves link and image URLs against base.
+func clean(n *html.Node, base *url.URL) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.ElementNode {
+			clean(child, base)
+
+			if !keptTags[child.DataAtom] {
+				// Unwrap: splice the element's children in its place.
+				for grandchild := child.FirstChild; grandchild != nil; {
+					after := grandchild.NextSibling
+					child.RemoveChild(grandchild)
+					n.InsertBefore(grandchild, child)
+					grandchild = after
+				}
+				n.RemoveChild(child)
+			} else if child.DataAtom == atom.P && strings.TrimSpace(textContent(child)) == "" && findFirst(child, atom.Img) == nil {
+				n.RemoveChild(child)
+			} else {
+				filterAttrs(child, base)
+			}
+		} else if child.Type != html.TextNode {
+			n.RemoveChild(child)
+		}
+		child = next
+	}
+}
+
+// filterAttrs keeps only the attributes allowed for the element, making URLs absolute.
+func filterAttrs(n *html.Node, base *url.URL) {
+	var kept []html.Attribute
+	for _, a := range n.Attr {
+		for _, allowed := range keptAttrs[n.DataAtom] {
+			if a.Namespace != "" || a.Key != allowed {
+				continue
+			}
+			if a.Key == "href" || a.Key == "src" {
+				a.Val = resolve(base, a.Val)
+			}
+			kept = append(kept, a)
+		}
+	}
+	n.Attr = kept
+}
+
+// resolve makes ref absolute against base, leaving it untouched if either is unusable.
+func resolve(base *url.URL, ref string) string {
+	if base == nil {
+		return ref
+	}
+	refURL, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(refURL).String()
+}
+
+// classWeight rewards class/id values that look like article containers and penalises
+// ones that look like page chrome.
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, value := range []string{attr(n, "class"), attr(n, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativeWeight.MatchString(value) {
+			weight -= 25
+		}
+		if positiveWeight.MatchString(value) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the fraction of an element's text that sits inside links.
+func linkDensity(n *html.Node) float64 {
+	textLength := len(collapse(textContent(n)))
+	if textLength == 0 {
+		return 0
+	}
+	linkLength := 0
+	var visit func(*html.Node)
+	visit = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode && child.DataAtom == atom.A {
+				linkLength += len(collapse(textContent(child)))
+				continue
+			}
+			visit(child)
+		}
+	}
+	visit(n)
+	return float64(linkLength) / float64(textLength)
+}
+
+// blockText flattens the subtree to text, with a blank line between block elements.
+func blockText(n *html.Node) string {
+	var paragraphs []string
+	var current strings.Builder
+
+	flush := func() {
+		if text := collapse(current.String()); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+		current.Reset()
+	}
+
+	var visit func(*html.Node)
+	visit = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			switch {
+			case child.Type == html.TextNode:
+				current.WriteString(child.Data)
+			case child.Type == html.ElementNode && blockTags[child.DataAtom]:
+				flush()
+				visit(child)
+				flush()
+			case child.Type == html.ElementNode:
+				visit(child)
+			}
+		}
+	}
+	visit(n)
+	flush()
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// textContent concatenates all text below n.
+func textContent(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(textContent(child))
+	}
+	return sb.String()
+}
+
+// findFirst returns the first element of the given type in document order, or nil.
+func findFirst(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findFirst(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// moveChildren moves all children of from to the end of to.
+func moveChildren(from, to *html.Node) {
+	for child := from.FirstChild; child != nil; {
+		next := child.NextSibling
+		from.RemoveChild(child)
+		to.AppendChild(child)
+		child = next
+	}
+}
+
+// attr returns the value of the named attribute, or "" if it's absent.
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasAttr reports whether the named attribute is present.
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// collapse trims s and squeezes runs of whitespace into single spaces.
+func collapse(s string) string {
+	return strings.TrimSpace(whitespaceRun.ReplaceAllString(s, " "))
+}
@@ -0,0 +1,153 @@
+package readability
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// paragraph is long enough, with enough commas, to count as article text.
+const paragraph = "This paragraph is long enough to count as article text, with a few commas, clauses, and words so that it scores well."
+
+func TestExtract(t *testing.T) {
+	base, _ := url.Parse("https://example.com/posts/1")
+	tests := []struct {
+		name     string
+		page     string
+		want     []string // in Article.HTML
+		wantNot  []string
+		wantText string // prefix of Article.Text
+	}{
+		{
+			name: "article among page chrome",
+			page: `<html><head><title>A post</title></head><body>
+<nav><a href="/">Home</a> <a href="/about">About</a></nav>
+<div class="sidebar"><p>` + paragraph + `</p></div>
+<article><p>` + paragraph + `</p><p>Second ` + paragraph + `</p></article>
+<footer>Copyright</footer></body></html>`,
+			want:     []string{"<p>This paragraph", "<p>Second This paragraph"},
+			wantNot:  []string{"Home", "Copyright", "<article"},
+			wantText: paragraph + "\n\nSecond ",
+		},
+		{
+			name:    "links and images made absolute, attributes dropped",
+			page:    `<body><div id="content"><p class="x" onclick="evil()">` + paragraph + ` <a href="../2" style="color:red">next</a></p><p><img src="/img.png" alt="pic" width="10"></p></div></body>`,
+			want:    []string{`<a href="https://example.com/2">next</a>`, `<img src="https://example.com/img.png" alt="pic"/>`},
+			wantNot: []string{"onclick", "style", "width", `class="x"`},
+		},
+		{
+			name:    "scripts and hidden elements removed",
+			page:    `<body><div><p>` + paragraph + `</p><script>alert(1)</script><p hidden>secret text</p><p style="display: none">also secret</p></div></body>`,
+			wantNot: []string{"alert", "secret"},
+		},
+		{
+			name: "wrappers unwrapped",
+			page: `<body><div><section><span><p>` + paragraph + `</p></span></section></div></body>`,
+			want: []string{"<p>This paragraph"}, wantNot: []string{"<span", "<section", "<div"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			article, err := Extract(strings.NewReader(tt.page), base)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(article.HTML, s) {
+					t.Errorf("HTML doesn't contain %q:\n%s", s, article.HTML)
+				}
+			}
+			for _, s := range tt.wantNot {
+				if strings.Contains(article.HTML, s) {
+					t.Errorf("HTML contains %q:\n%s", s, article.HTML)
+				}
+			}
+			if !strings.HasPrefix(article.Text, tt.wantText) {
+				t.Errorf("Text = %q, want it to start with %q", article.Text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestExtractTitle(t *testing.T) {
+	article, err := Extract(strings.NewReader(`<title> A  post </title><body><div><p>`+paragraph+`</p></div></body>`), nil)
+	if err != nil || article.Title != "A post" {
+		t.Errorf("Extract: title %q, %v", article.Title, err)
+	}
+}
+
+func TestExtractNoContent(t *testing.T) {
+	for _, page := range []string{
+		"",
+		"<body><p>Too short.</p></body>",
+		"<body><nav><p>" + paragraph + "</p></nav></body>",
+	} {
+		if article, err := Extract(strings.NewReader(page), nil); !errors.Is(err, ErrNoContent) {
+			t.Errorf("Extract(%q) = %+v, %v; want ErrNoContent", page, article, err)
+		}
+	}
+}
+
+func TestTopCandidateBreaksTiesInDocumentOrder(t *testing.T) {
+	page := `<body><div id="one"><p>` + paragraph + `</p></div><div id="two"><p>` + paragraph + `</p></div></body>`
+	for i := 0; i < 50; i++ {
+		doc, err := html.Parse(strings.NewReader(page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		top := topCandidate(findFirst(doc, atom.Body))
+		if top == nil || attr(top, "id") != "one" {
+			t.Fatalf("run %d: top candidate %v, want the first of two equal divs", i, top)
+		}
+	}
+}
+
+func TestLinkDensity(t *testing.T) {
+	tests := []struct {
+		page string
+		want float64
+	}{
+		{`<div>plain text only</div>`, 0},
+		{`<div><a href="/">all link</a></div>`, 1},
+		{`<div>half <a href="/">half</a></div>`, 4.0 / 9.0},
+	}
+	for _, tt := range tests {
+		doc, err := html.Parse(strings.NewReader(tt.page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		div := findFirst(doc, atom.Div)
+		if got := linkDensity(div); got != tt.want {
+			t.Errorf("linkDensity(%s) = %v, want %v", tt.page, got, tt.want)
+		}
+	}
+}
+
+func TestClassWeight(t *testing.T) {
+	tests := []struct {
+		class, id string
+		want      float64
+	}{
+		{"", "", 0},
+		{"post-content", "", 25},
+		{"sidebar", "", -25},
+		{"entry", "comments", 0},
+		{"", "main", 25},
+	}
+	for _, tt := range tests {
+		n := &html.Node{Type: html.ElementNode, Data: "div"}
+		if tt.class != "" {
+			n.Attr = append(n.Attr, html.Attribute{Key: "class", Val: tt.class})
+		}
+		if tt.id != "" {
+			n.Attr = append(n.Attr, html.Attribute{Key: "id", Val: tt.id})
+		}
+		if got := classWeight(n); got != tt.want {
+			t.Errorf("classWeight(class=%q id=%q) = %v, want %v", tt.class, tt.id, got, tt.want)
+		}
+	}
+}
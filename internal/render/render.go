@@ -0,0 +1,416 @@
+// Package render turns the HTML stored in post descriptions and content into output
+// that is safe and readable: Sanitize reduces it to an allow-list of tags and
+// attributes for HTML output, and Text converts it to word-wrapped terminal text with
+// numbered link footnotes. Relative URLs are resolved against a base URL in both.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowedTags maps each tag that survives sanitisation to the attributes it may keep.
+// Elements not listed are unwrapped (their children are kept), except droppedTags.
+var allowedTags = map[atom.Atom][]string{
+	atom.A: {"href", "title"}, atom.Img: {"src", "alt", "title"},
+	atom.P: nil, atom.Br: nil, atom.Hr: nil, atom.Div: nil, atom.Span: nil,
+	atom.H1: nil, atom.H2: nil, atom.H3: nil, atom.H4: nil, atom.H5: nil, atom.H6: nil,
+	atom.Ul: nil, atom.Ol: nil, atom.Li: nil, atom.Dl: nil, atom.Dt: nil, atom.Dd: nil,
+	atom.Blockquote: nil, atom.Pre: nil, atom.Code: nil,
+	atom.Em: nil, atom.Strong: nil, atom.B: nil, atom.I: nil, atom.U: nil, atom.S: nil,
+	atom.Sup: nil, atom.Sub: nil, atom.Small: nil, atom.Abbr: {"title"},
+	atom.Figure: nil, atom.Figcaption: nil,
+	atom.Table: nil, atom.Thead: nil, atom.Tbody: nil, atom.Tr: nil,
+	atom.Th: {"colspan", "rowspan"}, atom.Td: {"colspan", "rowspan"},
+}
+
+// droppedTags are removed together with everything inside them.
+var droppedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Iframe: true, atom.Object: true,
+	atom.Embed: true, atom.Noscript: true, atom.Template: true, atom.Form: true,
+	atom.Input: true, atom.Button: true, atom.Select: true, atom.Textarea: true,
+	atom.Svg: true, atom.Math: true, atom.Head: true, atom.Title: true,
+	atom.Link: true, atom.Meta: true, atom.Base: true,
+}
+
+// allowedSchemes are the URL schemes links and images may use after resolution.
+var allowedSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// Sanitize parses an HTML fragment and returns it reduced to the allow-list: unknown
+// tags are unwrapped, scripts, styles and embeds are removed with their contents,
+// event handlers and inline styles are stripped, and href/src values are resolved
+// against base (which may be nil) and dropped unless they use a safe scheme.
+func Sanitize(fragment string, base *url.URL) string {
+	root := sanitizedTree(fragment, base)
+
+	var buf bytes.Buffer
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		// Rendering only fails on write errors, which a bytes.Buffer never returns.
+		_ = html.Render(&buf, child)
+	}
+	return buf.String()
+}
+
+// sanitizedTree parses the fragment into a detached <body> node and sanitises it.
+func sanitizedTree(fragment string, base *url.URL) *html.Node {
+	root := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), root)
+	if err != nil {
+		// The HTML5 parser recovers from malformed markup, so this is only reached on
+		// reader errors; fall back to showing the input as plain text.
+		nodes = []*html.Node{{Type: html.TextNode, Data: fragment}}
+	}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+	sanitize(root, base)
+	return root
+}
+
+// sanitize applies the allow-list to n's children in place.
+func sanitize(n *html.Node, base *url.URL) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		switch child.Type {
+		case html.TextNode:
+		case html.ElementNode:
+			if droppedTags[child.DataAtom] {
+				n.RemoveChild(child)
+				break
+			}
+			sanitize(child, base)
+			allowedAttrs, ok := allowedTags[child.DataAtom]
+			if !ok {
+				for grandchild := child.FirstChild; grandchild != nil; {
+					after := grandchild.NextSibling
+					child.RemoveChild(grandchild)
+					n.InsertBefore(grandchild, child)
+					grandchild = after
+				}
+				n.RemoveChild(child)
+				break
+			}
+			child.Attr = filterAttrs(child.Attr, allowedAttrs, base)
+		default:
+			n.RemoveChild(child)
+		}
+		child = next
+	}
+}
+
+// filterAttrs keeps the allowed attributes, resolving and vetting URL values.
+func filterAttrs(attrs []html.Attribute, allowed []string, base *url.URL) []html.Attribute {
+	var kept []html.Attribute
+	for _, a := range attrs {
+		if a.Namespace != "" || !contains(allowed, a.Key) {
+			continue
+		}
+		if a.Key == "href" || a.Key == "src" {
+			resolved, ok := safeURL(a.Val, base)
+			if !ok {
+				continue
+			}
+			a.Val = resolved
+		}
+		kept = append(kept, a)
+	}
+	return kept
+}
+
+// safeURL resolves ref against base and reports whether the result uses an allowed
+// scheme. Relative references that can't be resolved (no base) are kept as they are.
+func safeURL(ref string, base *url.URL) (string, bool) {
+	refURL, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", false
+	}
+	if base != nil {
+		refURL = base.ResolveReference(refURL)
+	}
+	if refURL.Scheme == "" {
+		return refURL.String(), refURL.Opaque == ""
+	}
+	return refURL.String(), allowedSchemes[strings.ToLower(refURL.Scheme)]
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Text sanitises the fragment and renders it as plain terminal text wrapped to width
+// columns. Links and images are marked with [n] and listed as footnotes at the end;
+// lists, quotes and preformatted blocks keep a recognisable layout.
+func Text(fragment string, base *url.URL, width int) string {
+	if width < 20 {
+		width = 20
+	}
+	tr := &textRenderer{width: width}
+	tr.walk(sanitizedTree(fragment, base))
+	tr.flush()
+
+	out := strings.Join(tr.blocks, "\n\n")
+	if len(tr.links) > 0 {
+		var notes []string
+		for i, link := range tr.links {
+			notes = append(notes, fmt.Sprintf("[%d] %s", i+1, link))
+		}
+		if out != "" {
+			out += "\n\n"
+		}
+		out += strings.Join(notes, "\n")
+	}
+	return out
+}
+
+// inlineTags are the allowed tags that don't separate their text from the text around
+// them; every other element counts as a block.
+var inlineTags = map[atom.Atom]bool{
+	atom.A: true, atom.Span: true, atom.Code: true, atom.Em: true, atom.Strong: true,
+	atom.B: true, atom.I: true, atom.U: true, atom.S: true, atom.Sup: true, atom.Sub: true,
+	atom.Small: true, atom.Abbr: true,
+}
+
+// PlainText returns just the visible text of an HTML fragment on one line, with
+// whitespace collapsed and no footnotes; used for matching rather than display.
+func PlainText(fragment string) string {
+	var sb strings.Builder
+	writePlainText(&sb, sanitizedTree(fragment, nil))
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// writePlainText is textOf with a space after each block, so that adjacent paragraphs,
+// list items and table cells don't run their words together.
+func writePlainText(sb *strings.Builder, n *html.Node) {
+	if n.Type == html.TextNode {
+		sb.WriteString(n.Data)
+		return
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		writePlainText(sb, child)
+	}
+	if n.Type == html.ElementNode && !inlineTags[n.DataAtom] {
+		sb.WriteByte(' ')
+	}
+}
+
+// textRenderer accumulates inline text into the current paragraph and emits wrapped
+// blocks whenever a block-level element starts or ends.
+type textRenderer struct {
+	width  int
+	blocks []string        // finished, wrapped blocks
+	inline strings.Builder // text of the paragraph being built; '\n' marks a <br>
+	prefix string          // prepended to every line (quote markers, list indentation)
+	first  string          // replaces prefix on the paragraph's first line (list bullets)
+	links  []string        // footnote targets, numbered from 1
+}
+
+func (tr *textRenderer) walk(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		switch child.Type {
+		case html.TextNode:
+			tr.writeText(child.Data)
+		case html.ElementNode:
+			tr.element(child)
+		}
+	}
+}
+
+func (tr *textRenderer) element(n *html.Node) {
+	switch n.DataAtom {
+	case atom.Br:
+		tr.inline.WriteByte('\n')
+
+	case atom.A:
+		tr.walk(n)
+		if href := attr(n, "href"); href != "" && strings.TrimSpace(textOf(n)) != href {
+			tr.inline.WriteString(tr.footnote(href))
+		}
+
+	case atom.Img:
+		label := "image"
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			label = "image: " + alt
+		}
+		tr.inline.WriteString("[" + label + "]")
+		if src := attr(n, "src"); src != "" {
+			tr.inline.WriteString(tr.footnote(src))
+		}
+
+	case atom.Hr:
+		tr.flush()
+		tr.blocks = append(tr.blocks, strings.Repeat("-", min(tr.width, 40)))
+
+	case atom.Pre:
+		tr.flush()
+		var lines []string
+		for _, line := range strings.Split(strings.Trim(textOf(n), "\n"), "\n") {
+			lines = append(lines, tr.prefix+"    "+line)
+		}
+		tr.blocks = append(tr.blocks, strings.Join(lines, "\n"))
+
+	case atom.Blockquote:
+		tr.flush()
+		saved := tr.prefix
+		tr.prefix += "> "
+		tr.walk(n)
+		tr.flush()
+		tr.prefix = saved
+
+	case atom.Ul, atom.Ol:
+		tr.flush()
+		start := len(tr.blocks)
+		number := 0
+		for item := n.FirstChild; item != nil; item = item.NextSibling {
+			if item.Type != html.ElementNode || item.DataAtom != atom.Li {
+				if item.Type == html.ElementNode {
+					tr.element(item)
+				}
+				continue
+			}
+			number++
+			bullet := "* "
+			if n.DataAtom == atom.Ol {
+				bullet = fmt.Sprintf("%d. ", number)
+			}
+			saved := tr.prefix
+			tr.first = saved + "  " + bullet
+			tr.prefix = saved + strings.Repeat(" ", 2+len(bullet))
+			tr.walk(item)
+			tr.flush()
+			tr.prefix = saved
+		}
+		tr.flush()
+		// Keep list items on consecutive lines rather than separating them like paragraphs.
+		if len(tr.blocks) > start {
+			items := strings.Join(tr.blocks[start:], "\n")
+			tr.blocks = append(tr.blocks[:start], items)
+		}
+
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		tr.flush()
+		tr.walk(n)
+		heading := strings.TrimSpace(tr.inline.String())
+		tr.inline.Reset()
+		if heading != "" {
+			tr.blocks = append(tr.blocks, tr.prefix+strings.ToUpper(heading))
+		}
+
+	case atom.Td, atom.Th:
+		tr.walk(n)
+		tr.inline.WriteString("  ")
+
+	case atom.P, atom.Div, atom.Li, atom.Dt, atom.Dd, atom.Tr, atom.Table,
+		atom.Figure, atom.Figcaption, atom.Dl:
+		tr.flush()
+		tr.walk(n)
+		tr.flush()
+
+	default:
+		tr.walk(n)
+	}
+}
+
+// writeText appends text to the current paragraph with whitespace collapsed.
+func (tr *textRenderer) writeText(text string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		if text != "" {
+			tr.space()
+		}
+		return
+	}
+	if text[0] == ' ' || text[0] == '\n' || text[0] == '\t' {
+		tr.space()
+	}
+	tr.inline.WriteString(strings.Join(fields, " "))
+	if last := text[len(text)-1]; last == ' ' || last == '\n' || last == '\t' {
+		tr.space()
+	}
+}
+
+// space adds a single separating space unless the paragraph is empty or already ends in one.
+func (tr *textRenderer) space() {
+	s := tr.inline.String()
+	if s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+		tr.inline.WriteByte(' ')
+	}
+}
+
+// footnote records a link target and returns its [n] marker, reusing the number if
+// the same URL was already referenced.
+func (tr *textRenderer) footnote(target string) string {
+	for i, link := range tr.links {
+		if link == target {
+			return fmt.Sprintf("[%d]", i+1)
+		}
+	}
+	tr.links = append(tr.links, target)
+	return fmt.Sprintf("[%d]", len(tr.links))
+}
+
+// flush wraps the current paragraph, if any, into a finished block.
+func (tr *textRenderer) flush() {
+	text := strings.TrimSpace(tr.inline.String())
+	tr.inline.Reset()
+	if text == "" {
+		return
+	}
+
+	var lines []string
+	first := tr.prefix
+	if tr.first != "" {
+		first = tr.first
+		tr.first = ""
+	}
+	for _, hardLine := range strings.Split(text, "\n") {
+		current := first
+		empty := true
+		for _, word := range strings.Fields(hardLine) {
+			if !empty && utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) > tr.width {
+				lines = append(lines, current)
+				current, empty = tr.prefix, true
+			}
+			if !empty {
+				current += " "
+			}
+			current += word
+			empty = false
+		}
+		lines = append(lines, strings.TrimRight(current, " "))
+		first = tr.prefix
+	}
+	tr.blocks = append(tr.blocks, strings.Join(lines, "\n"))
+}
+
+// textOf concatenates all text below n without collapsing whitespace.
+func textOf(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(textOf(child))
+	}
+	return sb.String()
+}
+
+// attr returns the value of the named attribute, or "" if it's absent.
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
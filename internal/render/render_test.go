@@ -0,0 +1,104 @@
+package render
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post")
+	tests := []struct {
+		name, in, want string
+	}{
+		{"allowed tags kept", "<p>Hi <em>there</em></p>", "<p>Hi <em>there</em></p>"},
+		{"unknown tags unwrapped", "<section><p>Hi</p></section>", "<p>Hi</p>"},
+		{"scripts dropped with content", "<p>Hi</p><script>alert(1)</script>", "<p>Hi</p>"},
+		{"styles and iframes dropped", `<style>p{}</style><iframe src="x"></iframe>ok`, "ok"},
+		{"event handlers stripped", `<p onclick="x()" style="color:red">Hi</p>`, "<p>Hi</p>"},
+		{"comments dropped", "a<!-- b -->c", "ac"},
+		{"relative href resolved", `<a href="../about">About</a>`, `<a href="https://example.com/about">About</a>`},
+		{"relative src resolved", `<img src="pic.png" alt="A" width="10">`, `<img src="https://example.com/blog/pic.png" alt="A"/>`},
+		{"javascript href removed", `<a href="javascript:alert(1)">x</a>`, "<a>x</a>"},
+		{"data src removed", `<img src="data:image/png;base64,AA">`, "<img/>"},
+		{"mailto kept", `<a href="mailto:me@example.com">me</a>`, `<a href="mailto:me@example.com">me</a>`},
+		{"text escaped", "1 &lt; 2", "1 &lt; 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.in, base); got != tt.want {
+				t.Errorf("Sanitize(%q) = %q; want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeWithoutBase(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`<a href="/about">About</a>`, `<a href="/about">About</a>`},
+		{`<a href="JavaScript:x">x</a>`, "<a>x</a>"},
+	}
+	for _, tt := range tests {
+		if got := Sanitize(tt.in, nil); got != tt.want {
+			t.Errorf("Sanitize(%q, nil) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	base, _ := url.Parse("https://example.com/")
+	tests := []struct {
+		name, in string
+		width    int
+		want     string
+	}{
+		{"paragraphs", "<p>One</p><p>Two</p>", 80, "One\n\nTwo"},
+		{"whitespace collapsed", "<p>  a \n\t b  </p>", 80, "a b"},
+		{"wrapping", "<p>the quick brown fox jumps over the lazy dog</p>", 20,
+			"the quick brown fox\njumps over the lazy\ndog"},
+		{"minimum width", "<p>the quick brown fox jumps over the lazy dog</p>", 5,
+			"the quick brown fox\njumps over the lazy\ndog"},
+		{"line break", "a<br>b", 80, "a\nb"},
+		{"links as footnotes", `<p>See <a href="/a">this</a> and <a href="/a">that</a>.</p>`, 80,
+			"See this[1] and that[1].\n\n[1] https://example.com/a"},
+		{"link showing its URL", `<a href="https://example.com/a">https://example.com/a</a>`, 80,
+			"https://example.com/a"},
+		{"image", `<img src="/p.png" alt="A cat">`, 80, "[image: A cat][1]\n\n[1] https://example.com/p.png"},
+		{"unordered list", "<ul><li>one</li><li>two</li></ul>", 80, "  * one\n  * two"},
+		{"ordered list", "<ol><li>one</li><li>two</li></ol>", 80, "  1. one\n  2. two"},
+		{"quote", "<blockquote><p>said</p></blockquote>", 80, "> said"},
+		{"heading", "<h2>Title</h2><p>Body</p>", 80, "TITLE\n\nBody"},
+		{"preformatted", "<pre>a  b\n  c</pre>", 80, "    a  b\n      c"},
+		{"rule", "<p>a</p><hr><p>b</p>", 80, "a\n\n----------------------------------------\n\nb"},
+		{"script dropped", "<p>a</p><script>b</script>", 80, "a"},
+		{"empty", "", 80, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Text(tt.in, base, tt.width); got != tt.want {
+				t.Errorf("Text(%q, %d) =\n%s\nwant\n%s", tt.in, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"<p>Hello <b>world</b></p><p>again</p>", "Hello world again"},
+		{"<ul><li>one</li><li>two</li></ul>", "one two"},
+		{"a<br>b", "a b"},
+		{"<table><tr><td>x</td><td>y</td></tr></table>", "x y"},
+		{"un<em>believ</em>able", "unbelievable"},
+		{`<a href="/x">link</a> <img src="/y" alt="pic">`, "link"},
+		{"<script>x()</script>text", "text"},
+		{"plain &amp; simple", "plain & simple"},
+	}
+	for _, tt := range tests {
+		if got := PlainText(tt.in); got != tt.want {
+			t.Errorf("PlainText(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+// Package rules parses and evaluates the match expressions of user rules, which decide
+// which posts a rule's action (hide, mark read, star, tag, highlight) applies to.
+//
+// An expression is a list of terms combined with AND (also implied between adjacent
+// terms), OR, NOT (or a leading "-") and parentheses; AND binds tighter than OR. A term
+// is an optional field prefix followed by a value:
+//
+//	field:word  field:"quoted phrase"  field:/regular expression/
+//
+// Fields are feed, title, description, author and category; a term without a field
+// matches the title or the description. Words and phrases match case-insensitive
+// substrings; regular expressions use Go syntax and are case-insensitive too. Example:
+//
+//	category:jobs OR (feed:"Hacker News" title:/^(ask|show) hn/) -author:dang
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Post holds the parts of a post an expression can match on. Feed lists the names the
+// post's feed is known by (its own name, the user's title for it, its URL); the
+// description should be plain text.
+type Post struct {
+	Feed        []string
+	Title       string
+	Description string
+	Author      string
+	Categories  []string
+}
+
+// Expr is a parsed match expression.
+type Expr interface {
+	Match(post Post) bool
+}
+
+// fields maps the field names accepted before ":" to the post values they select.
+var fields = map[string]func(post Post) []string{
+	"feed":        func(post Post) []string { return post.Feed },
+	"title":       func(post Post) []string { return []string{post.Title} },
+	"description": func(post Post) []string { return []string{post.Description} },
+	"author":      func(post Post) []string { return []string{post.Author} },
+	"category":    func(post Post) []string { return post.Categories },
+}
+
+// anyText is used for terms without a field.
+func anyText(post Post) []string {
+	return []string{post.Title, post.Description}
+}
+
+// Parse parses an expression.
+func Parse(src string) (Expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("rules: empty expression")
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("rules: unexpected %q", p.tokens[p.pos].text)
+	}
+	return expr, nil
+}
+
+// tokenKind tells operators, parentheses and terms apart.
+type tokenKind int
+
+const (
+	tokenTerm tokenKind = iota
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+// token is one lexical element; terms carry their parsed matcher.
+type token struct {
+	kind tokenKind
+	text string
+	term *term
+}
+
+// tokenize splits src into tokens, parsing each term's field and value on the way.
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	rest := src
+	for {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			return tokens, nil
+		}
+
+		switch {
+		case rest[0] == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "("})
+			rest = rest[1:]
+			continue
+		case rest[0] == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")"})
+			rest = rest[1:]
+			continue
+		case rest[0] == '-' && len(rest) > 1 && !unicode.IsSpace(rune(rest[1])):
+			tokens = append(tokens, token{kind: tokenNot, text: "-"})
+			rest = rest[1:]
+			continue
+		}
+
+		// Bare AND, OR and NOT are operators; quote them to search for the words.
+		word := rest[:wordEnd(rest)]
+		switch word {
+		case "AND":
+			tokens = append(tokens, token{kind: tokenAnd, text: word})
+			rest = rest[len(word):]
+			continue
+		case "OR":
+			tokens = append(tokens, token{kind: tokenOr, text: word})
+			rest = rest[len(word):]
+			continue
+		case "NOT":
+			tokens = append(tokens, token{kind: tokenNot, text: word})
+			rest = rest[len(word):]
+			continue
+		}
+
+		t, n, err := scanTerm(rest)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token{kind: tokenTerm, text: rest[:n], term: t})
+		rest = rest[n:]
+	}
+}
+
+// wordEnd returns the length of the unquoted word at the start of s.
+func wordEnd(s string) int {
+	for i, r := range s {
+		if unicode.IsSpace(r) || r == '(' || r == ')' {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// scanTerm parses the term at the start of s and returns it with the number of bytes
+// it used.
+func scanTerm(s string) (*term, int, error) {
+	t := &term{values: anyText}
+	n := 0
+	if i := strings.IndexByte(s, ':'); i > 0 {
+		if values, ok := fields[strings.ToLower(s[:i])]; ok {
+			t.values = values
+			n = i + 1
+		}
+	}
+	value := s[n:]
+
+	switch {
+	case strings.HasPrefix(value, `"`):
+		text, used, err := scanQuoted(value, '"')
+		if err != nil {
+			return nil, 0, err
+		}
+		t.substring = strings.ToLower(text)
+		n += used
+	case strings.HasPrefix(value, "/"):
+		pattern, used, err := scanQuoted(value, '/')
+		if err != nil {
+			return nil, 0, err
+		}
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, 0, fmt.Errorf("rules: invalid regular expression /%s/: %w", pattern, err)
+		}
+		t.regexp = re
+		n += used
+	default:
+		end := wordEnd(value)
+		if end == 0 {
+			return nil, 0, fmt.Errorf("rules: missing value after %q", s[:n])
+		}
+		t.substring = strings.ToLower(value[:end])
+		n += end
+	}
+	return t, n, nil
+}
+
+// scanQuoted reads a value enclosed in delim, where a backslash escapes the delimiter,
+// and returns its content and the number of bytes used including both delimiters.
+func scanQuoted(s string, delim byte) (string, int, error) {
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == delim:
+			sb.WriteByte(delim)
+			i++
+		case s[i] == delim:
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("rules: missing closing %c in %s", delim, s)
+}
+
+// parser is a recursive-descent parser over the token list.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// peek returns the kind of the next token, or -1 at the end.
+func (p *parser) peek() tokenKind {
+	if p.pos >= len(p.tokens) {
+		return -1
+	}
+	return p.tokens[p.pos].kind
+}
+
+// or parses: and { OR and }
+func (p *parser) or() (Expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == tokenOr {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+// and parses: unary { [AND] unary }
+func (p *parser) and() (Expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case -1, tokenOr, tokenClose:
+			return left, nil
+		case tokenAnd:
+			p.pos++
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+}
+
+// unary parses: NOT unary | ( or ) | term
+func (p *parser) unary() (Expr, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, errors.New("rules: expression ends too early")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	switch tok.kind {
+	case tokenNot:
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{operand}, nil
+	case tokenOpen:
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != tokenClose {
+			return nil, errors.New("rules: missing )")
+		}
+		p.pos++
+		return inner, nil
+	case tokenTerm:
+		return tok.term, nil
+	}
+	return nil, fmt.Errorf("rules: unexpected %q", tok.text)
+}
+
+// term matches one field against a substring or a regular expression.
+type term struct {
+	values    func(post Post) []string
+	substring string // lower-cased; used when regexp is nil
+	regexp    *regexp.Regexp
+}
+
+func (t *term) Match(post Post) bool {
+	for _, value := range t.values(post) {
+		if t.regexp != nil {
+			if t.regexp.MatchString(value) {
+				return true
+			}
+		} else if strings.Contains(strings.ToLower(value), t.substring) {
+			return true
+		}
+	}
+	return false
+}
+
+type andExpr struct{ left, right Expr }
+
+func (e andExpr) Match(post Post) bool { return e.left.Match(post) && e.right.Match(post) }
+
+type orExpr struct{ left, right Expr }
+
+func (e orExpr) Match(post Post) bool { return e.left.Match(post) || e.right.Match(post) }
+
+type notExpr struct{ operand Expr }
+
+func (e notExpr) Match(post Post) bool { return !e.operand.Match(post) }
@@ -0,0 +1,90 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	post := Post{
+		Feed:        []string{"Hacker News", "HN", "https://news.ycombinator.com/rss"},
+		Title:       "Show HN: A tiny RSS reader",
+		Description: "Written in Go over a weekend.",
+		Author:      "dang",
+		Categories:  []string{"Programming", "Jobs"},
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"rss", true},
+		{"weekend", true},
+		{"python", false},
+		{"RSS READER", true},
+		{`"tiny rss"`, true},
+		{`"rss tiny"`, false},
+		{"title:weekend", false},
+		{"description:weekend", true},
+		{"Title:tiny", true},
+		{"feed:hn", true},
+		{"feed:ycombinator", true},
+		{`feed:"Hacker News"`, true},
+		{"author:dang", true},
+		{"category:jobs", true},
+		{"category:news", false},
+		{"title:/^show hn/", true},
+		{"title:/^ask hn/", false},
+		{`title:/a\/b/`, false},
+		{"rss go", true},
+		{"rss AND python", false},
+		{"rss OR python", true},
+		{"python OR perl", false},
+		{"-python", true},
+		{"NOT rss", false},
+		{"rss -author:dang", false},
+		{"python OR rss -author:dang", false},
+		{"python OR rss author:dang", true},
+		{"(python OR rss) -author:dang", false},
+		{`category:jobs OR (feed:"Hacker News" title:/^(ask|show) hn/) -author:dang`, true},
+		{`"OR"`, false},
+		{"unknown:rss", false},
+		{"x-ray", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+			if got := expr.Match(post); got != tt.want {
+				t.Errorf("%q matched %v; want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr, want string
+	}{
+		{"", "empty expression"},
+		{"   ", "empty expression"},
+		{"rss OR", "ends too early"},
+		{"NOT", "ends too early"},
+		{"(rss", "missing )"},
+		{"rss)", `unexpected ")"`},
+		{"OR rss", `unexpected "OR"`},
+		{`title:"rss`, "missing closing \""},
+		{"title:/rss", "missing closing /"},
+		{"title:/(/", "invalid regular expression"},
+		{"title:", "missing value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse(%q) = %v; want an error containing %q", tt.expr, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Package simhash computes 64-bit SimHash fingerprints of post titles. Titles that
+// describe the same story in slightly different words produce fingerprints that differ
+// in only a few bits, which is used to group the same story arriving from several feeds.
+package simhash
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// MaxDistance is the largest Hamming distance at which two fingerprints are considered
+// the same story.
+const MaxDistance = 3
+
+// minTokens is the fewest significant words a title needs before its fingerprint is
+// trusted; very short titles ("Update", "Weekly links") collide too easily.
+const minTokens = 3
+
+// stopWords are dropped before hashing so they don't dominate short titles.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "has": true, "how": true, "in": true, "is": true,
+	"it": true, "its": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "what": true, "why": true, "will": true, "with": true,
+}
+
+// Fingerprint returns the SimHash of a title built from its normalised words and word
+// pairs (shingles). ok is false if the title has too few significant words to compare.
+func Fingerprint(title string) (fingerprint uint64, ok bool) {
+	tokens := tokenize(title)
+	if len(tokens) < minTokens {
+		return 0, false
+	}
+
+	var features []string
+	features = append(features, tokens...)
+	for i := 0; i+1 < len(tokens); i++ {
+		features = append(features, tokens[i]+" "+tokens[i+1])
+	}
+
+	var weights [64]int
+	for _, feature := range features {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint, true
+}
+
+// Distance returns the number of bits in which two fingerprints differ.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// tokenize lower-cases the title, splits it on anything that isn't a letter or digit,
+// and drops stop words.
+func tokenize(title string) []string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var tokens []string
+	for _, word := range words {
+		if !stopWords[word] {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
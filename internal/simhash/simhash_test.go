@@ -0,0 +1,71 @@
+package simhash
+
+import "testing"
+
+func TestFingerprintTooShort(t *testing.T) {
+	for _, title := range []string{"", "Update", "Weekly links", "The state of the art", "!!! ???"} {
+		if fp, ok := Fingerprint(title); ok {
+			t.Errorf("Fingerprint(%q) = %x, true; want too short", title, fp)
+		}
+	}
+}
+
+func TestFingerprintIgnores(t *testing.T) {
+	tests := []struct {
+		name, a, b string
+	}{
+		{"case", "Go 1.30 Released Today", "go 1.30 released today"},
+		{"punctuation", "Go 1.30 released: today!", "Go 1.30 released today"},
+		{"stop words", "The Go 1.30 release is out", "Go 1.30 release out"},
+		{"whitespace", "  Go   1.30\treleased today ", "Go 1.30 released today"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, okA := Fingerprint(tt.a)
+			b, okB := Fingerprint(tt.b)
+			if !okA || !okB || a != b {
+				t.Errorf("Fingerprint(%q) = %x, %v and Fingerprint(%q) = %x, %v; want equal", tt.a, a, okA, tt.b, b, okB)
+			}
+		})
+	}
+}
+
+func TestFingerprintDistance(t *testing.T) {
+	tests := []struct {
+		name, a, b string
+		same       bool
+	}{
+		{"reworded", "Go 1.30 released with faster generics and a new garbage collector",
+			"Go 1.30 is released with faster generics and new garbage collector", true},
+		{"different story", "Go 1.30 released with faster generics and a new garbage collector",
+			"Rust borrow checker tips for beginners and experts", false},
+		{"shared words only", "Go 1.30 released today", "Python 3.14 released today", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := Fingerprint(tt.a)
+			b, _ := Fingerprint(tt.b)
+			if d := Distance(a, b); (d <= MaxDistance) != tt.same {
+				t.Errorf("Distance = %d for %q and %q; want same story %v", d, tt.a, tt.b, tt.same)
+			}
+		})
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0b1011, 0b1011, 0},
+		{0b1011, 0b0010, 2},
+		{0, ^uint64(0), 64},
+		{1 << 63, 1, 2},
+	}
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%b, %b) = %d; want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: feed_follows.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const deleteFeedFollow = `-- name: DeleteFeedFollow :exec
+DELETE FROM feed_follows WHERE feed_id = ? AND user_id = ?
+`
+
+type DeleteFeedFollowParams struct {
+	FeedID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowParams) error {
+	_, err := q.db.ExecContext(ctx, deleteFeedFollow, arg.FeedID, arg.UserID)
+	return err
+}
+
+const getFeedFollow = `-- name: GetFeedFollow :one
+SELECT
+    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id,
+    feed_follows.feed_id, feed_follows.folder_id, feed_follows.title, feed_follows.muted,
+    feed_follows.notify, feed_follows.priority,
+    feeds.name AS feed_name,
+    users.name AS user_name
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+INNER JOIN users ON feed_follows.user_id = users.id
+WHERE feed_follows.id = ?
+`
+
+type GetFeedFollowRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Title     sql.NullString
+	Muted     bool
+	Notify    bool
+	Priority  int64
+	FeedName  string
+	UserName  sql.NullString
+}
+
+func (q *Queries) GetFeedFollow(ctx context.Context, id uuid.UUID) (GetFeedFollowRow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollow, id)
+	var i GetFeedFollowRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.FolderID,
+		&i.Title,
+		&i.Muted,
+		&i.Notify,
+		&i.Priority,
+		&i.FeedName,
+		&i.UserName,
+	)
+	return i, err
+}
+
+const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.folder_id, feed_follows.title, feed_follows.muted, feed_follows.notify, feed_follows.priority, CAST(COALESCE(feed_follows.title, feeds.name) AS TEXT) AS feed_name,
+    users.name AS user_name, folders.name AS folder_name
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+INNER JOIN users ON feed_follows.user_id = users.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = ?
+ORDER BY folders.name NULLS FIRST, feed_follows.priority DESC, feed_name
+`
+
+type GetFeedFollowsForUserRow struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	FeedID     uuid.UUID
+	FolderID   uuid.NullUUID
+	Title      sql.NullString
+	Muted      bool
+	Notify     bool
+	Priority   int64
+	FeedName   string
+	UserName   sql.NullString
+	FolderName sql.NullString
+}
+
+// feed_name is the user's own title for the feed when they set one. Ordered by folder
+// (feeds outside any folder first), then priority (highest first) and name.
+func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedFollowsForUserRow
+	for rows.Next() {
+		var i GetFeedFollowsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.FolderID,
+			&i.Title,
+			&i.Muted,
+			&i.Notify,
+			&i.Priority,
+			&i.FeedName,
+			&i.UserName,
+			&i.FolderName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertFeedFollow = `-- name: InsertFeedFollow :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, folder_id)
+VALUES (?, ?, ?, ?, ?, ?)
+`
+
+type InsertFeedFollowParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+}
+
+// SQLite has no INSERT in WITH, so CreateFeedFollow inserts and then reads the row back
+// with the feed and user names through GetFeedFollow.
+func (q *Queries) InsertFeedFollow(ctx context.Context, arg InsertFeedFollowParams) error {
+	_, err := q.db.ExecContext(ctx, insertFeedFollow,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+		arg.FolderID,
+	)
+	return err
+}
+
+const setFeedFollowFolder = `-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder_id = ?1, updated_at = ?2
+WHERE user_id = ?3 AND feed_id = ?4
+`
+
+type SetFeedFollowFolderParams struct {
+	FolderID  uuid.NullUUID
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+}
+
+// folder_id NULL takes the feed out of its folder.
+func (q *Queries) SetFeedFollowFolder(ctx context.Context, arg SetFeedFollowFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowFolder,
+		arg.FolderID,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateFeedFollowSettings = `-- name: UpdateFeedFollowSettings :one
+UPDATE feed_follows
+SET title = CASE WHEN CAST(?1 AS BOOLEAN) THEN NULL ELSE COALESCE(?2, title) END,
+    muted = COALESCE(?3, muted),
+    notify = COALESCE(?4, notify),
+    priority = COALESCE(?5, priority),
+    updated_at = ?6
+WHERE user_id = ?7 AND feed_id = ?8
+RETURNING id, created_at, updated_at, user_id, feed_id, folder_id, title, muted, notify, priority
+`
+
+type UpdateFeedFollowSettingsParams struct {
+	ClearTitle bool
+	Title      sql.NullString
+	Muted      sql.NullBool
+	Notify     sql.NullBool
+	Priority   sql.NullInt64
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	FeedID     uuid.UUID
+}
+
+// Changes the user's settings for a followed feed. NULL arguments keep the current value;
+// clear_title removes the custom title so the feed's own name is used again.
+func (q *Queries) UpdateFeedFollowSettings(ctx context.Context, arg UpdateFeedFollowSettingsParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedFollowSettings,
+		arg.ClearTitle,
+		arg.Title,
+		arg.Muted,
+		arg.Notify,
+		arg.Priority,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+	)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.FolderID,
+		&i.Title,
+		&i.Muted,
+		&i.Notify,
+		&i.Priority,
+	)
+	return i, err
+}
@@ -0,0 +1,292 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: feeds.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createFeed = `-- name: CreateFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retain_posts, retain_seconds
+`
+
+type CreateFeedParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string
+	Url       string
+	UserID    uuid.NullUUID
+}
+
+func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, createFeed,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+		arg.Url,
+		arg.UserID,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetainPosts,
+		&i.RetainSeconds,
+	)
+	return i, err
+}
+
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retain_posts, retain_seconds FROM feeds
+WHERE id = ?
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetainPosts,
+		&i.RetainSeconds,
+	)
+	return i, err
+}
+
+const getFeedByURL = `-- name: GetFeedByURL :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retain_posts, retain_seconds FROM feeds
+WHERE url = ?
+`
+
+func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByURL, url)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetainPosts,
+		&i.RetainSeconds,
+	)
+	return i, err
+}
+
+const getFeeds = `-- name: GetFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retain_posts, retain_seconds FROM feeds
+`
+
+func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.FetchFullContent,
+			&i.RetainPosts,
+			&i.RetainSeconds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retain_posts, retain_seconds FROM feeds
+ORDER BY last_fetched_at ASC NULLS FIRST
+LIMIT 1
+`
+
+func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetainPosts,
+		&i.RetainSeconds,
+	)
+	return i, err
+}
+
+const getNextPolledFeedToFetch = `-- name: GetNextPolledFeedToFetch :one
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.fetch_full_content, feeds.retain_posts, feeds.retain_seconds FROM feeds
+LEFT JOIN websub_subscriptions ON websub_subscriptions.feed_id = feeds.id
+    AND websub_subscriptions.lease_expires_at > ?1
+WHERE websub_subscriptions.id IS NULL
+ORDER BY feeds.last_fetched_at ASC NULLS FIRST
+LIMIT 1
+`
+
+// Same as GetNextFeedToFetch, but skips feeds that currently receive WebSub pushes.
+func (q *Queries) GetNextPolledFeedToFetch(ctx context.Context, now sql.NullTime) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getNextPolledFeedToFetch, now)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetainPosts,
+		&i.RetainSeconds,
+	)
+	return i, err
+}
+
+const markFeedFetched = `-- name: MarkFeedFetched :one
+UPDATE feeds
+SET last_fetched_at = ?1,
+updated_at = ?1
+WHERE id = ?2
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retain_posts, retain_seconds
+`
+
+type MarkFeedFetchedParams struct {
+	Now sql.NullTime
+	ID  uuid.UUID
+}
+
+func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, markFeedFetched, arg.Now, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetainPosts,
+		&i.RetainSeconds,
+	)
+	return i, err
+}
+
+const setFeedFetchFullContent = `-- name: SetFeedFetchFullContent :one
+UPDATE feeds
+SET fetch_full_content = ?1,
+updated_at = ?2
+WHERE id = ?3
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retain_posts, retain_seconds
+`
+
+type SetFeedFetchFullContentParams struct {
+	FetchFullContent bool
+	UpdatedAt        time.Time
+	ID               uuid.UUID
+}
+
+func (q *Queries) SetFeedFetchFullContent(ctx context.Context, arg SetFeedFetchFullContentParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedFetchFullContent, arg.FetchFullContent, arg.UpdatedAt, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetainPosts,
+		&i.RetainSeconds,
+	)
+	return i, err
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :one
+UPDATE feeds
+SET retain_posts = ?1,
+retain_seconds = ?2,
+updated_at = ?3
+WHERE id = ?4
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retain_posts, retain_seconds
+`
+
+type SetFeedRetentionParams struct {
+	RetainPosts   sql.NullInt64
+	RetainSeconds sql.NullInt64
+	UpdatedAt     time.Time
+	ID            uuid.UUID
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedRetention,
+		arg.RetainPosts,
+		arg.RetainSeconds,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetainPosts,
+		&i.RetainSeconds,
+	)
+	return i, err
+}
@@ -0,0 +1,35 @@
+// Package sqlitedb stores gator's data in a SQLite file, for personal use without a
+// PostgreSQL server. The queries in sql/sqlite/queries are generated into this package
+// by sqlc like the PostgreSQL ones, and Store adapts them to database.Querier.
+package sqlitedb
+
+import (
+	"database/sql"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	_ "modernc.org/sqlite" // pure Go driver, registered as "sqlite"
+)
+
+// Open opens (creating it if needed) the SQLite database at path. Foreign keys are
+// enforced like in PostgreSQL, times are written in TimeFormat, and writers wait for
+// each other instead of failing with "database is locked".
+func Open(path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_time_format", "sqlite")
+	return sql.Open("sqlite", "file:"+escapePath(path)+"?"+params.Encode())
+}
+
+// escapePath escapes each segment of path for a file: URI, in which SQLite would take
+// a "?" for the start of the parameters, a "#" for a fragment and "%" for an escape.
+func escapePath(path string) string {
+	segments := strings.Split(filepath.ToSlash(path), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
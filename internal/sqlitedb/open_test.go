@@ -0,0 +1,44 @@
+package sqlitedb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenPathWithURICharacters(t *testing.T) {
+	for _, name := range []string{"gator.db", "what?.db", "no#1.db", "100%.db", "a%3Fb.db", "with space.db"} {
+		t.Run(name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "sub dir")
+			if err := os.Mkdir(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, name)
+			db, err := Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			if _, err := db.Exec("CREATE TABLE t (x INTEGER)"); err != nil {
+				t.Fatal(err)
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, entry := range entries {
+				found = found || entry.Name() == name
+			}
+			if !found {
+				t.Errorf("Open(%q) didn't create the file; the directory has %v", path, entries)
+			}
+
+			var foreignKeys int
+			if err := db.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys); err != nil || foreignKeys != 1 {
+				t.Errorf("foreign_keys pragma: got %d, %v", foreignKeys, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: post_reads.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const markAllPostsRead = `-- name: MarkAllPostsRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, ?1 FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = ?2
+AND (?3 IS NULL OR posts.feed_id = ?3)
+AND (?4 IS NULL OR COALESCE(posts.published_at, posts.created_at) < ?4)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type MarkAllPostsReadParams struct {
+	ReadAt time.Time
+	UserID uuid.UUID
+	FeedID interface{}
+	Before interface{}
+}
+
+// Marks every post in the user's followed feeds read, optionally only for one feed and/or
+// only posts published (or, lacking a date, fetched) before a cutoff.
+func (q *Queries) MarkAllPostsRead(ctx context.Context, arg MarkAllPostsReadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markAllPostsRead,
+		arg.ReadAt,
+		arg.UserID,
+		arg.FeedID,
+		arg.Before,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markPostRead = `-- name: MarkPostRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT ?1, posts.id, ?2 FROM posts
+WHERE posts.story_id = (SELECT story.story_id FROM posts AS story WHERE story.id = ?3)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type MarkPostReadParams struct {
+	UserID uuid.UUID
+	ReadAt time.Time
+	PostID uuid.UUID
+}
+
+// Marks a post read together with the other copies of its story, since browse shows a
+// story only once.
+func (q *Queries) MarkPostRead(ctx context.Context, arg MarkPostReadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markPostRead, arg.UserID, arg.ReadAt, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markPostUnread = `-- name: MarkPostUnread :execrows
+DELETE FROM post_reads
+WHERE post_reads.user_id = ?1
+AND post_reads.post_id IN (
+    SELECT posts.id FROM posts
+    WHERE posts.story_id = (SELECT story.story_id FROM posts AS story WHERE story.id = ?2)
+)
+`
+
+type MarkPostUnreadParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) MarkPostUnread(ctx context.Context, arg MarkPostUnreadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markPostUnread, arg.UserID, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const movePostReads = `-- name: MovePostReads :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT post_reads.user_id, ?1, post_reads.read_at FROM post_reads
+WHERE post_reads.post_id = ?2
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type MovePostReadsParams struct {
+	ToPostID   uuid.UUID
+	FromPostID uuid.UUID
+}
+
+// Copies read state from a post that is about to be merged into another one.
+func (q *Queries) MovePostReads(ctx context.Context, arg MovePostReadsParams) error {
+	_, err := q.db.ExecContext(ctx, movePostReads, arg.ToPostID, arg.FromPostID)
+	return err
+}
@@ -0,0 +1,145 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: post_stars.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const getStarredPostsForUser = `-- name: GetStarredPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.base_url, posts.original_url, posts.fingerprint, posts.story_id, posts.author, posts.categories, feeds.name AS feed_name, post_stars.starred_at FROM post_stars
+JOIN posts ON posts.id = post_stars.post_id
+JOIN feeds ON feeds.id = posts.feed_id
+WHERE post_stars.user_id = ?
+ORDER BY post_stars.starred_at DESC
+LIMIT ?
+`
+
+type GetStarredPostsForUserParams struct {
+	UserID uuid.UUID
+	Limit  int64
+}
+
+type GetStarredPostsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Content     sql.NullString
+	BaseUrl     sql.NullString
+	OriginalUrl sql.NullString
+	Fingerprint sql.NullInt64
+	StoryID     uuid.UUID
+	Author      sql.NullString
+	Categories  string
+	FeedName    string
+	StarredAt   time.Time
+}
+
+// Starred posts are listed whether or not the user still follows their feed.
+func (q *Queries) GetStarredPostsForUser(ctx context.Context, arg GetStarredPostsForUserParams) ([]GetStarredPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getStarredPostsForUser, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetStarredPostsForUserRow
+	for rows.Next() {
+		var i GetStarredPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.BaseUrl,
+			&i.OriginalUrl,
+			&i.Fingerprint,
+			&i.StoryID,
+			&i.Author,
+			&i.Categories,
+			&i.FeedName,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const movePostStars = `-- name: MovePostStars :exec
+INSERT INTO post_stars (user_id, post_id, starred_at)
+SELECT post_stars.user_id, ?1, post_stars.starred_at FROM post_stars
+WHERE post_stars.post_id = ?2
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type MovePostStarsParams struct {
+	ToPostID   uuid.UUID
+	FromPostID uuid.UUID
+}
+
+// Copies stars from a post that is about to be merged into another one.
+func (q *Queries) MovePostStars(ctx context.Context, arg MovePostStarsParams) error {
+	_, err := q.db.ExecContext(ctx, movePostStars, arg.ToPostID, arg.FromPostID)
+	return err
+}
+
+const starPost = `-- name: StarPost :execrows
+INSERT INTO post_stars (user_id, post_id, starred_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type StarPostParams struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	StarredAt time.Time
+}
+
+func (q *Queries) StarPost(ctx context.Context, arg StarPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, starPost, arg.UserID, arg.PostID, arg.StarredAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const unstarPost = `-- name: UnstarPost :execrows
+DELETE FROM post_stars WHERE user_id = ? AND post_id = ?
+`
+
+type UnstarPostParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) UnstarPost(ctx context.Context, arg UnstarPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unstarPost, arg.UserID, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,551 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: posts.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createPost = `-- name: CreatePost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, base_url, original_url, fingerprint, story_id, author, categories)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (feed_id, url) DO NOTHING
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, content, base_url, original_url, fingerprint, story_id, author, categories
+`
+
+type CreatePostParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	BaseUrl     sql.NullString
+	OriginalUrl sql.NullString
+	Fingerprint sql.NullInt64
+	StoryID     uuid.UUID
+	Author      sql.NullString
+	Categories  string
+}
+
+// Inserts nothing, and so returns no row, when the feed already has a post with the URL.
+func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, createPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.BaseUrl,
+		arg.OriginalUrl,
+		arg.Fingerprint,
+		arg.StoryID,
+		arg.Author,
+		arg.Categories,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.BaseUrl,
+		&i.OriginalUrl,
+		&i.Fingerprint,
+		&i.StoryID,
+		&i.Author,
+		&i.Categories,
+	)
+	return i, err
+}
+
+const deletePost = `-- name: DeletePost :exec
+DELETE FROM posts WHERE id = ?
+`
+
+func (q *Queries) DeletePost(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deletePost, id)
+	return err
+}
+
+const findUserPostsByIDRange = `-- name: FindUserPostsByIDRange :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.base_url, posts.original_url, posts.fingerprint, posts.story_id, posts.author, posts.categories, feeds.name AS feed_name FROM posts
+JOIN feeds ON feeds.id = posts.feed_id
+WHERE posts.id >= ?1 AND posts.id <= ?2
+AND (
+    EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = ?3
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id AND post_stars.user_id = ?3
+    )
+)
+ORDER BY posts.id
+LIMIT 10
+`
+
+type FindUserPostsByIDRangeParams struct {
+	LowID  uuid.UUID
+	HighID uuid.UUID
+	UserID uuid.UUID
+}
+
+type FindUserPostsByIDRangeRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Content     sql.NullString
+	BaseUrl     sql.NullString
+	OriginalUrl sql.NullString
+	Fingerprint sql.NullInt64
+	StoryID     uuid.UUID
+	Author      sql.NullString
+	Categories  string
+	FeedName    string
+}
+
+// Posts the user can see (followed feeds or starred) whose ID lies in a range.
+func (q *Queries) FindUserPostsByIDRange(ctx context.Context, arg FindUserPostsByIDRangeParams) ([]FindUserPostsByIDRangeRow, error) {
+	rows, err := q.db.QueryContext(ctx, findUserPostsByIDRange, arg.LowID, arg.HighID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindUserPostsByIDRangeRow
+	for rows.Next() {
+		var i FindUserPostsByIDRangeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.BaseUrl,
+			&i.OriginalUrl,
+			&i.Fingerprint,
+			&i.StoryID,
+			&i.Author,
+			&i.Categories,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedPostByURL = `-- name: GetFeedPostByURL :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, base_url, original_url, fingerprint, story_id, author, categories FROM posts
+WHERE feed_id = ? AND url = ?
+`
+
+type GetFeedPostByURLParams struct {
+	FeedID uuid.UUID
+	Url    string
+}
+
+func (q *Queries) GetFeedPostByURL(ctx context.Context, arg GetFeedPostByURLParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getFeedPostByURL, arg.FeedID, arg.Url)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.BaseUrl,
+		&i.OriginalUrl,
+		&i.Fingerprint,
+		&i.StoryID,
+		&i.Author,
+		&i.Categories,
+	)
+	return i, err
+}
+
+const getPostURLs = `-- name: GetPostURLs :many
+SELECT id, feed_id, url, original_url FROM posts
+ORDER BY created_at ASC
+`
+
+type GetPostURLsRow struct {
+	ID          uuid.UUID
+	FeedID      uuid.UUID
+	Url         string
+	OriginalUrl sql.NullString
+}
+
+// Oldest first, so when two posts normalise to the same URL the older one keeps it.
+func (q *Queries) GetPostURLs(ctx context.Context) ([]GetPostURLsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostURLs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostURLsRow
+	for rows.Next() {
+		var i GetPostURLsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FeedID,
+			&i.Url,
+			&i.OriginalUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsForUser = `-- name: GetPostsForUser :many
+WITH visible AS (
+    SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.base_url, posts.original_url, posts.fingerprint, posts.story_id, posts.author, posts.categories, CAST(COALESCE(feed_follows.title, feeds.name) AS TEXT) AS feed_name, feed_follows.folder_id,
+        EXISTS (
+            SELECT 1 FROM post_reads
+            JOIN posts AS read_posts ON read_posts.id = post_reads.post_id
+            WHERE post_reads.user_id = feed_follows.user_id AND read_posts.story_id = posts.story_id
+        ) AS is_read,
+        EXISTS (
+            SELECT 1 FROM post_stars
+            WHERE post_stars.user_id = feed_follows.user_id AND post_stars.post_id = posts.id
+        ) AS is_starred,
+        EXISTS (
+            SELECT 1 FROM post_highlights
+            WHERE post_highlights.user_id = feed_follows.user_id AND post_highlights.post_id = posts.id
+        ) AS is_highlighted
+    FROM posts
+    JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+    JOIN feeds ON posts.feed_id = feeds.id
+    WHERE feed_follows.user_id = ?1
+    AND (NOT feed_follows.muted OR feed_follows.feed_id = ?7)
+    AND (CAST(?8 AS BOOLEAN) OR NOT EXISTS (
+        SELECT 1 FROM post_hides
+        WHERE post_hides.user_id = feed_follows.user_id AND post_hides.post_id = posts.id
+    ))
+), filtered AS (
+    SELECT visible.id, visible.created_at, visible.updated_at, visible.title, visible.url, visible.description, visible.published_at, visible.feed_id, visible.content, visible.base_url, visible.original_url, visible.fingerprint, visible.story_id, visible.author, visible.categories, visible.feed_name, visible.folder_id, visible.is_read, visible.is_starred, visible.is_highlighted,
+        ROW_NUMBER() OVER (
+            PARTITION BY visible.story_id
+            ORDER BY visible.published_at ASC NULLS LAST, visible.created_at ASC
+        ) AS story_rank
+    FROM visible
+    WHERE (CAST(?9 AS BOOLEAN) = false OR NOT visible.is_read)
+    AND (CAST(?10 AS BOOLEAN) = false OR visible.is_starred)
+    AND (?11 IS NULL OR EXISTS (
+        SELECT 1 FROM post_tags
+        JOIN tags ON tags.id = post_tags.tag_id
+        WHERE post_tags.post_id = visible.id AND tags.user_id = ?1 AND tags.name = ?11
+    ))
+    AND (?7 IS NULL OR visible.feed_id = ?7)
+    AND (?12 IS NULL OR visible.folder_id = ?12)
+    AND (?13 IS NULL OR COALESCE(visible.published_at, visible.created_at) >= ?13)
+    AND (?14 IS NULL OR COALESCE(visible.published_at, visible.created_at) < ?14)
+), keyed AS (
+    SELECT filtered.id, filtered.created_at, filtered.updated_at, filtered.title, filtered.url,
+        filtered.description, filtered.published_at, filtered.feed_id, filtered.content,
+        filtered.base_url, filtered.original_url, filtered.fingerprint, filtered.story_id,
+        filtered.author, filtered.categories, filtered.feed_name, filtered.folder_id,
+        filtered.is_read, filtered.is_starred, filtered.is_highlighted,
+        CAST(CASE WHEN ?15 = 'feed' THEN filtered.feed_name ELSE '' END AS TEXT) AS sort_group,
+        CAST(COALESCE(
+            CASE WHEN ?15 = 'fetched' THEN filtered.created_at ELSE filtered.published_at END,
+            '0001-01-01 00:00:00+00:00'
+        ) AS TEXT) AS sort_time
+    FROM filtered
+    WHERE filtered.story_rank = 1
+)
+SELECT keyed.id, keyed.created_at, keyed.updated_at, keyed.title, keyed.url, keyed.description, keyed.published_at, keyed.feed_id, keyed.content, keyed.base_url, keyed.original_url, keyed.fingerprint, keyed.story_id, keyed.author, keyed.categories, keyed.feed_name, keyed.folder_id, keyed.is_read, keyed.is_starred, keyed.is_highlighted, keyed.sort_group, keyed.sort_time,
+    CAST(json_group_array(DISTINCT other.feed_name) AS TEXT) AS also_in,
+    CAST(json_group_array(DISTINCT tags.name) AS TEXT) AS tags
+FROM keyed
+LEFT JOIN visible AS other ON other.story_id = keyed.story_id AND other.feed_id <> keyed.feed_id
+LEFT JOIN post_tags ON post_tags.post_id = keyed.id
+LEFT JOIN tags ON tags.id = post_tags.tag_id AND tags.user_id = ?1
+WHERE ?2 IS NULL
+OR keyed.sort_group > CAST(?3 AS TEXT)
+OR (keyed.sort_group = CAST(?3 AS TEXT) AND keyed.sort_time < CAST(?4 AS TEXT))
+OR (keyed.sort_group = CAST(?3 AS TEXT) AND keyed.sort_time = CAST(?4 AS TEXT) AND keyed.id < CAST(?2 AS TEXT))
+GROUP BY keyed.id
+ORDER BY keyed.sort_group ASC, keyed.sort_time DESC, keyed.id DESC
+LIMIT ?6
+OFFSET ?5
+`
+
+type GetPostsForUserParams struct {
+	UserID      uuid.UUID
+	CursorID    interface{}
+	CursorGroup sql.NullString
+	CursorTime  sql.NullString
+	Offset      int64
+	Limit       int64
+	FeedID      uuid.NullUUID
+	ShowHidden  bool
+	UnreadOnly  bool
+	StarredOnly bool
+	Tag         interface{}
+	FolderID    interface{}
+	Since       interface{}
+	Until       interface{}
+	Sort        interface{}
+}
+
+type GetPostsForUserRow struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Title         string
+	Url           string
+	Description   sql.NullString
+	PublishedAt   sql.NullTime
+	FeedID        uuid.UUID
+	Content       sql.NullString
+	BaseUrl       sql.NullString
+	OriginalUrl   sql.NullString
+	Fingerprint   sql.NullInt64
+	StoryID       uuid.UUID
+	Author        sql.NullString
+	Categories    string
+	FeedName      string
+	FolderID      uuid.NullUUID
+	IsRead        int64
+	IsStarred     int64
+	IsHighlighted int64
+	SortGroup     string
+	SortTime      string
+	AlsoIn        string
+	Tags          string
+}
+
+// Returns one row per story, like the PostgreSQL query of the same name; see there for
+// the filters and the cursor. The earliest published copy of a story is picked with
+// ROW_NUMBER instead of DISTINCT ON; also_in and tags are unordered JSON arrays that may
+// contain null (the Store sorts them and drops the nulls), and sort_time is returned as
+// text since SQLite doesn't type expressions.
+func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.CursorID,
+		arg.CursorGroup,
+		arg.CursorTime,
+		arg.Offset,
+		arg.Limit,
+		arg.FeedID,
+		arg.ShowHidden,
+		arg.UnreadOnly,
+		arg.StarredOnly,
+		arg.Tag,
+		arg.FolderID,
+		arg.Since,
+		arg.Until,
+		arg.Sort,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsForUserRow
+	for rows.Next() {
+		var i GetPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.BaseUrl,
+			&i.OriginalUrl,
+			&i.Fingerprint,
+			&i.StoryID,
+			&i.Author,
+			&i.Categories,
+			&i.FeedName,
+			&i.FolderID,
+			&i.IsRead,
+			&i.IsStarred,
+			&i.IsHighlighted,
+			&i.SortGroup,
+			&i.SortTime,
+			&i.AlsoIn,
+			&i.Tags,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentPostFingerprints = `-- name: GetRecentPostFingerprints :many
+SELECT id, story_id, fingerprint FROM posts
+WHERE fingerprint IS NOT NULL AND feed_id <> ? AND created_at > ?
+`
+
+type GetRecentPostFingerprintsParams struct {
+	FeedID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type GetRecentPostFingerprintsRow struct {
+	ID          uuid.UUID
+	StoryID     uuid.UUID
+	Fingerprint sql.NullInt64
+}
+
+// Fingerprinted posts from other feeds since a cutoff, to match near-duplicate titles against.
+func (q *Queries) GetRecentPostFingerprints(ctx context.Context, arg GetRecentPostFingerprintsParams) ([]GetRecentPostFingerprintsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentPostFingerprints, arg.FeedID, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRecentPostFingerprintsRow
+	for rows.Next() {
+		var i GetRecentPostFingerprintsRow
+		if err := rows.Scan(&i.ID, &i.StoryID, &i.Fingerprint); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStoryIDByURL = `-- name: GetStoryIDByURL :one
+SELECT story_id FROM posts
+WHERE url = ? AND feed_id <> ?
+ORDER BY created_at ASC
+LIMIT 1
+`
+
+type GetStoryIDByURLParams struct {
+	Url    string
+	FeedID uuid.UUID
+}
+
+// Story of a post from another feed linking to the same (normalised) URL, if any.
+func (q *Queries) GetStoryIDByURL(ctx context.Context, arg GetStoryIDByURLParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getStoryIDByURL, arg.Url, arg.FeedID)
+	var story_id uuid.UUID
+	err := row.Scan(&story_id)
+	return story_id, err
+}
+
+const setPostStory = `-- name: SetPostStory :exec
+UPDATE posts
+SET story_id = ?
+WHERE id = ?
+`
+
+type SetPostStoryParams struct {
+	StoryID uuid.UUID
+	ID      uuid.UUID
+}
+
+func (q *Queries) SetPostStory(ctx context.Context, arg SetPostStoryParams) error {
+	_, err := q.db.ExecContext(ctx, setPostStory, arg.StoryID, arg.ID)
+	return err
+}
+
+const updatePostContent = `-- name: UpdatePostContent :exec
+UPDATE posts
+SET content = ?,
+updated_at = ?
+WHERE id = ?
+`
+
+type UpdatePostContentParams struct {
+	Content   sql.NullString
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) UpdatePostContent(ctx context.Context, arg UpdatePostContentParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostContent, arg.Content, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const updatePostURL = `-- name: UpdatePostURL :exec
+UPDATE posts
+SET url = ?,
+original_url = ?,
+updated_at = ?
+WHERE id = ?
+`
+
+type UpdatePostURLParams struct {
+	Url         string
+	OriginalUrl sql.NullString
+	UpdatedAt   time.Time
+	ID          uuid.UUID
+}
+
+func (q *Queries) UpdatePostURL(ctx context.Context, arg UpdatePostURLParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostURL,
+		arg.Url,
+		arg.OriginalUrl,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
@@ -0,0 +1,161 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: pruned_posts.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createPrunedPost = `-- name: CreatePrunedPost :exec
+INSERT INTO pruned_posts (feed_id, url, pruned_at)
+VALUES (?, ?, ?)
+ON CONFLICT (feed_id, url) DO NOTHING
+`
+
+type CreatePrunedPostParams struct {
+	FeedID   uuid.UUID
+	Url      string
+	PrunedAt time.Time
+}
+
+func (q *Queries) CreatePrunedPost(ctx context.Context, arg CreatePrunedPostParams) error {
+	_, err := q.db.ExecContext(ctx, createPrunedPost, arg.FeedID, arg.Url, arg.PrunedAt)
+	return err
+}
+
+const deletePrunedPost = `-- name: DeletePrunedPost :exec
+DELETE FROM pruned_posts WHERE feed_id = ? AND url = ?
+`
+
+type DeletePrunedPostParams struct {
+	FeedID uuid.UUID
+	Url    string
+}
+
+func (q *Queries) DeletePrunedPost(ctx context.Context, arg DeletePrunedPostParams) error {
+	_, err := q.db.ExecContext(ctx, deletePrunedPost, arg.FeedID, arg.Url)
+	return err
+}
+
+const getFeedPrunedURLs = `-- name: GetFeedPrunedURLs :many
+SELECT url FROM pruned_posts WHERE feed_id = ? ORDER BY url
+`
+
+func (q *Queries) GetFeedPrunedURLs(ctx context.Context, feedID uuid.UUID) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedPrunedURLs, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		items = append(items, url)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPrunablePosts = `-- name: GetPrunablePosts :many
+WITH ranked AS (
+    SELECT posts.id, posts.feed_id, feeds.name AS feed_name, posts.title, posts.url, posts.created_at,
+        ROW_NUMBER() OVER (PARTITION BY posts.feed_id ORDER BY posts.created_at DESC, posts.id DESC) AS position,
+        COALESCE(feeds.retain_posts, CAST(?2 AS INTEGER)) AS keep_posts,
+        COALESCE(feeds.retain_seconds, CAST(?3 AS INTEGER)) AS keep_seconds
+    FROM posts
+    JOIN feeds ON feeds.id = posts.feed_id
+    WHERE ?4 IS NULL OR posts.feed_id = ?4
+)
+SELECT ranked.id, ranked.feed_id, ranked.feed_name, ranked.title, ranked.url, ranked.created_at
+FROM ranked
+WHERE ((ranked.keep_posts > 0 AND ranked.position > ranked.keep_posts)
+    OR (ranked.keep_seconds > 0 AND unixepoch(ranked.created_at) < unixepoch(?1) - ranked.keep_seconds))
+    AND NOT EXISTS (SELECT 1 FROM post_stars WHERE post_stars.post_id = ranked.id)
+    AND NOT EXISTS (SELECT 1 FROM post_tags WHERE post_tags.post_id = ranked.id)
+ORDER BY ranked.feed_name, ranked.feed_id, ranked.created_at DESC, ranked.id DESC
+`
+
+type GetPrunablePostsParams struct {
+	Now            interface{}
+	DefaultPosts   int64
+	DefaultSeconds int64
+	FeedID         interface{}
+}
+
+type GetPrunablePostsRow struct {
+	ID        uuid.UUID
+	FeedID    uuid.UUID
+	FeedName  string
+	Title     string
+	Url       string
+	CreatedAt time.Time
+}
+
+// Posts beyond their feed's retention, like the PostgreSQL query of the same name.
+// Times are compared as Unix seconds, since the age limit varies by feed.
+func (q *Queries) GetPrunablePosts(ctx context.Context, arg GetPrunablePostsParams) ([]GetPrunablePostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPrunablePosts,
+		arg.Now,
+		arg.DefaultPosts,
+		arg.DefaultSeconds,
+		arg.FeedID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPrunablePostsRow
+	for rows.Next() {
+		var i GetPrunablePostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FeedID,
+			&i.FeedName,
+			&i.Title,
+			&i.Url,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const isPostPruned = `-- name: IsPostPruned :one
+SELECT EXISTS (
+    SELECT 1 FROM pruned_posts WHERE feed_id = ? AND url = ?
+)
+`
+
+type IsPostPrunedParams struct {
+	FeedID uuid.UUID
+	Url    string
+}
+
+func (q *Queries) IsPostPruned(ctx context.Context, arg IsPostPrunedParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, isPostPruned, arg.FeedID, arg.Url)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
@@ -0,0 +1,283 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: rules.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createRule = `-- name: CreateRule :one
+INSERT INTO rules (id, created_at, user_id, expression, action, tag)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING id, created_at, user_id, expression, "action", tag
+`
+
+type CreateRuleParams struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Expression string
+	Action     string
+	Tag        sql.NullString
+}
+
+func (q *Queries) CreateRule(ctx context.Context, arg CreateRuleParams) (Rule, error) {
+	row := q.db.QueryRowContext(ctx, createRule,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UserID,
+		arg.Expression,
+		arg.Action,
+		arg.Tag,
+	)
+	var i Rule
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.Expression,
+		&i.Action,
+		&i.Tag,
+	)
+	return i, err
+}
+
+const deleteRule = `-- name: DeleteRule :execrows
+DELETE FROM rules
+WHERE id = ? AND user_id = ?
+`
+
+type DeleteRuleParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteRule(ctx context.Context, arg DeleteRuleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteRule, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getRuleCandidatePosts = `-- name: GetRuleCandidatePosts :many
+SELECT posts.id, posts.title, posts.description, posts.author, posts.categories,
+    feeds.name AS feed_name, feeds.url AS feed_url, feed_follows.title AS feed_title
+FROM posts
+JOIN feeds ON feeds.id = posts.feed_id
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = ?
+ORDER BY posts.created_at
+`
+
+type GetRuleCandidatePostsRow struct {
+	ID          uuid.UUID
+	Title       string
+	Description sql.NullString
+	Author      sql.NullString
+	Categories  string
+	FeedName    string
+	FeedUrl     string
+	FeedTitle   sql.NullString
+}
+
+// Everything a rule can match on, for each post in the feeds the user follows.
+func (q *Queries) GetRuleCandidatePosts(ctx context.Context, userID uuid.UUID) ([]GetRuleCandidatePostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRuleCandidatePosts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRuleCandidatePostsRow
+	for rows.Next() {
+		var i GetRuleCandidatePostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Description,
+			&i.Author,
+			&i.Categories,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.FeedTitle,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRulesForFeed = `-- name: GetRulesForFeed :many
+SELECT rules.id, rules.created_at, rules.user_id, rules.expression, rules."action", rules.tag, feed_follows.title AS feed_title FROM rules
+JOIN feed_follows ON feed_follows.user_id = rules.user_id
+WHERE feed_follows.feed_id = ?
+ORDER BY rules.user_id, rules.created_at
+`
+
+type GetRulesForFeedRow struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Expression string
+	Action     string
+	Tag        sql.NullString
+	FeedTitle  sql.NullString
+}
+
+// The rules of every user following the feed, with that user's own title for it.
+func (q *Queries) GetRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]GetRulesForFeedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRulesForFeed, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRulesForFeedRow
+	for rows.Next() {
+		var i GetRulesForFeedRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.Expression,
+			&i.Action,
+			&i.Tag,
+			&i.FeedTitle,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRulesForUser = `-- name: GetRulesForUser :many
+SELECT id, created_at, user_id, expression, "action", tag FROM rules
+WHERE user_id = ?
+ORDER BY created_at
+`
+
+func (q *Queries) GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]Rule, error) {
+	rows, err := q.db.QueryContext(ctx, getRulesForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Rule
+	for rows.Next() {
+		var i Rule
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.Expression,
+			&i.Action,
+			&i.Tag,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const hidePost = `-- name: HidePost :execrows
+INSERT INTO post_hides (user_id, post_id, hidden_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type HidePostParams struct {
+	UserID   uuid.UUID
+	PostID   uuid.UUID
+	HiddenAt time.Time
+}
+
+func (q *Queries) HidePost(ctx context.Context, arg HidePostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, hidePost, arg.UserID, arg.PostID, arg.HiddenAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const highlightPost = `-- name: HighlightPost :execrows
+INSERT INTO post_highlights (user_id, post_id, highlighted_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type HighlightPostParams struct {
+	UserID        uuid.UUID
+	PostID        uuid.UUID
+	HighlightedAt time.Time
+}
+
+func (q *Queries) HighlightPost(ctx context.Context, arg HighlightPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, highlightPost, arg.UserID, arg.PostID, arg.HighlightedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const movePostHides = `-- name: MovePostHides :exec
+INSERT INTO post_hides (user_id, post_id, hidden_at)
+SELECT post_hides.user_id, ?1, post_hides.hidden_at FROM post_hides
+WHERE post_hides.post_id = ?2
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type MovePostHidesParams struct {
+	ToPostID   uuid.UUID
+	FromPostID uuid.UUID
+}
+
+// Copies hides from a post that is about to be merged into another one.
+func (q *Queries) MovePostHides(ctx context.Context, arg MovePostHidesParams) error {
+	_, err := q.db.ExecContext(ctx, movePostHides, arg.ToPostID, arg.FromPostID)
+	return err
+}
+
+const movePostHighlights = `-- name: MovePostHighlights :exec
+INSERT INTO post_highlights (user_id, post_id, highlighted_at)
+SELECT post_highlights.user_id, ?1, post_highlights.highlighted_at FROM post_highlights
+WHERE post_highlights.post_id = ?2
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type MovePostHighlightsParams struct {
+	ToPostID   uuid.UUID
+	FromPostID uuid.UUID
+}
+
+// Copies highlights from a post that is about to be merged into another one.
+func (q *Queries) MovePostHighlights(ctx context.Context, arg MovePostHighlightsParams) error {
+	_, err := q.db.ExecContext(ctx, movePostHighlights, arg.ToPostID, arg.FromPostID)
+	return err
+}
@@ -0,0 +1,149 @@
+package sqlitedb
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+)
+
+// searchPosts is SearchPosts for SQLite. sqlc can't check queries on FTS5 tables, so it
+// is written out here. bm25 is negated so that, as with ts_rank_cd, a higher rank is a
+// better match; title matches weigh most. snippet marks matches with control characters
+// so they survive stripping the markup from the article text.
+const searchPosts = `
+SELECT posts.id, posts.title, posts.url, posts.published_at, feeds.name AS feed_name,
+    posts.description, posts.content, posts.base_url,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        JOIN posts AS read_posts ON read_posts.id = post_reads.post_id
+        WHERE post_reads.user_id = ?1 AND read_posts.story_id = posts.story_id
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.user_id = ?1 AND post_stars.post_id = posts.id
+    ) AS is_starred,
+    -bm25(posts_fts, 10.0, 2.0, 1.0) AS rank,
+    snippet(posts_fts, -1, char(2), char(3), '…', 20) AS snippet
+FROM posts_fts
+JOIN posts ON posts.rowid = posts_fts.rowid
+JOIN feeds ON feeds.id = posts.feed_id
+WHERE posts_fts MATCH ?2
+AND (?3 OR EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = ?1
+))
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST
+LIMIT ?4
+`
+
+// markupPattern matches HTML tags, and a tag cut off at either end of a snippet.
+var markupPattern = regexp.MustCompile(`<[^<>]*>|^[^<>]*>|<[^<>]*$`)
+
+func (s *Store) SearchPosts(ctx context.Context, arg database.SearchPostsParams) ([]database.SearchPostsRow, error) {
+	match := ftsQuery(arg.Query)
+	if match == "" {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, searchPosts, arg.UserID, match, arg.AllFeeds, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.SearchPostsRow
+	for rows.Next() {
+		var i database.SearchPostsRow
+		var rank float64
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.PublishedAt,
+			&i.FeedName,
+			&i.Description,
+			&i.Content,
+			&i.BaseUrl,
+			&i.IsRead,
+			&i.IsStarred,
+			&rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		i.Rank = float32(rank)
+		i.Snippet = markupPattern.ReplaceAllString(i.Snippet, " ")
+		i.Snippet = strings.NewReplacer("\x02", "<b>", "\x03", "</b>").Replace(i.Snippet)
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ftsQuery turns a query in web search syntax ("quoted phrases", OR, -excluded words),
+// as PostgreSQL's websearch_to_tsquery reads it, into an FTS5 query. Every word and
+// phrase is quoted so FTS5 syntax in it is taken literally. Returns "" if nothing is
+// left to search for: FTS5 can't match on excluded words alone.
+func ftsQuery(query string) string {
+	var included, excluded []string
+	or := false
+	for len(query) > 0 {
+		query = strings.TrimLeft(query, " \t\n")
+		if query == "" {
+			break
+		}
+		exclude := false
+		if query[0] == '-' {
+			exclude = true
+			query = query[1:]
+		}
+
+		var term string
+		if strings.HasPrefix(query, `"`) {
+			end := strings.Index(query[1:], `"`)
+			if end < 0 {
+				term, query = query[1:], ""
+			} else {
+				term, query = query[1:end+1], query[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(query, " \t\n")
+			if end < 0 {
+				end = len(query)
+			}
+			term, query = query[:end], query[end:]
+			if !exclude && strings.EqualFold(term, "or") {
+				or = len(included) > 0
+				continue
+			}
+		}
+		if strings.TrimSpace(term) == "" {
+			continue
+		}
+
+		quoted := `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+		switch {
+		case exclude:
+			excluded = append(excluded, quoted)
+		case or:
+			included[len(included)-1] += " OR " + quoted
+			or = false
+		default:
+			included = append(included, quoted)
+		}
+	}
+	if len(included) == 0 {
+		return ""
+	}
+
+	match := "(" + strings.Join(included, ") AND (") + ")"
+	for _, term := range excluded {
+		match += " NOT " + term
+	}
+	return match
+}
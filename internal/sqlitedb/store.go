@@ -0,0 +1,798 @@
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// TimeFormat is how times are stored: the driver's "sqlite" write format, which
+// Open selects. Every time is converted to UTC before it is written, so the text
+// sorts chronologically and the queries can compare times as strings.
+const TimeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// Store implements database.Querier on top of the queries generated for SQLite, so
+// the rest of gator can use either database. It converts between the two packages'
+// types (IDs and times are the same, but SQLite has no arrays or booleans and
+// returns computed columns as text) and implements the few queries that can't be
+// written the same way, such as full-text search.
+type Store struct {
+	db DBTX
+	q  *Queries
+}
+
+var _ database.Querier = (*Store)(nil)
+
+// NewStore returns a Store running its queries on db, a connection opened with Open
+// or a transaction on one.
+func NewStore(db DBTX) *Store {
+	return &Store{db: db, q: New(db)}
+}
+
+// WithTx returns a Store running its queries in tx.
+func (s *Store) WithTx(tx *sql.Tx) *Store {
+	return &Store{db: tx, q: s.q.WithTx(tx)}
+}
+
+// utc returns t in UTC; see TimeFormat.
+func utc(t time.Time) time.Time {
+	return t.UTC()
+}
+
+// nullUTC returns t in UTC if it is set; see TimeFormat.
+func nullUTC(t sql.NullTime) sql.NullTime {
+	if t.Valid {
+		t.Time = t.Time.UTC()
+	}
+	return t
+}
+
+// nullTimeArg turns an optional time into a query argument: the time in UTC, or NULL.
+func nullTimeArg(t sql.NullTime) interface{} {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time.UTC()
+}
+
+// encodeStrings stores a string list as a JSON array.
+func encodeStrings(values []string) string {
+	if len(values) == 0 {
+		return "[]"
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return "[]"
+	}
+	return string(encoded)
+}
+
+// decodeStrings reads a JSON array of strings, dropping nulls, as returned for
+// categories and by json_group_array. The result is never nil, like a PostgreSQL array.
+func decodeStrings(encoded string) []string {
+	var values []*string
+	_ = json.Unmarshal([]byte(encoded), &values)
+	decoded := []string{}
+	for _, value := range values {
+		if value != nil {
+			decoded = append(decoded, *value)
+		}
+	}
+	return decoded
+}
+
+// sortedStrings is decodeStrings for unordered aggregates, sorted the way
+// PostgreSQL's array_agg(... ORDER BY ...) returns them.
+func sortedStrings(encoded string) []string {
+	values := decodeStrings(encoded)
+	sort.Strings(values)
+	return values
+}
+
+// parseTime reads a time returned as text, such as GetPostsForUser's sort_time.
+func parseTime(value string) time.Time {
+	t, err := time.Parse(TimeFormat, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func convertUser(u User) database.User {
+	return database.User{ID: u.ID, CreatedAt: u.CreatedAt, UpdatedAt: u.UpdatedAt, Name: u.Name}
+}
+
+func convertFeed(f Feed) database.Feed {
+	return database.Feed{
+		ID:               f.ID,
+		CreatedAt:        f.CreatedAt,
+		UpdatedAt:        f.UpdatedAt,
+		Name:             f.Name,
+		Url:              f.Url,
+		UserID:           f.UserID,
+		LastFetchedAt:    f.LastFetchedAt,
+		FetchFullContent: f.FetchFullContent,
+		RetainPosts:      sql.NullInt32{Int32: int32(f.RetainPosts.Int64), Valid: f.RetainPosts.Valid},
+		RetainSeconds:    f.RetainSeconds,
+	}
+}
+
+func convertFeeds(feeds []Feed) []database.Feed {
+	converted := make([]database.Feed, len(feeds))
+	for i, f := range feeds {
+		converted[i] = convertFeed(f)
+	}
+	return converted
+}
+
+func convertFeedFollow(f FeedFollow) database.FeedFollow {
+	return database.FeedFollow{
+		ID:        f.ID,
+		CreatedAt: f.CreatedAt,
+		UpdatedAt: f.UpdatedAt,
+		UserID:    f.UserID,
+		FeedID:    f.FeedID,
+		FolderID:  f.FolderID,
+		Title:     f.Title,
+		Muted:     f.Muted,
+		Notify:    f.Notify,
+		Priority:  int32(f.Priority),
+	}
+}
+
+func convertFolder(f Folder) database.Folder {
+	return database.Folder{ID: f.ID, CreatedAt: f.CreatedAt, UpdatedAt: f.UpdatedAt, UserID: f.UserID, Name: f.Name}
+}
+
+func convertPost(p Post) database.Post {
+	return database.Post{
+		ID:          p.ID,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+		Title:       p.Title,
+		Url:         p.Url,
+		Description: p.Description,
+		PublishedAt: p.PublishedAt,
+		FeedID:      p.FeedID,
+		Content:     p.Content,
+		BaseUrl:     p.BaseUrl,
+		OriginalUrl: p.OriginalUrl,
+		Fingerprint: p.Fingerprint,
+		StoryID:     p.StoryID,
+		Author:      p.Author,
+		Categories:  decodeStrings(p.Categories),
+	}
+}
+
+func convertRule(r Rule) database.Rule {
+	return database.Rule{ID: r.ID, CreatedAt: r.CreatedAt, UserID: r.UserID, Expression: r.Expression, Action: r.Action, Tag: r.Tag}
+}
+
+func convertRules(rules []Rule) []database.Rule {
+	converted := make([]database.Rule, len(rules))
+	for i, r := range rules {
+		converted[i] = convertRule(r)
+	}
+	return converted
+}
+
+func convertWebSubSubscription(s WebsubSubscription) database.WebsubSubscription {
+	return database.WebsubSubscription{
+		ID:             s.ID,
+		CreatedAt:      s.CreatedAt,
+		UpdatedAt:      s.UpdatedAt,
+		FeedID:         s.FeedID,
+		HubUrl:         s.HubUrl,
+		TopicUrl:       s.TopicUrl,
+		Secret:         s.Secret,
+		RequestedAt:    s.RequestedAt,
+		LeaseExpiresAt: s.LeaseExpiresAt,
+	}
+}
+
+// Users
+
+func (s *Store) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	u, err := s.q.CreateUser(ctx, CreateUserParams{ID: arg.ID, CreatedAt: utc(arg.CreatedAt), UpdatedAt: utc(arg.UpdatedAt), Name: arg.Name})
+	return convertUser(u), err
+}
+
+func (s *Store) DeleteUsers(ctx context.Context) error {
+	return s.q.DeleteUsers(ctx)
+}
+
+func (s *Store) GetUser(ctx context.Context, name sql.NullString) (database.User, error) {
+	u, err := s.q.GetUser(ctx, name)
+	return convertUser(u), err
+}
+
+func (s *Store) GetUserByUUID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	u, err := s.q.GetUserByUUID(ctx, id)
+	return convertUser(u), err
+}
+
+func (s *Store) GetUsers(ctx context.Context) ([]sql.NullString, error) {
+	return s.q.GetUsers(ctx)
+}
+
+// Feeds
+
+func (s *Store) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	f, err := s.q.CreateFeed(ctx, CreateFeedParams{
+		ID:        arg.ID,
+		CreatedAt: utc(arg.CreatedAt),
+		UpdatedAt: utc(arg.UpdatedAt),
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UserID:    arg.UserID,
+	})
+	return convertFeed(f), err
+}
+
+func (s *Store) GetFeedByID(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	f, err := s.q.GetFeedByID(ctx, id)
+	return convertFeed(f), err
+}
+
+func (s *Store) GetFeedByURL(ctx context.Context, url string) (database.Feed, error) {
+	f, err := s.q.GetFeedByURL(ctx, url)
+	return convertFeed(f), err
+}
+
+func (s *Store) GetFeeds(ctx context.Context) ([]database.Feed, error) {
+	feeds, err := s.q.GetFeeds(ctx)
+	return convertFeeds(feeds), err
+}
+
+func (s *Store) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	f, err := s.q.GetNextFeedToFetch(ctx)
+	return convertFeed(f), err
+}
+
+func (s *Store) GetNextPolledFeedToFetch(ctx context.Context, now time.Time) (database.Feed, error) {
+	f, err := s.q.GetNextPolledFeedToFetch(ctx, sql.NullTime{Time: utc(now), Valid: true})
+	return convertFeed(f), err
+}
+
+func (s *Store) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) (database.Feed, error) {
+	f, err := s.q.MarkFeedFetched(ctx, MarkFeedFetchedParams{Now: sql.NullTime{Time: utc(arg.Now), Valid: true}, ID: arg.ID})
+	return convertFeed(f), err
+}
+
+func (s *Store) SetFeedFetchFullContent(ctx context.Context, arg database.SetFeedFetchFullContentParams) (database.Feed, error) {
+	f, err := s.q.SetFeedFetchFullContent(ctx, SetFeedFetchFullContentParams{
+		FetchFullContent: arg.FetchFullContent,
+		UpdatedAt:        utc(arg.UpdatedAt),
+		ID:               arg.ID,
+	})
+	return convertFeed(f), err
+}
+
+func (s *Store) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) (database.Feed, error) {
+	f, err := s.q.SetFeedRetention(ctx, SetFeedRetentionParams{
+		RetainPosts:   sql.NullInt64{Int64: int64(arg.RetainPosts.Int32), Valid: arg.RetainPosts.Valid},
+		RetainSeconds: arg.RetainSeconds,
+		UpdatedAt:     utc(arg.UpdatedAt),
+		ID:            arg.ID,
+	})
+	return convertFeed(f), err
+}
+
+// Feed follows
+
+func (s *Store) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	err := s.q.InsertFeedFollow(ctx, InsertFeedFollowParams{
+		ID:        arg.ID,
+		CreatedAt: utc(arg.CreatedAt),
+		UpdatedAt: utc(arg.UpdatedAt),
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+		FolderID:  arg.FolderID,
+	})
+	if err != nil {
+		return database.CreateFeedFollowRow{}, err
+	}
+	f, err := s.q.GetFeedFollow(ctx, arg.ID)
+	return database.CreateFeedFollowRow{
+		ID:        f.ID,
+		CreatedAt: f.CreatedAt,
+		UpdatedAt: f.UpdatedAt,
+		UserID:    f.UserID,
+		FeedID:    f.FeedID,
+		FolderID:  f.FolderID,
+		Title:     f.Title,
+		Muted:     f.Muted,
+		Notify:    f.Notify,
+		Priority:  int32(f.Priority),
+		FeedName:  f.FeedName,
+		UserName:  f.UserName,
+	}, err
+}
+
+func (s *Store) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	return s.q.DeleteFeedFollow(ctx, DeleteFeedFollowParams{FeedID: arg.FeedID, UserID: arg.UserID})
+}
+
+func (s *Store) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	rows, err := s.q.GetFeedFollowsForUser(ctx, userID)
+	converted := make([]database.GetFeedFollowsForUserRow, len(rows))
+	for i, f := range rows {
+		converted[i] = database.GetFeedFollowsForUserRow{
+			ID:         f.ID,
+			CreatedAt:  f.CreatedAt,
+			UpdatedAt:  f.UpdatedAt,
+			UserID:     f.UserID,
+			FeedID:     f.FeedID,
+			FolderID:   f.FolderID,
+			Title:      f.Title,
+			Muted:      f.Muted,
+			Notify:     f.Notify,
+			Priority:   int32(f.Priority),
+			FeedName:   f.FeedName,
+			UserName:   f.UserName,
+			FolderName: f.FolderName,
+		}
+	}
+	return converted, err
+}
+
+func (s *Store) SetFeedFollowFolder(ctx context.Context, arg database.SetFeedFollowFolderParams) (int64, error) {
+	return s.q.SetFeedFollowFolder(ctx, SetFeedFollowFolderParams{
+		FolderID:  arg.FolderID,
+		UpdatedAt: utc(arg.UpdatedAt),
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+	})
+}
+
+func (s *Store) UpdateFeedFollowSettings(ctx context.Context, arg database.UpdateFeedFollowSettingsParams) (database.FeedFollow, error) {
+	f, err := s.q.UpdateFeedFollowSettings(ctx, UpdateFeedFollowSettingsParams{
+		ClearTitle: arg.ClearTitle,
+		Title:      arg.Title,
+		Muted:      arg.Muted,
+		Notify:     arg.Notify,
+		Priority:   sql.NullInt64{Int64: int64(arg.Priority.Int32), Valid: arg.Priority.Valid},
+		UpdatedAt:  utc(arg.UpdatedAt),
+		UserID:     arg.UserID,
+		FeedID:     arg.FeedID,
+	})
+	return convertFeedFollow(f), err
+}
+
+// Folders
+
+func (s *Store) CreateFolder(ctx context.Context, arg database.CreateFolderParams) (database.Folder, error) {
+	f, err := s.q.CreateFolder(ctx, CreateFolderParams{
+		ID:        arg.ID,
+		CreatedAt: utc(arg.CreatedAt),
+		UpdatedAt: utc(arg.UpdatedAt),
+		UserID:    arg.UserID,
+		Name:      arg.Name,
+	})
+	return convertFolder(f), err
+}
+
+func (s *Store) DeleteFolder(ctx context.Context, arg database.DeleteFolderParams) (int64, error) {
+	return s.q.DeleteFolder(ctx, DeleteFolderParams{UserID: arg.UserID, Name: arg.Name})
+}
+
+func (s *Store) GetFolderByName(ctx context.Context, arg database.GetFolderByNameParams) (database.Folder, error) {
+	f, err := s.q.GetFolderByName(ctx, GetFolderByNameParams{UserID: arg.UserID, Name: arg.Name})
+	return convertFolder(f), err
+}
+
+func (s *Store) GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]database.Folder, error) {
+	folders, err := s.q.GetFoldersForUser(ctx, userID)
+	converted := make([]database.Folder, len(folders))
+	for i, f := range folders {
+		converted[i] = convertFolder(f)
+	}
+	return converted, err
+}
+
+func (s *Store) RenameFolder(ctx context.Context, arg database.RenameFolderParams) (int64, error) {
+	return s.q.RenameFolder(ctx, RenameFolderParams{NewName: arg.NewName, UpdatedAt: utc(arg.UpdatedAt), UserID: arg.UserID, Name: arg.Name})
+}
+
+// Posts
+
+func (s *Store) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	p, err := s.q.CreatePost(ctx, CreatePostParams{
+		ID:          arg.ID,
+		CreatedAt:   utc(arg.CreatedAt),
+		UpdatedAt:   utc(arg.UpdatedAt),
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		PublishedAt: nullUTC(arg.PublishedAt),
+		FeedID:      arg.FeedID,
+		BaseUrl:     arg.BaseUrl,
+		OriginalUrl: arg.OriginalUrl,
+		Fingerprint: arg.Fingerprint,
+		StoryID:     arg.StoryID,
+		Author:      arg.Author,
+		Categories:  encodeStrings(arg.Categories),
+	})
+	return convertPost(p), err
+}
+
+func (s *Store) DeletePost(ctx context.Context, id uuid.UUID) error {
+	return s.q.DeletePost(ctx, id)
+}
+
+func (s *Store) FindUserPostsByIDRange(ctx context.Context, arg database.FindUserPostsByIDRangeParams) ([]database.FindUserPostsByIDRangeRow, error) {
+	rows, err := s.q.FindUserPostsByIDRange(ctx, FindUserPostsByIDRangeParams{LowID: arg.LowID, HighID: arg.HighID, UserID: arg.UserID})
+	converted := make([]database.FindUserPostsByIDRangeRow, len(rows))
+	for i, p := range rows {
+		converted[i] = database.FindUserPostsByIDRangeRow{
+			ID:          p.ID,
+			CreatedAt:   p.CreatedAt,
+			UpdatedAt:   p.UpdatedAt,
+			Title:       p.Title,
+			Url:         p.Url,
+			Description: p.Description,
+			PublishedAt: p.PublishedAt,
+			FeedID:      p.FeedID,
+			Content:     p.Content,
+			BaseUrl:     p.BaseUrl,
+			OriginalUrl: p.OriginalUrl,
+			Fingerprint: p.Fingerprint,
+			StoryID:     p.StoryID,
+			Author:      p.Author,
+			Categories:  decodeStrings(p.Categories),
+			FeedName:    p.FeedName,
+		}
+	}
+	return converted, err
+}
+
+func (s *Store) GetFeedPostByURL(ctx context.Context, arg database.GetFeedPostByURLParams) (database.Post, error) {
+	p, err := s.q.GetFeedPostByURL(ctx, GetFeedPostByURLParams{FeedID: arg.FeedID, Url: arg.Url})
+	return convertPost(p), err
+}
+
+func (s *Store) GetPostURLs(ctx context.Context) ([]database.GetPostURLsRow, error) {
+	rows, err := s.q.GetPostURLs(ctx)
+	converted := make([]database.GetPostURLsRow, len(rows))
+	for i, p := range rows {
+		converted[i] = database.GetPostURLsRow{ID: p.ID, FeedID: p.FeedID, Url: p.Url, OriginalUrl: p.OriginalUrl}
+	}
+	return converted, err
+}
+
+func (s *Store) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+	cursorTime := sql.NullString{String: utc(arg.CursorTime.Time).Format(TimeFormat), Valid: arg.CursorTime.Valid}
+	rows, err := s.q.GetPostsForUser(ctx, GetPostsForUserParams{
+		UserID:      arg.UserID,
+		CursorID:    arg.CursorID,
+		CursorGroup: arg.CursorGroup,
+		CursorTime:  cursorTime,
+		Offset:      int64(arg.Offset),
+		Limit:       int64(arg.Limit),
+		FeedID:      arg.FeedID,
+		ShowHidden:  arg.ShowHidden,
+		UnreadOnly:  arg.UnreadOnly,
+		StarredOnly: arg.StarredOnly,
+		Tag:         arg.Tag,
+		FolderID:    arg.FolderID,
+		Since:       nullTimeArg(arg.Since),
+		Until:       nullTimeArg(arg.Until),
+		Sort:        arg.Sort,
+	})
+	converted := make([]database.GetPostsForUserRow, len(rows))
+	for i, p := range rows {
+		converted[i] = database.GetPostsForUserRow{
+			ID:            p.ID,
+			CreatedAt:     p.CreatedAt,
+			UpdatedAt:     p.UpdatedAt,
+			Title:         p.Title,
+			Url:           p.Url,
+			Description:   p.Description,
+			PublishedAt:   p.PublishedAt,
+			FeedID:        p.FeedID,
+			Content:       p.Content,
+			BaseUrl:       p.BaseUrl,
+			OriginalUrl:   p.OriginalUrl,
+			Fingerprint:   p.Fingerprint,
+			StoryID:       p.StoryID,
+			Author:        p.Author,
+			Categories:    decodeStrings(p.Categories),
+			FeedName:      p.FeedName,
+			FolderID:      p.FolderID,
+			IsRead:        p.IsRead != 0,
+			IsStarred:     p.IsStarred != 0,
+			IsHighlighted: p.IsHighlighted != 0,
+			SortGroup:     p.SortGroup,
+			SortTime:      parseTime(p.SortTime),
+			AlsoIn:        sortedStrings(p.AlsoIn),
+			Tags:          sortedStrings(p.Tags),
+		}
+	}
+	return converted, err
+}
+
+func (s *Store) GetRecentPostFingerprints(ctx context.Context, arg database.GetRecentPostFingerprintsParams) ([]database.GetRecentPostFingerprintsRow, error) {
+	rows, err := s.q.GetRecentPostFingerprints(ctx, GetRecentPostFingerprintsParams{FeedID: arg.FeedID, CreatedAt: utc(arg.CreatedAt)})
+	converted := make([]database.GetRecentPostFingerprintsRow, len(rows))
+	for i, p := range rows {
+		converted[i] = database.GetRecentPostFingerprintsRow{ID: p.ID, StoryID: p.StoryID, Fingerprint: p.Fingerprint}
+	}
+	return converted, err
+}
+
+func (s *Store) GetStoryIDByURL(ctx context.Context, arg database.GetStoryIDByURLParams) (uuid.UUID, error) {
+	return s.q.GetStoryIDByURL(ctx, GetStoryIDByURLParams{Url: arg.Url, FeedID: arg.FeedID})
+}
+
+func (s *Store) SetPostStory(ctx context.Context, arg database.SetPostStoryParams) error {
+	return s.q.SetPostStory(ctx, SetPostStoryParams{StoryID: arg.StoryID, ID: arg.ID})
+}
+
+func (s *Store) UpdatePostContent(ctx context.Context, arg database.UpdatePostContentParams) error {
+	return s.q.UpdatePostContent(ctx, UpdatePostContentParams{Content: arg.Content, UpdatedAt: utc(arg.UpdatedAt), ID: arg.ID})
+}
+
+func (s *Store) UpdatePostURL(ctx context.Context, arg database.UpdatePostURLParams) error {
+	return s.q.UpdatePostURL(ctx, UpdatePostURLParams{Url: arg.Url, OriginalUrl: arg.OriginalUrl, UpdatedAt: utc(arg.UpdatedAt), ID: arg.ID})
+}
+
+// Pruning
+
+func (s *Store) CreatePrunedPost(ctx context.Context, arg database.CreatePrunedPostParams) error {
+	return s.q.CreatePrunedPost(ctx, CreatePrunedPostParams{FeedID: arg.FeedID, Url: arg.Url, PrunedAt: utc(arg.PrunedAt)})
+}
+
+func (s *Store) DeletePrunedPost(ctx context.Context, arg database.DeletePrunedPostParams) error {
+	return s.q.DeletePrunedPost(ctx, DeletePrunedPostParams{FeedID: arg.FeedID, Url: arg.Url})
+}
+
+func (s *Store) GetFeedPrunedURLs(ctx context.Context, feedID uuid.UUID) ([]string, error) {
+	return s.q.GetFeedPrunedURLs(ctx, feedID)
+}
+
+func (s *Store) GetPrunablePosts(ctx context.Context, arg database.GetPrunablePostsParams) ([]database.GetPrunablePostsRow, error) {
+	rows, err := s.q.GetPrunablePosts(ctx, GetPrunablePostsParams{
+		Now:            utc(arg.Now),
+		DefaultPosts:   int64(arg.DefaultPosts),
+		DefaultSeconds: arg.DefaultSeconds,
+		FeedID:         arg.FeedID,
+	})
+	converted := make([]database.GetPrunablePostsRow, len(rows))
+	for i, r := range rows {
+		converted[i] = database.GetPrunablePostsRow(r)
+	}
+	return converted, err
+}
+
+func (s *Store) IsPostPruned(ctx context.Context, arg database.IsPostPrunedParams) (bool, error) {
+	pruned, err := s.q.IsPostPruned(ctx, IsPostPrunedParams{FeedID: arg.FeedID, Url: arg.Url})
+	return pruned != 0, err
+}
+
+// Read state and stars
+
+func (s *Store) MarkAllPostsRead(ctx context.Context, arg database.MarkAllPostsReadParams) (int64, error) {
+	var feedID interface{}
+	if arg.FeedID.Valid {
+		feedID = arg.FeedID.UUID
+	}
+	return s.q.MarkAllPostsRead(ctx, MarkAllPostsReadParams{
+		ReadAt: utc(arg.ReadAt),
+		UserID: arg.UserID,
+		FeedID: feedID,
+		Before: nullTimeArg(arg.Before),
+	})
+}
+
+func (s *Store) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) (int64, error) {
+	return s.q.MarkPostRead(ctx, MarkPostReadParams{UserID: arg.UserID, ReadAt: utc(arg.ReadAt), PostID: arg.PostID})
+}
+
+func (s *Store) MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) (int64, error) {
+	return s.q.MarkPostUnread(ctx, MarkPostUnreadParams{UserID: arg.UserID, PostID: arg.PostID})
+}
+
+func (s *Store) MovePostReads(ctx context.Context, arg database.MovePostReadsParams) error {
+	return s.q.MovePostReads(ctx, MovePostReadsParams{ToPostID: arg.ToPostID, FromPostID: arg.FromPostID})
+}
+
+func (s *Store) GetStarredPostsForUser(ctx context.Context, arg database.GetStarredPostsForUserParams) ([]database.GetStarredPostsForUserRow, error) {
+	rows, err := s.q.GetStarredPostsForUser(ctx, GetStarredPostsForUserParams{UserID: arg.UserID, Limit: int64(arg.Limit)})
+	converted := make([]database.GetStarredPostsForUserRow, len(rows))
+	for i, p := range rows {
+		converted[i] = database.GetStarredPostsForUserRow{
+			ID:          p.ID,
+			CreatedAt:   p.CreatedAt,
+			UpdatedAt:   p.UpdatedAt,
+			Title:       p.Title,
+			Url:         p.Url,
+			Description: p.Description,
+			PublishedAt: p.PublishedAt,
+			FeedID:      p.FeedID,
+			Content:     p.Content,
+			BaseUrl:     p.BaseUrl,
+			OriginalUrl: p.OriginalUrl,
+			Fingerprint: p.Fingerprint,
+			StoryID:     p.StoryID,
+			Author:      p.Author,
+			Categories:  decodeStrings(p.Categories),
+			FeedName:    p.FeedName,
+			StarredAt:   p.StarredAt,
+		}
+	}
+	return converted, err
+}
+
+func (s *Store) MovePostStars(ctx context.Context, arg database.MovePostStarsParams) error {
+	return s.q.MovePostStars(ctx, MovePostStarsParams{ToPostID: arg.ToPostID, FromPostID: arg.FromPostID})
+}
+
+func (s *Store) StarPost(ctx context.Context, arg database.StarPostParams) (int64, error) {
+	return s.q.StarPost(ctx, StarPostParams{UserID: arg.UserID, PostID: arg.PostID, StarredAt: utc(arg.StarredAt)})
+}
+
+func (s *Store) UnstarPost(ctx context.Context, arg database.UnstarPostParams) (int64, error) {
+	return s.q.UnstarPost(ctx, UnstarPostParams{UserID: arg.UserID, PostID: arg.PostID})
+}
+
+// Rules
+
+func (s *Store) CreateRule(ctx context.Context, arg database.CreateRuleParams) (database.Rule, error) {
+	r, err := s.q.CreateRule(ctx, CreateRuleParams{
+		ID:         arg.ID,
+		CreatedAt:  utc(arg.CreatedAt),
+		UserID:     arg.UserID,
+		Expression: arg.Expression,
+		Action:     arg.Action,
+		Tag:        arg.Tag,
+	})
+	return convertRule(r), err
+}
+
+func (s *Store) DeleteRule(ctx context.Context, arg database.DeleteRuleParams) (int64, error) {
+	return s.q.DeleteRule(ctx, DeleteRuleParams{ID: arg.ID, UserID: arg.UserID})
+}
+
+func (s *Store) GetRuleCandidatePosts(ctx context.Context, userID uuid.UUID) ([]database.GetRuleCandidatePostsRow, error) {
+	rows, err := s.q.GetRuleCandidatePosts(ctx, userID)
+	converted := make([]database.GetRuleCandidatePostsRow, len(rows))
+	for i, p := range rows {
+		converted[i] = database.GetRuleCandidatePostsRow{
+			ID:          p.ID,
+			Title:       p.Title,
+			Description: p.Description,
+			Author:      p.Author,
+			Categories:  decodeStrings(p.Categories),
+			FeedName:    p.FeedName,
+			FeedUrl:     p.FeedUrl,
+			FeedTitle:   p.FeedTitle,
+		}
+	}
+	return converted, err
+}
+
+func (s *Store) GetRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.GetRulesForFeedRow, error) {
+	rows, err := s.q.GetRulesForFeed(ctx, feedID)
+	converted := make([]database.GetRulesForFeedRow, len(rows))
+	for i, r := range rows {
+		converted[i] = database.GetRulesForFeedRow{
+			ID:         r.ID,
+			CreatedAt:  r.CreatedAt,
+			UserID:     r.UserID,
+			Expression: r.Expression,
+			Action:     r.Action,
+			Tag:        r.Tag,
+			FeedTitle:  r.FeedTitle,
+		}
+	}
+	return converted, err
+}
+
+func (s *Store) GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.Rule, error) {
+	rules, err := s.q.GetRulesForUser(ctx, userID)
+	return convertRules(rules), err
+}
+
+func (s *Store) HidePost(ctx context.Context, arg database.HidePostParams) (int64, error) {
+	return s.q.HidePost(ctx, HidePostParams{UserID: arg.UserID, PostID: arg.PostID, HiddenAt: utc(arg.HiddenAt)})
+}
+
+func (s *Store) MovePostHides(ctx context.Context, arg database.MovePostHidesParams) error {
+	return s.q.MovePostHides(ctx, MovePostHidesParams{ToPostID: arg.ToPostID, FromPostID: arg.FromPostID})
+}
+
+func (s *Store) MovePostHighlights(ctx context.Context, arg database.MovePostHighlightsParams) error {
+	return s.q.MovePostHighlights(ctx, MovePostHighlightsParams{ToPostID: arg.ToPostID, FromPostID: arg.FromPostID})
+}
+
+func (s *Store) HighlightPost(ctx context.Context, arg database.HighlightPostParams) (int64, error) {
+	return s.q.HighlightPost(ctx, HighlightPostParams{UserID: arg.UserID, PostID: arg.PostID, HighlightedAt: utc(arg.HighlightedAt)})
+}
+
+// Tags
+
+func (s *Store) DeleteUnusedTags(ctx context.Context, userID uuid.UUID) error {
+	return s.q.DeleteUnusedTags(ctx, userID)
+}
+
+func (s *Store) GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetTagsForUserRow, error) {
+	rows, err := s.q.GetTagsForUser(ctx, userID)
+	converted := make([]database.GetTagsForUserRow, len(rows))
+	for i, t := range rows {
+		converted[i] = database.GetTagsForUserRow{Name: t.Name, PostCount: t.PostCount}
+	}
+	return converted, err
+}
+
+func (s *Store) MovePostTags(ctx context.Context, arg database.MovePostTagsParams) error {
+	return s.q.MovePostTags(ctx, MovePostTagsParams{ToPostID: arg.ToPostID, FromPostID: arg.FromPostID})
+}
+
+func (s *Store) TagPost(ctx context.Context, arg database.TagPostParams) (int64, error) {
+	return s.q.TagPost(ctx, TagPostParams{TagID: arg.TagID, PostID: arg.PostID, TaggedAt: utc(arg.TaggedAt)})
+}
+
+func (s *Store) UntagPost(ctx context.Context, arg database.UntagPostParams) (int64, error) {
+	return s.q.UntagPost(ctx, UntagPostParams{PostID: arg.PostID, UserID: arg.UserID, Name: arg.Name})
+}
+
+func (s *Store) UpsertTag(ctx context.Context, arg database.UpsertTagParams) (database.Tag, error) {
+	t, err := s.q.UpsertTag(ctx, UpsertTagParams{ID: arg.ID, CreatedAt: utc(arg.CreatedAt), UserID: arg.UserID, Name: arg.Name})
+	return database.Tag{ID: t.ID, CreatedAt: t.CreatedAt, UserID: t.UserID, Name: t.Name}, err
+}
+
+// WebSub subscriptions
+
+func (s *Store) ConfirmWebSubSubscription(ctx context.Context, arg database.ConfirmWebSubSubscriptionParams) (database.WebsubSubscription, error) {
+	sub, err := s.q.ConfirmWebSubSubscription(ctx, ConfirmWebSubSubscriptionParams{
+		LeaseExpiresAt: nullUTC(arg.LeaseExpiresAt),
+		UpdatedAt:      utc(arg.UpdatedAt),
+		ID:             arg.ID,
+	})
+	return convertWebSubSubscription(sub), err
+}
+
+func (s *Store) DeleteWebSubSubscription(ctx context.Context, id uuid.UUID) error {
+	return s.q.DeleteWebSubSubscription(ctx, id)
+}
+
+func (s *Store) GetWebSubSubscription(ctx context.Context, id uuid.UUID) (database.WebsubSubscription, error) {
+	sub, err := s.q.GetWebSubSubscription(ctx, id)
+	return convertWebSubSubscription(sub), err
+}
+
+func (s *Store) GetWebSubSubscriptionForFeed(ctx context.Context, feedID uuid.UUID) (database.WebsubSubscription, error) {
+	sub, err := s.q.GetWebSubSubscriptionForFeed(ctx, feedID)
+	return convertWebSubSubscription(sub), err
+}
+
+func (s *Store) GetWebSubSubscriptionsToRenew(ctx context.Context, arg database.GetWebSubSubscriptionsToRenewParams) ([]database.WebsubSubscription, error) {
+	subs, err := s.q.GetWebSubSubscriptionsToRenew(ctx, GetWebSubSubscriptionsToRenewParams{
+		ExpiresBefore:   sql.NullTime{Time: utc(arg.ExpiresBefore), Valid: true},
+		RequestedBefore: utc(arg.RequestedBefore),
+	})
+	converted := make([]database.WebsubSubscription, len(subs))
+	for i, sub := range subs {
+		converted[i] = convertWebSubSubscription(sub)
+	}
+	return converted, err
+}
+
+func (s *Store) UpsertWebSubSubscription(ctx context.Context, arg database.UpsertWebSubSubscriptionParams) (database.WebsubSubscription, error) {
+	sub, err := s.q.UpsertWebSubSubscription(ctx, UpsertWebSubSubscriptionParams{
+		ID:          arg.ID,
+		CreatedAt:   utc(arg.CreatedAt),
+		UpdatedAt:   utc(arg.UpdatedAt),
+		FeedID:      arg.FeedID,
+		HubUrl:      arg.HubUrl,
+		TopicUrl:    arg.TopicUrl,
+		Secret:      arg.Secret,
+		RequestedAt: utc(arg.RequestedAt),
+	})
+	return convertWebSubSubscription(sub), err
+}
@@ -0,0 +1,90 @@
+// Package urlnorm normalises post URLs so the same article reached through slightly
+// different links (tracking parameters, default ports, trailing slashes, fragments,
+// letter case in the host) is stored only once.
+package urlnorm
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// DefaultTrackingParams are the query parameters stripped when the config doesn't list
+// its own. A trailing "*" matches any parameter starting with the text before it.
+var DefaultTrackingParams = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "msclkid", "yclid", "mc_cid", "mc_eid", "igshid", "_ga",
+}
+
+// Normalize returns the canonical form of rawURL:
+//   - scheme and host are lower-cased and default ports (:80, :443) removed
+//   - the fragment is dropped
+//   - "." and ".." path segments are resolved and a trailing slash is removed
+//     (an empty path becomes "/")
+//   - query parameters matching trackingParams are removed and the rest sorted by key
+//
+// Only absolute http(s) URLs are normalised; anything else is returned with an error.
+func Normalize(rawURL string, trackingParams []string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", errors.New("urlnorm: not an absolute http(s) URL")
+	}
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6 literal
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	// path.Clean resolves "." and ".." segments and drops the trailing slash. It runs on
+	// the escaped path, so an escaped slash (%2F) stays inside its segment rather than
+	// turning /a%2Fb into the different /a/b.
+	escaped := path.Clean("/" + u.EscapedPath())
+	unescaped, err := url.PathUnescape(escaped)
+	if err != nil {
+		return "", err
+	}
+	u.Path, u.RawPath = unescaped, escaped
+
+	query := u.Query()
+	for key := range query {
+		if isTrackingParam(key, trackingParams) {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode() // Encode sorts by key
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+// isTrackingParam reports whether key matches one of the patterns, case-insensitively.
+func isTrackingParam(key string, patterns []string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package urlnorm
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"host case and default port", "HTTPS://Example.COM:443/Post", "https://example.com/Post"},
+		{"other port kept", "http://example.com:8080/a", "http://example.com:8080/a"},
+		{"fragment and trailing slash", "https://example.com/a/b/#comments", "https://example.com/a/b"},
+		{"empty path", "https://example.com", "https://example.com/"},
+		{"dot segments", "https://example.com/a/./b/../c", "https://example.com/a/c"},
+		{"tracking parameters", "https://example.com/a?utm_source=rss&id=2&fbclid=x&b=1", "https://example.com/a?b=1&id=2"},
+		{"tracking parameters are case-insensitive", "https://example.com/a?UTM_Medium=x", "https://example.com/a"},
+		{"empty query", "https://example.com/a?", "https://example.com/a"},
+		{"IPv6 host", "http://[::1]:80/a", "http://[::1]/a"},
+		{"escaped slash stays escaped", "https://example.com/a%2Fb", "https://example.com/a%2Fb"},
+		{"escaped dots are not a segment", "https://example.com/x/a%2F..%2Fb", "https://example.com/x/a%2F..%2Fb"},
+		{"escaped characters", "https://example.com/caf%C3%A9/%20x", "https://example.com/caf%C3%A9/%20x"},
+		{"whitespace", "  https://example.com/a  ", "https://example.com/a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.in, DefaultTrackingParams)
+			if err != nil || got != tt.want {
+				t.Errorf("Normalize(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeKeepsDistinctPaths(t *testing.T) {
+	a, err := Normalize("https://example.com/a%2Fb", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Normalize("https://example.com/a/b", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Errorf("/a%%2Fb and /a/b both normalise to %s", a)
+	}
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	for _, in := range []string{"ftp://example.com/a", "/relative/path", "mailto:someone@example.com", "https://exa mple.com/%zz"} {
+		if got, err := Normalize(in, nil); err == nil {
+			t.Errorf("Normalize(%q) = %q, want an error", in, got)
+		}
+	}
+}
+
+func TestIsTrackingParam(t *testing.T) {
+	patterns := []string{"utm_*", "ref"}
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"utm_source", true},
+		{"UTM_CAMPAIGN", true},
+		{"ref", true},
+		{"referrer", false},
+		{"id", false},
+	}
+	for _, tt := range tests {
+		if got := isTrackingParam(tt.key, patterns); got != tt.want {
+			t.Errorf("isTrackingParam(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
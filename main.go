@@ -1,13 +1,13 @@
 package main
 
 import (
-    "database/sql"
+    "errors"
     "log"
     "os"
+    "strings"
 
     "github.com/Marcus-Gustafsson/gator/internal/config"
-    "github.com/Marcus-Gustafsson/gator/internal/database"
-    _ "github.com/lib/pq"
+    "github.com/Marcus-Gustafsson/gator/internal/output"
 )
 
 func main() {
@@ -17,58 +17,338 @@ func main() {
         log.Fatal(err)
     }
 
-    dbPtr, err := sql.Open("postgres", cfg.DbURL)
+    // The db_url scheme picks PostgreSQL or SQLite.
+    connPtr, db, driver, err := openDatabase(cfg.DbURL)
     if err != nil {
         log.Fatal(err)
     }
-    defer dbPtr.Close()
+    defer connPtr.Close()
 
-    // Initialize State with config and database pointer.
-    st := state{cfgPtr: &cfg, dbPtr: database.New(dbPtr)}
+    // Pull the global --output flag out of the arguments; it applies to every command.
+    // Shell completion sees the words as typed, --output included.
+    outputSpec, args := "", os.Args[1:]
+    if len(args) == 0 || args[0] != "__complete" {
+        outputSpec, args, err = extractOutputFlag(args)
+        if err != nil {
+            log.Fatal(err)
+        }
+    }
+    outPtr, err := output.New(outputSpec, os.Stdout)
+    if err != nil {
+        log.Fatal(err)
+    }
 
-    // Initialize Cmds with a map to store handlers.
-    cmds := cmds{FunctionMap: make(map[string]func(*state, command) error)}
+    // Initialize State with config, database pointer and output format.
+    st := state{cfgPtr: &cfg, dbPtr: db, connPtr: connPtr, driver: driver, outPtr: outPtr}
+
+    // Initialize Cmds with the command registry.
+    cmds := cmds{Specs: make(map[string]*commandSpec)}
 
     // Register all handlers
     registerCommands(&cmds)
 
-    // Get command-line arguments.
-    args := os.Args
-
-    // Require at least the program name and a command.
-    if len(args) < 2 {
-        log.Fatal("Not enough arguments. Usage: go run . <command> [args...]")
+    // Without a command, show what's available.
+    if len(args) < 1 {
+        cmds.printHelp(os.Stderr)
+        os.Exit(exitUsage)
     }
 
     // Extract command name and arguments.
-    cmdName := args[1]
-    cmdArgs := args[2:]
+    cmdName := args[0]
+    cmdArgs := args[1:]
 
     // Create Command instance.
     cmd := command{Name: cmdName, Args: cmdArgs}
 
-    // Run the command; print any errors and exit on failure.
+    // Run the command; print any errors and exit with a status that tells usage
+    // mistakes (2) apart from failures (1).
     err = cmds.run(&st, cmd)
     if err != nil {
-        log.Fatal(err)
+        log.Print(err)
+        os.Exit(exitCode(err))
     }
 }
 
-// registerCommands registers all available command handlers
+// extractOutputFlag removes "--output <format>" or "--output=<format>" from anywhere in
+// the arguments before a "--", which ends the flags, and returns the format (empty if not
+// given) and the remaining arguments. Arguments after "--" are left alone, so a search
+// term or rule expression can be "--output" itself.
+func extractOutputFlag(args []string) (string, []string, error) {
+    spec := ""
+    var rest []string
+    for i := 0; i < len(args); i++ {
+        if args[i] == "--" {
+            rest = append(rest, args[i:]...)
+            break
+        } else if value, ok := strings.CutPrefix(args[i], "--output="); ok {
+            spec = value
+        } else if args[i] == "--output" {
+            if i+1 == len(args) {
+                return "", nil, errors.New("--output needs a format: text, table, json, ndjson, csv or template=...")
+            }
+            spec = args[i+1]
+            i++
+        } else {
+            rest = append(rest, args[i])
+        }
+    }
+    return spec, rest, nil
+}
+
+// registerCommands registers all available commands with their arguments, flags and
+// help text. The order here is the order of "gator help".
 func registerCommands(cmds *cmds) {
 
     // User commands
-    cmds.register("login", handlerLogin)
-    cmds.register("register", handlerRegister)
-    cmds.register("reset", handlerReset)
-    cmds.register("users", handlerGetUsers)
-    
+    cmds.register(commandSpec{
+        Name: "login", Group: "User commands", Handler: handlerLogin,
+        Summary: "Switch to an existing user",
+        Args:    []argSpec{{Name: "username", Complete: "users"}},
+    })
+    cmds.register(commandSpec{
+        Name: "register", Group: "User commands", Handler: handlerRegister,
+        Summary: "Create a user and switch to it",
+        Args:    []argSpec{{Name: "username"}},
+    })
+    cmds.register(commandSpec{
+        Name: "reset", Group: "User commands", Handler: handlerReset,
+        Summary:     "Delete all users and their data",
+        Description: "Meant for development and testing: every user, and everything that belongs to them, is deleted.",
+    })
+    cmds.register(commandSpec{
+        Name: "users", Group: "User commands", Handler: handlerGetUsers,
+        Summary: "List users, marking the current one",
+    })
+
     // Feed commands
-    cmds.register("agg", handlerAgg)
-	cmds.register("addfeed", middlewareLoggedIn(handlerAddFeed))
-    cmds.register("feeds", handlerGetFeeds)
-	cmds.register("follow", middlewareLoggedIn(handlerFollow))
-	cmds.register("following", middlewareLoggedIn(handlerListFeedFollows))
-    cmds.register("unfollow", middlewareLoggedIn(handlerUnfollow))
-	cmds.register("browse", middlewareLoggedIn(handlerBrowse))
-}
\ No newline at end of file
+    cmds.register(commandSpec{
+        Name: "agg", Group: "Feed commands", Handler: handlerAgg,
+        Summary:     "Fetch feeds in a loop",
+        Description: "Fetches the least recently fetched feed once per interval (e.g. 30s, 1m, 1h) until interrupted.",
+        Args:        []argSpec{{Name: "time_between_reqs"}},
+        Flags:       []flagSpec{{Name: "prune", Kind: flagBool, Usage: "delete posts past their retention after each fetch, like prune"}},
+    })
+    cmds.register(commandSpec{
+        Name: "serve", Group: "Feed commands", Handler: handlerServe,
+        Summary: "Fetch feeds and receive WebSub pushes",
+        Description: "Like agg, but also runs a WebSub callback server on listen_addr, reachable by hubs at callback_base_url.\n" +
+            "Feeds that advertise a hub are subscribed to and receive new posts by push instead of being polled.",
+        Args:  []argSpec{{Name: "listen_addr"}, {Name: "callback_base_url"}, {Name: "time_between_reqs"}},
+        Flags: []flagSpec{{Name: "prune", Kind: flagBool, Usage: "delete posts past their retention after each fetch, like prune"}},
+    })
+    cmds.register(commandSpec{
+        Name: "addfeed", Group: "Feed commands", UserHandler: handlerAddFeed,
+        Summary: "Add a feed and follow it",
+        Args:    []argSpec{{Name: "name"}, {Name: "feed_url"}},
+    })
+    cmds.register(commandSpec{
+        Name: "feeds", Group: "Feed commands", Handler: handlerGetFeeds,
+        Summary: "List all feeds",
+    })
+    cmds.register(commandSpec{
+        Name: "fullcontent", Group: "Feed commands", UserHandler: handlerFullContent,
+        Summary:     "Download full articles for a feed's posts",
+        Description: "With on, the page behind every new post is downloaded and its article text shown by browse instead of the feed's summary. Only the user who added the feed can change this.",
+        Args:        []argSpec{{Name: "feed_url", Complete: "feeds"}, {Name: "on|off", Complete: "on,off"}},
+    })
+    cmds.register(commandSpec{
+        Name: "retention", Group: "Feed commands", UserHandler: handlerRetention,
+        Summary: "Show or change how long a feed's posts are kept",
+        Description: "Overrides the retain_posts and retain_for defaults of the config file for one feed; prune deletes the rest.\n" +
+            "Both flags also accept none (no limit) and default (the config file's setting). Without flags the current settings are printed.",
+        Args: []argSpec{{Name: "feed_url", Complete: "feeds"}},
+        Flags: []flagSpec{
+            {Name: "keep", Arg: "<n>", Usage: "keep the feed's n most recently saved posts", Complete: "none,default"},
+            {Name: "max-age", Arg: "<duration>", Usage: "keep posts saved within this time, e.g. 720h or 90d", Complete: "none,default"},
+        },
+    })
+    cmds.register(commandSpec{
+        Name: "follow", Group: "Feed commands", UserHandler: handlerFollow,
+        Summary: "Follow a feed",
+        Args:    []argSpec{{Name: "feed_url", Complete: "feeds"}},
+        Flags:   []flagSpec{{Name: "folder", Arg: "<name>", Usage: "put the feed in this folder", Complete: "folders"}},
+    })
+    cmds.register(commandSpec{
+        Name: "following", Group: "Feed commands", UserHandler: handlerListFeedFollows,
+        Summary: "List the feeds you follow by folder",
+    })
+    cmds.register(commandSpec{
+        Name: "unfollow", Group: "Feed commands", UserHandler: handlerUnfollow,
+        Summary: "Stop following a feed",
+        Args:    []argSpec{{Name: "feed_url", Complete: "feeds"}},
+    })
+    cmds.register(commandSpec{
+        Name: "browse", Group: "Feed commands", UserHandler: handlerBrowse,
+        Summary: "Show posts from the feeds you follow",
+        Description: "Shows the newest unread posts. A story that reached you through several feeds is shown once.\n" +
+            "After a full page, browse prints a --cursor to pass with the same flags for the next page.",
+        Args: []argSpec{{Name: "limit", Optional: true}},
+        Flags: []flagSpec{
+            {Name: "limit", Kind: flagInt, Arg: "<n>", Default: "2", Usage: "number of posts per page"},
+            {Name: "all", Kind: flagBool, Usage: "include posts that were already read"},
+            {Name: "unread", Kind: flagBool, Default: "true", Usage: "only show unread posts"},
+            {Name: "starred", Kind: flagBool, Usage: "only show starred posts"},
+            {Name: "tag", Arg: "<tag>", Usage: "only show posts with this tag", Complete: "tags"},
+            {Name: "hidden", Kind: flagBool, Usage: "include posts hidden by rules"},
+            {Name: "feed", Arg: "<feed_url>", Usage: "only show posts of the feed with this URL", Complete: "feeds"},
+            {Name: "folder", Arg: "<name>", Usage: "only show posts of feeds in this folder", Complete: "folders"},
+            {Name: "since", Arg: "<date>", Usage: "only show posts published on or after this date"},
+            {Name: "until", Arg: "<date>", Usage: "only show posts published before this date"},
+            {Name: "sort", Arg: "<order>", Default: "published", Usage: "order by published, fetched or feed", Complete: "published,fetched,feed"},
+            {Name: "offset", Kind: flagInt, Arg: "<n>", Usage: "skip this many posts"},
+            {Name: "page", Kind: flagInt, Arg: "<n>", Usage: "show this page (1-based) of --limit posts"},
+            {Name: "cursor", Arg: "<token>", Usage: "continue after the page that printed this cursor"},
+        },
+    })
+    cmds.register(commandSpec{
+        Name: "tui", Group: "Feed commands", UserHandler: handlerTUI,
+        Summary: "Read in an interactive three-pane reader",
+    })
+    cmds.register(commandSpec{
+        Name: "folder", Group: "Feed commands", UserHandler: handlerFolder,
+        Summary: "Create, rename or delete folders",
+        Description: "  folder create <name>\n  folder rename <name> <new_name>\n  folder delete <name>\n" +
+            "Deleting a folder keeps its feeds followed.",
+        Args: []argSpec{{Name: "create|rename|delete", Complete: "create,rename,delete"}, {Name: "name", Variadic: true, Complete: "folders"}},
+    })
+    cmds.register(commandSpec{
+        Name: "move", Group: "Feed commands", UserHandler: handlerMove,
+        Summary: "Move a followed feed into a folder",
+        Args:    []argSpec{{Name: "feed_url", Complete: "feeds"}, {Name: "folder", Optional: true, Complete: "folders"}},
+        Flags:   []flagSpec{{Name: "unfiled", Kind: flagBool, Usage: "take the feed out of its folder instead"}},
+    })
+    cmds.register(commandSpec{
+        Name: "feed-settings", Group: "Feed commands", UserHandler: handlerFeedSettings,
+        Summary:     "Show or change your settings for a feed",
+        Description: "Only the given settings change; without flags the current settings are printed.",
+        Args:        []argSpec{{Name: "feed_url", Complete: "feeds"}},
+        Flags: []flagSpec{
+            {Name: "title", Arg: "<name>", Usage: "your own name for the feed"},
+            {Name: "clear-title", Kind: flagBool, Usage: "use the feed's own name again"},
+            {Name: "mute", Kind: flagBool, Usage: "hide the feed's posts from browse"},
+            {Name: "unmute", Kind: flagBool, Usage: "show the feed's posts in browse again"},
+            {Name: "notify", Arg: "<on|off>", Usage: "record whether you want to hear about new posts (nothing notifies yet)", Complete: "on,off"},
+            {Name: "priority", Kind: flagInt, Arg: "<n>", Usage: "position in the following list, higher first"},
+        },
+    })
+
+    // Post commands
+    cmds.register(commandSpec{
+        Name: "read", Group: "Post commands", UserHandler: handlerRead,
+        Summary: "Mark posts as read",
+        Args:    []argSpec{{Name: "post-id", Variadic: true}},
+    })
+    cmds.register(commandSpec{
+        Name: "unread", Group: "Post commands", UserHandler: handlerUnread,
+        Summary: "Mark posts as unread",
+        Args:    []argSpec{{Name: "post-id", Variadic: true}},
+    })
+    cmds.register(commandSpec{
+        Name: "mark-all-read", Group: "Post commands", UserHandler: handlerMarkAllRead,
+        Summary: "Mark everything in the feeds you follow as read",
+        Flags: []flagSpec{
+            {Name: "feed", Arg: "<feed_url>", Usage: "only mark posts of the feed with this URL", Complete: "feeds"},
+            {Name: "before", Arg: "<date>", Usage: "only mark posts published before this date"},
+        },
+    })
+    cmds.register(commandSpec{
+        Name: "star", Group: "Post commands", UserHandler: handlerStar,
+        Summary: "Save a post",
+        Args:    []argSpec{{Name: "post-id"}},
+    })
+    cmds.register(commandSpec{
+        Name: "unstar", Group: "Post commands", UserHandler: handlerUnstar,
+        Summary: "Stop saving a post",
+        Args:    []argSpec{{Name: "post-id"}},
+    })
+    cmds.register(commandSpec{
+        Name: "starred", Group: "Post commands", UserHandler: handlerStarred,
+        Summary: "List your starred posts",
+        Args:    []argSpec{{Name: "limit", Optional: true}},
+    })
+    cmds.register(commandSpec{
+        Name: "tag", Group: "Post commands", UserHandler: handlerTag,
+        Summary: "Add tags to a post",
+        Args:    []argSpec{{Name: "post-id"}, {Name: "tag", Variadic: true, Complete: "tags"}},
+    })
+    cmds.register(commandSpec{
+        Name: "untag", Group: "Post commands", UserHandler: handlerUntag,
+        Summary: "Remove tags from a post",
+        Args:    []argSpec{{Name: "post-id"}, {Name: "tag", Variadic: true, Complete: "tags"}},
+    })
+    cmds.register(commandSpec{
+        Name: "tags", Group: "Post commands", UserHandler: handlerTags,
+        Summary: "List your tags",
+    })
+    cmds.register(commandSpec{
+        Name: "rules", Group: "Post commands", UserHandler: handlerRules,
+        Summary: "Add, list, delete or apply rules that act on matching posts",
+        Description: "  rules add <hide|read|star|highlight|tag:<name>> <expression...>\n  rules list\n  rules delete <rule-id>\n  rules apply\n" +
+            "Expressions match feed:, title:, description:, author: and category: with words, \"phrases\" or /regexps/,\n" +
+            "combined with AND, OR, NOT (or -) and parentheses.",
+        Args:     []argSpec{{Name: "add|list|delete|apply", Complete: "add,list,delete,apply"}, {Name: "args", Optional: true, Variadic: true}},
+        DashArgs: true,
+    })
+    cmds.register(commandSpec{
+        Name: "search", Group: "Post commands", UserHandler: handlerSearch,
+        Summary:     "Full-text search over posts",
+        Description: "Accepts \"quoted phrases\", OR and -excluded words; several arguments are joined into one query.",
+        Args:        []argSpec{{Name: "query", Variadic: true}},
+        Flags: []flagSpec{
+            {Name: "limit", Kind: flagInt, Arg: "<n>", Default: "10", Usage: "number of results"},
+            {Name: "all-feeds", Kind: flagBool, Usage: "also search feeds you don't follow"},
+        },
+        DashArgs: true,
+    })
+
+    // Maintenance commands
+    cmds.register(commandSpec{
+        Name: "normalize-posts", Group: "Maintenance commands", Handler: handlerNormalizePosts,
+        Summary: "Re-normalise stored post URLs and merge duplicates",
+    })
+    cmds.register(commandSpec{
+        Name: "prune", Group: "Maintenance commands", Handler: handlerPrune,
+        Summary: "Delete posts past their feed's retention",
+        Description: "Each feed keeps its most recent posts and recent posts as set with retention, or by retain_posts and\n" +
+            "retain_for in the config file. Starred and tagged posts are always kept.",
+        Flags: []flagSpec{
+            {Name: "dry-run", Kind: flagBool, Usage: "list the posts that would be deleted instead"},
+            {Name: "feed", Arg: "<feed_url>", Usage: "only prune the feed with this URL", Complete: "feeds"},
+        },
+    })
+    cmds.register(commandSpec{
+        Name: "migrate", Group: "Maintenance commands", Handler: handlerMigrate, NoDatabase: true,
+        Summary: "Apply or roll back database schema migrations",
+        Description: "  migrate [up]     apply all pending migrations (run this after installing or upgrading)\n" +
+            "  migrate down     roll back the latest migration\n" +
+            "  migrate redo     roll back the latest migration and apply it again\n" +
+            "  migrate status   list the migrations and when they were applied\n" +
+            "Other commands refuse to run until all migrations are applied.",
+        Args: []argSpec{{Name: "up|down|redo|status", Optional: true, Complete: "up,down,redo,status"}},
+    })
+    cmds.register(commandSpec{
+        Name: "help", Group: "Maintenance commands", Handler: cmds.handlerHelp, NoDatabase: true,
+        Summary: "Show the commands, or details on one",
+        Args:    []argSpec{{Name: "command", Optional: true, Complete: "commands"}},
+    })
+    cmds.register(commandSpec{
+        Name: "shell", Group: "Maintenance commands", Handler: cmds.handlerShell, NoDatabase: true,
+        Summary: "Run commands at an interactive prompt",
+        Description: "Keeps the database connection open between commands, with history, line editing and Tab completion.\n" +
+            "Quote arguments as in your shell; leave with exit, quit or Ctrl-D.",
+    })
+    cmds.register(commandSpec{
+        Name: "completion", Group: "Maintenance commands", Handler: handlerCompletion, NoDatabase: true,
+        Summary: "Print a shell completion script",
+        Description: "Load it in the current shell with:\n" +
+            "  bash:  source <(gator completion bash)\n" +
+            "  zsh:   source <(gator completion zsh)\n" +
+            "  fish:  gator completion fish | source\n" +
+            "Feed URLs, folders, tags and usernames are completed from the database.",
+        Args: []argSpec{{Name: "bash|zsh|fish", Complete: "bash,zsh,fish"}},
+    })
+    cmds.register(commandSpec{
+        Name: "__complete", Handler: cmds.handlerComplete, Hidden: true, RawArgs: true, NoDatabase: true,
+        Summary: "Print completions for the words typed so far (used by the completion scripts)",
+    })
+}
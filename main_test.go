@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Marcus-Gustafsson/gator/internal/config"
+	"github.com/Marcus-Gustafsson/gator/internal/memdb"
+	"github.com/Marcus-Gustafsson/gator/internal/output"
+)
+
+// testEnv runs gator commands against an in-memory store, with a home directory of its
+// own for the config file and a local server for the feeds and articles they fetch.
+type testEnv struct {
+	t     *testing.T
+	st    *state
+	cmds  *cmds
+	store *memdb.Store
+	feeds *httptest.Server
+}
+
+// newTestEnv returns a fresh environment. The feed server is stopped, and the log
+// output restored, when the test ends.
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("COLUMNS", "80")
+
+	previousLog := log.Writer()
+	log.SetOutput(testLogWriter{t})
+	t.Cleanup(func() { log.SetOutput(previousLog) })
+
+	feeds := httptest.NewServer(feedHandler())
+	t.Cleanup(feeds.Close)
+
+	store := memdb.New()
+	outPtr, _ := output.New("", os.Stdout)
+	env := &testEnv{
+		t:     t,
+		st:    &state{cfgPtr: &config.Config{DbURL: "memory"}, dbPtr: store, outPtr: outPtr},
+		cmds:  &cmds{Specs: make(map[string]*commandSpec)},
+		store: store,
+		feeds: feeds,
+	}
+	registerCommands(env.cmds)
+	return env
+}
+
+// testLogWriter sends log output to the test's log, shown for failing tests.
+type testLogWriter struct{ t *testing.T }
+
+func (w testLogWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// url returns the feed server URL of path.
+func (env *testEnv) url(path string) string {
+	return env.feeds.URL + path
+}
+
+// run runs one command line the way main does, --output included, and returns what it
+// printed to stdout.
+func (env *testEnv) run(args ...string) (string, error) {
+	env.t.Helper()
+	var out string
+	err := captureStdout(env.t, &out, func() error {
+		outputSpec, rest := "", args
+		if args[0] != "__complete" {
+			var err error
+			if outputSpec, rest, err = extractOutputFlag(args); err != nil {
+				return err
+			}
+		}
+		outPtr, err := output.New(outputSpec, os.Stdout)
+		if err != nil {
+			return err
+		}
+		env.st.outPtr = outPtr
+		return env.cmds.run(env.st, command{Name: rest[0], Args: rest[1:]})
+	})
+	return out, err
+}
+
+// mustRun is run for commands that are expected to succeed.
+func (env *testEnv) mustRun(args ...string) string {
+	env.t.Helper()
+	out, err := env.run(args...)
+	if err != nil {
+		env.t.Fatalf("gator %s: %v\noutput:\n%s", strings.Join(args, " "), err, out)
+	}
+	return out
+}
+
+// mustFail runs a command that is expected to fail and returns its error.
+func (env *testEnv) mustFail(args ...string) error {
+	env.t.Helper()
+	out, err := env.run(args...)
+	if err == nil {
+		env.t.Fatalf("gator %s succeeded, expected an error\noutput:\n%s", strings.Join(args, " "), out)
+	}
+	return err
+}
+
+// runJSON runs a listing command with --output json and decodes the result into v.
+func (env *testEnv) runJSON(v any, args ...string) {
+	env.t.Helper()
+	out := env.mustRun(append(args, "--output", "json")...)
+	if err := json.Unmarshal([]byte(out), v); err != nil {
+		env.t.Fatalf("gator %s: invalid JSON: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+// browse returns the posts browse lists for the arguments, as structured output.
+func (env *testEnv) browse(args ...string) []postItem {
+	env.t.Helper()
+	var posts []postItem
+	env.runJSON(&posts, append([]string{"browse"}, args...)...)
+	return posts
+}
+
+// postID returns the short ID of the post with the given title. Posts are looked up feed
+// by feed, so copies of a story that browse shows only once are found too.
+func (env *testEnv) postID(title string) string {
+	env.t.Helper()
+	feeds, err := env.st.dbPtr.GetFeeds(env.t.Context())
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	for _, feed := range feeds {
+		for _, post := range env.browse("100", "--all", "--hidden", "--feed", feed.Url) {
+			if post.Title == title {
+				return shortID(post.ID)
+			}
+		}
+	}
+	env.t.Fatalf("no post titled %q", title)
+	return ""
+}
+
+// scrapeAll fetches every feed once, oldest fetch first, like agg does over its first
+// few ticks.
+func (env *testEnv) scrapeAll() {
+	env.t.Helper()
+	feeds, err := env.st.dbPtr.GetFeeds(env.t.Context())
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	for range feeds {
+		scrapeFeeds(env.st)
+	}
+}
+
+// captureStdout runs fn with os.Stdout (and os.Stderr, which is dropped) redirected and
+// stores what fn printed in out.
+func captureStdout(t *testing.T, out *string, fn func() error) error {
+	t.Helper()
+	stdout, stderr := os.Stdout, os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&buf, r)
+		close(done)
+	}()
+
+	os.Stdout, os.Stderr = w, devNull
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	fnErr := fn()
+
+	w.Close()
+	<-done
+	r.Close()
+	*out = buf.String()
+	return fnErr
+}
+
+// assertContains fails the test unless out contains every one of wants.
+func assertContains(t *testing.T, out string, wants ...string) {
+	t.Helper()
+	for _, want := range wants {
+		if !strings.Contains(out, want) {
+			t.Errorf("output doesn't contain %q:\n%s", want, out)
+		}
+	}
+}
+
+// Feeds served by feedHandler. The Go release is in both feeds under the same link (once
+// with a tracking parameter), so the two copies are one story.
+const (
+	techFeedPath = "/tech.xml"
+	newsFeedPath = "/news.xml"
+)
+
+// feedHandler serves two RSS feeds and the article pages they link to.
+func feedHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(techFeedPath, func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprintf(w, `<?xml version="1.0"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel>
+<title>Tech</title>
+<link>%[1]s/</link>
+<description>Technology news</description>
+<item>
+  <title>Go 1.30 released</title>
+  <link>%[1]s/articles/go?utm_source=rss</link>
+  <description>&lt;p&gt;The Go team announces generic methods.&lt;/p&gt;</description>
+  <pubDate>Fri, 02 Jan 2026 10:00:00 +0000</pubDate>
+  <dc:creator>Gopher</dc:creator>
+  <category>golang</category>
+</item>
+<item>
+  <title>Rust borrow checker tips</title>
+  <link>%[1]s/articles/rust</link>
+  <description>&lt;p&gt;Lifetimes without tears.&lt;/p&gt;</description>
+  <pubDate>Sat, 03 Jan 2026 09:00:00 +0000</pubDate>
+  <category>rust</category>
+</item>
+<item>
+  <title>Undated musings</title>
+  <link>%[1]s/articles/undated</link>
+  <description>Thoughts on nothing in particular.</description>
+</item>
+</channel>
+</rss>`, base)
+	})
+	mux.HandleFunc(newsFeedPath, func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprintf(w, `<?xml version="1.0"?>
+<rss version="2.0">
+<channel>
+<title>News</title>
+<link>%[1]s/</link>
+<description>General news</description>
+<item>
+  <title>Go 1.30 is out</title>
+  <link>%[1]s/articles/go</link>
+  <description>A new Go release.</description>
+  <pubDate>Thu, 01 Jan 2026 12:00:00 +0000</pubDate>
+</item>
+</channel>
+</rss>`, base)
+	})
+	mux.HandleFunc("/articles/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<!DOCTYPE html>
+<html><head><title>%[1]s</title></head>
+<body>
+<nav><a href="/">Home</a></nav>
+<article>
+<h1>%[1]s</h1>
+<p>This is the full text of the %[1]s article. It goes on for a while so that the
+readability extractor recognises it as the main content of the page, with enough
+sentences, commas, and words to score well above the navigation and footer.</p>
+<p>A second paragraph adds more detail about %[1]s, because real articles rarely
+consist of a single paragraph, and the extractor prefers longer runs of text.</p>
+</article>
+<footer>Copyright</footer>
+</body></html>`, strings.TrimPrefix(r.URL.Path, "/articles/"))
+	})
+	return mux
+}
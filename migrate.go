@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+// schemaFS holds the goose migrations under sql/schema, and sqliteSchemaFS those for
+// SQLite under sql/sqlite/schema, so a gator binary can set up and upgrade its database
+// without the goose tool or the source tree.
+//
+//go:embed sql/schema/*.sql
+var schemaFS embed.FS
+
+//go:embed sql/sqlite/schema/*.sql
+var sqliteSchemaFS embed.FS
+
+// migrationProvider returns a goose provider for the embedded migrations of the
+// database's driver. Applied versions are recorded in goose's own goose_db_version
+// table, so databases migrated with the goose command line tool before are picked up
+// where they are.
+func migrationProvider(stPtr *state) (*goose.Provider, error) {
+	dialect, migrations, dir := goose.DialectPostgres, schemaFS, "sql/schema"
+	if stPtr.driver == driverSQLite {
+		dialect, migrations, dir = goose.DialectSQLite3, sqliteSchemaFS, "sql/sqlite/schema"
+	}
+	sub, err := fs.Sub(migrations, dir)
+	if err != nil {
+		return nil, err
+	}
+	return goose.NewProvider(dialect, stPtr.connPtr, sub)
+}
+
+// handlerMigrate applies or rolls back the embedded schema migrations. "up" (the
+// default) applies all pending migrations, "down" rolls back the latest one, "redo"
+// rolls back the latest one and applies it again, and "status" lists every migration
+// with the time it was applied. Each migration runs in its own transaction, so a
+// failing one leaves the schema at the previous version. Returns an error if a
+// migration fails.
+func handlerMigrate(stPtr *state, cmd command) error {
+	provider, err := migrationProvider(stPtr)
+	if err != nil {
+		return fmt.Errorf("handlerMigrate: couldn't load migrations: %w", err)
+	}
+	ctx := context.Background()
+
+	direction := "up"
+	if len(cmd.Args) == 1 {
+		direction = cmd.Args[0]
+	}
+
+	switch direction {
+	case "up":
+		results, err := provider.Up(ctx)
+		printMigrationResults(results)
+		if err != nil {
+			return fmt.Errorf("handlerMigrate: %w", err)
+		}
+		if len(results) == 0 {
+			fmt.Println("The database schema is up to date.")
+		}
+
+	case "down", "redo":
+		result, err := provider.Down(ctx)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			fmt.Println("No migrations to roll back.")
+			return nil
+		}
+		printMigrationResults([]*goose.MigrationResult{result})
+		if err != nil {
+			return fmt.Errorf("handlerMigrate: %w", err)
+		}
+		if direction == "redo" {
+			result, err = provider.UpByOne(ctx)
+			printMigrationResults([]*goose.MigrationResult{result})
+			if err != nil {
+				return fmt.Errorf("handlerMigrate: %w", err)
+			}
+		}
+
+	case "status":
+		statuses, err := provider.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("handlerMigrate: couldn't get migration status: %w", err)
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.State == goose.StateApplied {
+				applied = "applied " + status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-32s %s\n", path.Base(status.Source.Path), applied)
+		}
+
+	default:
+		return cmd.usageError("unknown direction %q, expected up, down, redo or status", direction)
+	}
+	return nil
+}
+
+// printMigrationResults prints one line per migration applied or rolled back.
+func printMigrationResults(results []*goose.MigrationResult) {
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		if result.Error != nil {
+			fmt.Printf("FAILED %s %s: %v\n", result.Direction, path.Base(result.Source.Path), result.Error)
+			continue
+		}
+		fmt.Printf("%s %s (%s)\n", result.Direction, path.Base(result.Source.Path), result.Duration.Round(time.Millisecond))
+	}
+}
+
+// errNoSchema is returned by checkSchema for a database no migrations were applied to.
+var errNoSchema = errors.New("the database has no gator schema yet; run 'gator migrate' to set it up")
+
+// versionTableQuery returns a query telling whether goose's goose_db_version table
+// exists, in the dialect of the database's driver.
+func versionTableQuery(stPtr *state) string {
+	if stPtr.driver == driverSQLite {
+		return "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'goose_db_version')"
+	}
+	return "SELECT to_regclass('goose_db_version') IS NOT NULL"
+}
+
+// checkSchema returns an error if the database hasn't had all the embedded migrations
+// applied, so commands don't run against tables or columns that don't exist yet.
+func checkSchema(stPtr *state) error {
+	if stPtr.connPtr == nil {
+		return nil // an in-memory store, as in the tests, has no schema to migrate
+	}
+	provider, err := migrationProvider(stPtr)
+	if err != nil {
+		return fmt.Errorf("couldn't load migrations: %w", err)
+	}
+
+	// GetVersions creates goose's version table when it's missing, so look for it first
+	// to keep this check from writing to a database that was never set up.
+	var exists bool
+	err = stPtr.connPtr.QueryRowContext(context.Background(), versionTableQuery(stPtr)).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("couldn't check the database schema version: %w", err)
+	}
+	if !exists {
+		return errNoSchema
+	}
+	current, target, err := provider.GetVersions(context.Background())
+	if err != nil {
+		return fmt.Errorf("couldn't check the database schema version: %w", err)
+	}
+	if current == 0 {
+		return errNoSchema
+	}
+	if current < target {
+		return fmt.Errorf("the database schema is at version %d but this gator needs version %d; run 'gator migrate' first", current, target)
+	}
+	return nil
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/Marcus-Gustafsson/gator/internal/urlnorm"
+	"github.com/google/uuid"
+)
+
+// normalizePostURL returns the canonical form of a post link using the tracking
+// parameters from the config (or urlnorm's defaults). Links that can't be normalised,
+// such as relative or non-http URLs, are returned unchanged.
+func (st *state) normalizePostURL(rawURL string) string {
+	trackingParams := st.cfgPtr.TrackingParams
+	if len(trackingParams) == 0 {
+		trackingParams = urlnorm.DefaultTrackingParams
+	}
+
+	normalized, err := urlnorm.Normalize(rawURL, trackingParams)
+	if err != nil {
+		return rawURL
+	}
+	return normalized
+}
+
+// handlerNormalizePosts is a one-off maintenance command that re-normalises the URLs of
+// all stored posts, e.g. after upgrading from a version that stored links verbatim or
+// after changing tracking_params in the config. Posts are visited oldest first; when a
+// post's normalised URL already belongs to another post of the same feed, the newer one
+// is merged into it by deleting it. Each post is updated or merged in its own
+// transaction, so a failure leaves it as it was. Prints how many posts were updated and
+// merged. Returns an error if reading or updating posts fails.
+func handlerNormalizePosts(stPtr *state, cmd command) error {
+	posts, err := stPtr.dbPtr.GetPostURLs(context.Background())
+	if err != nil {
+		return fmt.Errorf("handlerNormalizePosts: couldn't retrieve posts: %w", err)
+	}
+
+	updated, merged := 0, 0
+	for _, post := range posts {
+		// Normalise from the link as published, so a changed tracking_params list
+		// applies to the full original URL rather than an already-stripped one.
+		originalURL := post.Url
+		if post.OriginalUrl.Valid {
+			originalURL = post.OriginalUrl.String
+		}
+
+		normalized := stPtr.normalizePostURL(originalURL)
+		if normalized == post.Url {
+			continue
+		}
+
+		wasMerged := false
+		err := stPtr.withTx(context.Background(), func(txPtr *state) error {
+			existing, err := txPtr.dbPtr.GetFeedPostByURL(context.Background(), database.GetFeedPostByURLParams{
+				FeedID: post.FeedID,
+				Url:    normalized,
+			})
+			if err == nil && existing.ID != post.ID {
+				wasMerged = true
+				return mergePost(txPtr, post.ID, existing.ID)
+			}
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("couldn't look up post by URL: %w", err)
+			}
+
+			err = txPtr.dbPtr.UpdatePostURL(context.Background(), database.UpdatePostURLParams{
+				ID:          post.ID,
+				Url:         normalized,
+				OriginalUrl: sql.NullString{String: originalURL, Valid: true},
+				UpdatedAt:   time.Now().UTC(),
+			})
+			if err != nil {
+				return fmt.Errorf("couldn't update post %s: %w", post.ID, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("handlerNormalizePosts: %w", err)
+		}
+		if wasMerged {
+			merged++
+		} else {
+			updated++
+		}
+	}
+
+	fmt.Printf("Checked %d posts: %d URLs normalised, %d duplicates merged.\n", len(posts), updated, merged)
+	return nil
+}
+
+// mergePost moves users' read state, stars and tags from a duplicate post to the post
+// it duplicates, then deletes the duplicate. Run it in a transaction.
+func mergePost(txPtr *state, fromID, toID uuid.UUID) error {
+	// Keep users' read state when the duplicate disappears.
+	err := txPtr.dbPtr.MovePostReads(context.Background(), database.MovePostReadsParams{
+		FromPostID: fromID,
+		ToPostID:   toID,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't move read state of post %s: %w", fromID, err)
+	}
+	// Stars and tags too, or a saved article could vanish in the merge.
+	err = txPtr.dbPtr.MovePostStars(context.Background(), database.MovePostStarsParams{
+		FromPostID: fromID,
+		ToPostID:   toID,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't move stars of post %s: %w", fromID, err)
+	}
+	err = txPtr.dbPtr.MovePostTags(context.Background(), database.MovePostTagsParams{
+		FromPostID: fromID,
+		ToPostID:   toID,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't move tags of post %s: %w", fromID, err)
+	}
+	// And what users' rules did to it, so a hidden post doesn't reappear.
+	err = txPtr.dbPtr.MovePostHides(context.Background(), database.MovePostHidesParams{
+		FromPostID: fromID,
+		ToPostID:   toID,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't move hides of post %s: %w", fromID, err)
+	}
+	err = txPtr.dbPtr.MovePostHighlights(context.Background(), database.MovePostHighlightsParams{
+		FromPostID: fromID,
+		ToPostID:   toID,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't move highlights of post %s: %w", fromID, err)
+	}
+	if err := txPtr.dbPtr.DeletePost(context.Background(), fromID); err != nil {
+		return fmt.Errorf("couldn't merge post %s into %s: %w", fromID, toID, err)
+	}
+	return nil
+}
+
+// handlerRead marks one or more posts as read for the current user. It expects at least
+// one post ID, either in full or the short form shown by browse. Reading a post also
+// marks the other copies of the same story read. Returns an error if an ID is invalid,
+// unknown, or the update fails.
+func handlerRead(stPtr *state, cmd command, user database.User) error {
+	return setPostsRead(stPtr, cmd, user, true)
+}
+
+// handlerUnread marks one or more posts as unread again for the current user, so browse
+// shows them again. It expects at least one post ID. Returns an error if an ID is
+// invalid, unknown, or the update fails.
+func handlerUnread(stPtr *state, cmd command, user database.User) error {
+	return setPostsRead(stPtr, cmd, user, false)
+}
+
+// setPostsRead implements read and unread: it validates every ID before changing
+// anything, then marks each post (and its story) read or unread.
+func setPostsRead(stPtr *state, cmd command, user database.User, read bool) error {
+	var posts []database.FindUserPostsByIDRangeRow
+	for _, arg := range cmd.Args {
+		post, err := getPostArg(stPtr, user, arg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", cmd.Name, err)
+		}
+		posts = append(posts, post)
+	}
+
+	var err error
+	for _, post := range posts {
+		if read {
+			_, err = stPtr.dbPtr.MarkPostRead(context.Background(), database.MarkPostReadParams{
+				UserID: user.ID,
+				ReadAt: time.Now().UTC(),
+				PostID: post.ID,
+			})
+		} else {
+			_, err = stPtr.dbPtr.MarkPostUnread(context.Background(), database.MarkPostUnreadParams{
+				UserID: user.ID,
+				PostID: post.ID,
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("%s: couldn't update post %s: %w", cmd.Name, post.ID, err)
+		}
+
+		if read {
+			fmt.Printf("Marked read: %s\n", post.Title)
+		} else {
+			fmt.Printf("Marked unread: %s\n", post.Title)
+		}
+	}
+	return nil
+}
+
+// handlerMarkAllRead marks every post in the current user's followed feeds as read. The
+// optional --feed <url> flag limits it to one feed and --before <date> to posts
+// published before that date (YYYY-MM-DD or RFC 3339). Prints how many posts were
+// marked. Returns an error if flags are invalid, the feed is not found, or the update
+// fails.
+func handlerMarkAllRead(stPtr *state, cmd command, user database.User) error {
+	feedURL, before := cmd.stringFlag("feed"), cmd.stringFlag("before")
+
+	params := database.MarkAllPostsReadParams{
+		ReadAt: time.Now().UTC(),
+		UserID: user.ID,
+	}
+	if feedURL != "" {
+		feed, err := stPtr.dbPtr.GetFeedByURL(context.Background(), feedURL)
+		if err != nil {
+			return fmt.Errorf("handlerMarkAllRead: couldn't get feed: %w", err)
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	if before != "" {
+		beforeTime, err := stPtr.parseDate(before)
+		if err != nil {
+			return fmt.Errorf("handlerMarkAllRead: %w", err)
+		}
+		params.Before = sql.NullTime{Time: beforeTime.UTC(), Valid: true}
+	}
+
+	marked, err := stPtr.dbPtr.MarkAllPostsRead(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("handlerMarkAllRead: couldn't mark posts read: %w", err)
+	}
+
+	fmt.Printf("Marked %d posts as read.\n", marked)
+	return nil
+}
+
+// handlerStar saves a post for the current user. It expects exactly one post ID.
+// Starred posts are listed by the starred command and are never removed by retention
+// or pruning. Returns an error if the ID is invalid, unknown, or the update fails.
+func handlerStar(stPtr *state, cmd command, user database.User) error {
+	post, err := getPostArg(stPtr, user, cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("handlerStar: %w", err)
+	}
+
+	_, err = stPtr.dbPtr.StarPost(context.Background(), database.StarPostParams{
+		UserID:    user.ID,
+		PostID:    post.ID,
+		StarredAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("handlerStar: couldn't star post: %w", err)
+	}
+
+	fmt.Printf("Starred: %s\n", post.Title)
+	return nil
+}
+
+// handlerUnstar removes a post from the current user's starred posts. It expects
+// exactly one post ID. Returns an error if the ID is invalid, unknown, or the update
+// fails.
+func handlerUnstar(stPtr *state, cmd command, user database.User) error {
+	post, err := getPostArg(stPtr, user, cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("handlerUnstar: %w", err)
+	}
+
+	unstarred, err := stPtr.dbPtr.UnstarPost(context.Background(), database.UnstarPostParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("handlerUnstar: couldn't unstar post: %w", err)
+	}
+
+	if unstarred == 0 {
+		fmt.Printf("Post wasn't starred: %s\n", post.Title)
+		return nil
+	}
+	fmt.Printf("Unstarred: %s\n", post.Title)
+	return nil
+}
+
+// handlerStarred lists the current user's starred posts, most recently starred first,
+// with an optional limit (defaults to 20). Posts stay listed even after their feed is
+// unfollowed. Returns an error if the limit is invalid or retrieval fails.
+func handlerStarred(stPtr *state, cmd command, user database.User) error {
+	limit := 20
+	if len(cmd.Args) == 1 {
+		specifiedLimit, err := strconv.Atoi(cmd.Args[0])
+		if err != nil {
+			return cmd.usageError("invalid limit %q", cmd.Args[0])
+		}
+		limit = specifiedLimit
+	}
+	if limit <= 0 {
+		return cmd.usageError("limit must be positive")
+	}
+
+	posts, err := stPtr.dbPtr.GetStarredPostsForUser(context.Background(), database.GetStarredPostsForUserParams{
+		UserID: user.ID,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		return fmt.Errorf("handlerStarred: couldn't retrieve starred posts: %w", err)
+	}
+	dates, err := stPtr.dateDisplay(postDateLayout)
+	if err != nil {
+		return fmt.Errorf("handlerStarred: %w", err)
+	}
+
+	views := make([]postView, len(posts))
+	for i, post := range posts {
+		views[i] = postView{
+			ID:          post.ID,
+			PublishedAt: post.PublishedAt,
+			FeedName:    post.FeedName,
+			Title:       post.Title,
+			Description: post.Description,
+			Content:     post.Content,
+			BaseUrl:     post.BaseUrl,
+			Url:         post.Url,
+			IsStarred:   true,
+		}
+	}
+
+	return stPtr.outPtr.Print(postItems(views), func() {
+		fmt.Printf("Found %d starred posts for user %s:\n", len(posts), user.Name.String)
+		for _, view := range views {
+			printPost(view, dates)
+		}
+	})
+}
+
+// getPostArg resolves a post argument, either a full post ID or the short prefix shown
+// by browse, among the posts the user can see (followed feeds and starred posts). If the
+// prefix matches more than one post, the error lists the candidates so the user can
+// type a longer prefix.
+func getPostArg(stPtr *state, user database.User, arg string) (database.FindUserPostsByIDRangeRow, error) {
+	low, high, err := postIDRange(arg)
+	if err != nil {
+		return database.FindUserPostsByIDRangeRow{}, err
+	}
+
+	matches, err := stPtr.dbPtr.FindUserPostsByIDRange(context.Background(), database.FindUserPostsByIDRangeParams{
+		LowID:  low,
+		HighID: high,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return database.FindUserPostsByIDRangeRow{}, fmt.Errorf("couldn't look up post %s: %w", arg, err)
+	}
+
+	switch len(matches) {
+	case 0:
+		return database.FindUserPostsByIDRangeRow{}, fmt.Errorf("no post with ID %s in your feeds", arg)
+	case 1:
+		return matches[0], nil
+	}
+
+	var candidates strings.Builder
+	for _, match := range matches {
+		fmt.Fprintf(&candidates, "\n  %s  %s (%s)", match.ID, match.Title, match.FeedName)
+	}
+	return database.FindUserPostsByIDRangeRow{}, fmt.Errorf("post ID %s is ambiguous, it matches:%s", arg, candidates.String())
+}
@@ -0,0 +1,63 @@
+//go:build postgres
+
+package main
+
+import (
+	"database/sql"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// With the postgres build tag, the tests that loop over backends also run against the
+// PostgreSQL server at GATOR_TEST_DATABASE_URL, each in a schema of its own that is
+// dropped afterwards:
+//
+//	GATOR_TEST_DATABASE_URL="postgres://localhost/gator_test?sslmode=disable" go test -tags postgres ./...
+func init() {
+	backends = append(backends, "postgres")
+	usePostgres = func(t *testing.T, env *testEnv) {
+		t.Helper()
+		dbURL := os.Getenv("GATOR_TEST_DATABASE_URL")
+		if dbURL == "" {
+			t.Skip("GATOR_TEST_DATABASE_URL is not set")
+		}
+
+		adminPtr, err := sql.Open("postgres", dbURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { adminPtr.Close() })
+		schema := "gator_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+		if _, err := adminPtr.Exec("CREATE SCHEMA " + schema); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if _, err := adminPtr.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+				t.Errorf("couldn't drop schema %s: %v", schema, err)
+			}
+		})
+
+		u, err := url.Parse(dbURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		query := u.Query()
+		query.Set("search_path", schema)
+		u.RawQuery = query.Encode()
+
+		connPtr, db, driver, err := openDatabase(u.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { connPtr.Close() })
+		if driver != driverPostgres {
+			t.Fatalf("GATOR_TEST_DATABASE_URL opened a %s database", driver)
+		}
+		env.st.connPtr, env.st.dbPtr, env.st.driver = connPtr, db, driver
+		env.mustRun("migrate")
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerPrune deletes the posts that are past their feed's retention: beyond its most
+// recent posts to keep, or saved longer ago than its maximum age. Each feed's own
+// settings (see handlerRetention) override the defaults in the config file. Starred and
+// tagged posts are never deleted. With --dry-run the posts are listed instead of
+// deleted, and --feed limits pruning to one feed.
+func handlerPrune(stPtr *state, cmd command) error {
+	feedID := uuid.NullUUID{}
+	if feedURL := cmd.stringFlag("feed"); feedURL != "" {
+		feed, err := stPtr.dbPtr.GetFeedByURL(context.Background(), feedURL)
+		if err != nil {
+			return fmt.Errorf("handlerPrune: couldn't get feed: %w", err)
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	if cmd.boolFlag("dry-run") {
+		posts, err := findPrunablePosts(stPtr, feedID)
+		if err != nil {
+			return fmt.Errorf("handlerPrune: %w", err)
+		}
+		dates, err := stPtr.dateDisplay(time.DateOnly)
+		if err != nil {
+			return fmt.Errorf("handlerPrune: %w", err)
+		}
+		fmt.Printf("Would delete %d posts.\n", len(posts))
+		for i, post := range posts {
+			if i == 0 || post.FeedID != posts[i-1].FeedID {
+				fmt.Printf("%s:\n", post.FeedName)
+			}
+			fmt.Printf("    * %s (saved %s)\n", post.Title, dates.format(post.CreatedAt))
+		}
+		return nil
+	}
+
+	posts, err := prunePosts(stPtr, feedID)
+	if err != nil {
+		return fmt.Errorf("handlerPrune: %w", err)
+	}
+	fmt.Printf("Deleted %d posts.\n", len(posts))
+	for start := 0; start < len(posts); {
+		end := start + 1
+		for end < len(posts) && posts[end].FeedID == posts[start].FeedID {
+			end++
+		}
+		fmt.Printf("* %s: %d\n", posts[start].FeedName, end-start)
+		start = end
+	}
+	return nil
+}
+
+// findPrunablePosts returns the posts past their feed's retention, of one feed if feedID
+// is set, grouped by feed and newest first.
+func findPrunablePosts(stPtr *state, feedID uuid.NullUUID) ([]database.GetPrunablePostsRow, error) {
+	defaultPosts, defaultSeconds, err := stPtr.defaultRetention()
+	if err != nil {
+		return nil, err
+	}
+	posts, err := stPtr.dbPtr.GetPrunablePosts(context.Background(), database.GetPrunablePostsParams{
+		Now:            time.Now().UTC(),
+		DefaultPosts:   defaultPosts,
+		DefaultSeconds: defaultSeconds,
+		FeedID:         feedID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't find posts to prune: %w", err)
+	}
+	return posts, nil
+}
+
+// prunePosts deletes the posts findPrunablePosts returns, in one transaction, and
+// returns them. Their URLs are remembered so the next fetch doesn't save them again.
+func prunePosts(stPtr *state, feedID uuid.NullUUID) ([]database.GetPrunablePostsRow, error) {
+	var posts []database.GetPrunablePostsRow
+	err := stPtr.withTx(context.Background(), func(txPtr *state) error {
+		var err error
+		posts, err = findPrunablePosts(txPtr, feedID)
+		if err != nil {
+			return err
+		}
+		for _, post := range posts {
+			err := txPtr.dbPtr.CreatePrunedPost(context.Background(), database.CreatePrunedPostParams{
+				FeedID:   post.FeedID,
+				Url:      post.Url,
+				PrunedAt: time.Now().UTC(),
+			})
+			if err != nil {
+				return fmt.Errorf("couldn't remember pruned post %s: %w", post.ID, err)
+			}
+			if err := txPtr.dbPtr.DeletePost(context.Background(), post.ID); err != nil {
+				return fmt.Errorf("couldn't delete post %s: %w", post.ID, err)
+			}
+		}
+		return nil
+	})
+	return posts, err
+}
+
+// pruneAfterCycle runs prunePosts for agg --prune, logging the outcome.
+func pruneAfterCycle(stPtr *state) {
+	posts, err := prunePosts(stPtr, uuid.NullUUID{})
+	if err != nil {
+		log.Printf("pruneAfterCycle: %v", err)
+		return
+	}
+	if len(posts) > 0 {
+		log.Printf("pruneAfterCycle: deleted %d posts", len(posts))
+	}
+}
+
+// defaultRetention returns the config file's retain_posts and retain_for as the
+// defaults of GetPrunablePosts.
+func (stPtr *state) defaultRetention() (int32, int64, error) {
+	var seconds int64
+	if stPtr.cfgPtr.RetainFor != "" {
+		age, err := parseRetentionAge(stPtr.cfgPtr.RetainFor)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid retain_for in the config file: %w", err)
+		}
+		seconds = int64(age / time.Second)
+	}
+	if stPtr.cfgPtr.RetainPosts < 0 {
+		return 0, 0, fmt.Errorf("invalid retain_posts in the config file: %d is negative", stPtr.cfgPtr.RetainPosts)
+	}
+	return int32(stPtr.cfgPtr.RetainPosts), seconds, nil
+}
+
+// parseRetentionAge parses a duration such as "720h", also accepting a number of days
+// such as "90d", which time.ParseDuration doesn't.
+func parseRetentionAge(s string) (time.Duration, error) {
+	var age time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		age = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if age, err = time.ParseDuration(s); err != nil {
+			return 0, err
+		}
+	}
+	if age < time.Second {
+		return 0, fmt.Errorf("duration %q must be at least a second", s)
+	}
+	return age, nil
+}
+
+// formatRetentionAge formats seconds the way parseRetentionAge reads them, in days when
+// they are whole days.
+func formatRetentionAge(seconds int64) string {
+	if seconds%(24*60*60) == 0 {
+		return fmt.Sprintf("%dd", seconds/(24*60*60))
+	}
+	return (time.Duration(seconds) * time.Second).String()
+}
+
+// handlerRetention shows or changes a feed's retention settings, which replace the
+// config file's defaults for it: --keep <n> keeps the feed's n most recently saved
+// posts, --max-age <duration> the posts saved within that time. Either accepts "none"
+// for no limit and "default" to go back to the config file's setting. Since pruning
+// deletes posts for every follower, only the user who added the feed can change them.
+// Without flags the current settings are printed.
+func handlerRetention(stPtr *state, cmd command, user database.User) error {
+	feed, err := stPtr.dbPtr.GetFeedByURL(context.Background(), cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("handlerRetention: couldn't get feed: %w", err)
+	}
+
+	if keep, maxAge := cmd.stringFlag("keep"), cmd.stringFlag("max-age"); keep != "" || maxAge != "" {
+		if !feed.UserID.Valid || feed.UserID.UUID != user.ID {
+			return fmt.Errorf("handlerRetention: only the user who added %s can change its retention", feed.Name)
+		}
+		params := database.SetFeedRetentionParams{
+			ID:            feed.ID,
+			RetainPosts:   feed.RetainPosts,
+			RetainSeconds: feed.RetainSeconds,
+			UpdatedAt:     time.Now().UTC(),
+		}
+		switch keep {
+		case "":
+		case "default":
+			params.RetainPosts = sql.NullInt32{}
+		case "none":
+			params.RetainPosts = sql.NullInt32{Int32: 0, Valid: true}
+		default:
+			n, err := strconv.ParseInt(keep, 10, 32)
+			if err != nil || n < 1 {
+				return cmd.usageError("--keep expects a positive number, none or default, got %q", keep)
+			}
+			params.RetainPosts = sql.NullInt32{Int32: int32(n), Valid: true}
+		}
+		switch maxAge {
+		case "":
+		case "default":
+			params.RetainSeconds = sql.NullInt64{}
+		case "none":
+			params.RetainSeconds = sql.NullInt64{Int64: 0, Valid: true}
+		default:
+			age, err := parseRetentionAge(maxAge)
+			if err != nil {
+				return cmd.usageError("--max-age expects a duration such as 720h or 90d, none or default: %v", err)
+			}
+			params.RetainSeconds = sql.NullInt64{Int64: int64(age / time.Second), Valid: true}
+		}
+
+		feed, err = stPtr.dbPtr.SetFeedRetention(context.Background(), params)
+		if err != nil {
+			return fmt.Errorf("handlerRetention: couldn't update feed: %w", err)
+		}
+	}
+
+	defaultPosts, defaultSeconds, err := stPtr.defaultRetention()
+	if err != nil {
+		return fmt.Errorf("handlerRetention: %w", err)
+	}
+	describe := func(value int64, set bool, defaultValue int64, format func(int64) string) string {
+		limit := func(value int64) string {
+			if value == 0 {
+				return "no limit"
+			}
+			return format(value)
+		}
+		if !set {
+			return "default (" + limit(defaultValue) + ")"
+		}
+		return limit(value)
+	}
+	fmt.Printf("Retention for %s:\n", feed.Name)
+	fmt.Printf("* Keep posts:    %s\n", describe(int64(feed.RetainPosts.Int32), feed.RetainPosts.Valid, int64(defaultPosts),
+		func(n int64) string { return strconv.FormatInt(n, 10) }))
+	fmt.Printf("* Max age:       %s\n", describe(feed.RetainSeconds.Int64, feed.RetainSeconds.Valid, defaultSeconds, formatRetentionAge))
+	return nil
+}
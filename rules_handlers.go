@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/Marcus-Gustafsson/gator/internal/render"
+	"github.com/Marcus-Gustafsson/gator/internal/rules"
+	"github.com/google/uuid"
+)
+
+// feedRule is a user's rule compiled for matching posts of one feed.
+type feedRule struct {
+	rule      database.Rule
+	expr      rules.Expr
+	feedTitle sql.NullString // the user's own title for the feed
+}
+
+// loadFeedRules compiles the rules of every user following the feed. Rules that no
+// longer parse are logged and skipped rather than stopping ingestion; an error is
+// returned only if the rules can't be read.
+func loadFeedRules(stPtr *state, feed database.Feed) ([]feedRule, error) {
+	rows, err := stPtr.dbPtr.GetRulesForFeed(context.Background(), feed.ID)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get rules: %w", err)
+	}
+
+	var compiled []feedRule
+	for _, row := range rows {
+		expr, err := rules.Parse(row.Expression)
+		if err != nil {
+			log.Printf("loadFeedRules: skipping rule %s: %v", row.ID, err)
+			continue
+		}
+		compiled = append(compiled, feedRule{
+			rule: database.Rule{
+				ID:         row.ID,
+				CreatedAt:  row.CreatedAt,
+				UserID:     row.UserID,
+				Expression: row.Expression,
+				Action:     row.Action,
+				Tag:        row.Tag,
+			},
+			expr:      expr,
+			feedTitle: row.FeedTitle,
+		})
+	}
+	return compiled, nil
+}
+
+// applyFeedRules runs the feed's rules against a newly ingested post. It runs inside
+// the ingest transaction, where a failed statement aborts the transaction on
+// PostgreSQL, so the first failing action is returned rather than skipped.
+func applyFeedRules(stPtr *state, feedRules []feedRule, feed database.Feed, post database.Post) error {
+	if len(feedRules) == 0 {
+		return nil
+	}
+	description := render.PlainText(post.Description.String)
+	for _, fr := range feedRules {
+		matchPost := rules.Post{
+			Feed:        feedNames(feed.Name, feed.Url, fr.feedTitle),
+			Title:       post.Title,
+			Description: description,
+			Author:      post.Author.String,
+			Categories:  post.Categories,
+		}
+		if !fr.expr.Match(matchPost) {
+			continue
+		}
+		if _, err := applyRule(stPtr, fr.rule, post.ID); err != nil {
+			return fmt.Errorf("couldn't apply rule %s: %w", fr.rule.ID, err)
+		}
+	}
+	return nil
+}
+
+// feedNames lists the names a feed expression can match: the feed's name, its URL and
+// the user's own title for it.
+func feedNames(name, url string, title sql.NullString) []string {
+	names := []string{name, url}
+	if title.Valid {
+		names = append(names, title.String)
+	}
+	return names
+}
+
+// applyRule performs the rule's action on a post for the rule's owner and reports
+// whether anything changed (false if e.g. the post was already starred).
+func applyRule(stPtr *state, rule database.Rule, postID uuid.UUID) (bool, error) {
+	var changed int64
+	var err error
+	switch rule.Action {
+	case "hide":
+		changed, err = stPtr.dbPtr.HidePost(context.Background(), database.HidePostParams{
+			UserID:   rule.UserID,
+			PostID:   postID,
+			HiddenAt: time.Now().UTC(),
+		})
+	case "read":
+		changed, err = stPtr.dbPtr.MarkPostRead(context.Background(), database.MarkPostReadParams{
+			UserID: rule.UserID,
+			ReadAt: time.Now().UTC(),
+			PostID: postID,
+		})
+	case "star":
+		changed, err = stPtr.dbPtr.StarPost(context.Background(), database.StarPostParams{
+			UserID:    rule.UserID,
+			PostID:    postID,
+			StarredAt: time.Now().UTC(),
+		})
+	case "highlight":
+		changed, err = stPtr.dbPtr.HighlightPost(context.Background(), database.HighlightPostParams{
+			UserID:        rule.UserID,
+			PostID:        postID,
+			HighlightedAt: time.Now().UTC(),
+		})
+	case "tag":
+		return stPtr.tagPost(rule.UserID, postID, rule.Tag.String)
+	default:
+		return false, fmt.Errorf("unknown action %q", rule.Action)
+	}
+	return changed > 0, err
+}
+
+// ruleItem is a rule as listed by rules list in structured output.
+type ruleItem struct {
+	ID         uuid.UUID `json:"id"`
+	Action     string    `json:"action"`
+	Expression string    `json:"expression"`
+}
+
+// ruleAction returns the action as written in rules add, e.g. "tag:weekly".
+func ruleAction(rule database.Rule) string {
+	if rule.Action == "tag" {
+		return "tag:" + rule.Tag.String
+	}
+	return rule.Action
+}
+
+// handlerRules manages the current user's rules. "add" creates a rule from an action
+// and a match expression (see internal/rules for the syntax), "list" shows the rules,
+// "delete" removes one by the ID prefix shown in the list, and "apply" runs all rules
+// against the posts already stored for the feeds the user follows. New posts are
+// matched against the rules as they are ingested. Returns an error if the subcommand,
+// action or expression is invalid or a database operation fails.
+func handlerRules(stPtr *state, cmd command, user database.User) error {
+	switch args := cmd.Args[1:]; cmd.Args[0] {
+	case "add":
+		if len(args) < 2 {
+			return cmd.usageError("add takes an action (hide, read, star, highlight or tag:<name>) and an expression")
+		}
+		action, tag := args[0], sql.NullString{}
+		if name, ok := strings.CutPrefix(action, "tag:"); ok {
+			action, tag = "tag", sql.NullString{String: normalizeTag(name), Valid: true}
+			if tag.String == "" {
+				return fmt.Errorf("handlerRules: missing tag name in %q", args[0])
+			}
+		}
+		if action != "hide" && action != "read" && action != "star" && action != "highlight" && action != "tag" {
+			return cmd.usageError("unknown action %q, expected hide, read, star, highlight or tag:<name>", args[0])
+		}
+		expression := strings.Join(args[1:], " ")
+		if _, err := rules.Parse(expression); err != nil {
+			return fmt.Errorf("handlerRules: %w", err)
+		}
+
+		rule, err := stPtr.dbPtr.CreateRule(context.Background(), database.CreateRuleParams{
+			ID:         uuid.New(),
+			CreatedAt:  time.Now().UTC(),
+			UserID:     user.ID,
+			Expression: expression,
+			Action:     action,
+			Tag:        tag,
+		})
+		if err != nil {
+			return fmt.Errorf("handlerRules: couldn't create rule: %w", err)
+		}
+		fmt.Printf("Rule [%s] added: %s %s\n", shortID(rule.ID), ruleAction(rule), rule.Expression)
+		fmt.Println("It applies to new posts; run \"rules apply\" to apply it to stored ones.")
+
+	case "list":
+		userRules, err := stPtr.dbPtr.GetRulesForUser(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("handlerRules: couldn't retrieve rules: %w", err)
+		}
+		items := make([]ruleItem, len(userRules))
+		for i, rule := range userRules {
+			items[i] = ruleItem{ID: rule.ID, Action: ruleAction(rule), Expression: rule.Expression}
+		}
+		return stPtr.outPtr.Print(items, func() {
+			if len(userRules) == 0 {
+				fmt.Println("No rules found for this user.")
+				return
+			}
+			fmt.Printf("Rules for user %s:\n", user.Name.String)
+			for _, rule := range userRules {
+				fmt.Printf("[%s] %s %s\n", shortID(rule.ID), ruleAction(rule), rule.Expression)
+			}
+		})
+
+	case "delete":
+		if len(args) != 1 {
+			return cmd.usageError("delete takes a rule ID")
+		}
+		digits, err := idPrefixDigits("rule", args[0])
+		if err != nil {
+			return fmt.Errorf("handlerRules: %w", err)
+		}
+		userRules, err := stPtr.dbPtr.GetRulesForUser(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("handlerRules: couldn't retrieve rules: %w", err)
+		}
+		var matches []database.Rule
+		for _, rule := range userRules {
+			if strings.HasPrefix(strings.ReplaceAll(rule.ID.String(), "-", ""), digits) {
+				matches = append(matches, rule)
+			}
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("handlerRules: no rule with ID %s, see \"rules list\"", args[0])
+		}
+		if len(matches) > 1 {
+			var candidates strings.Builder
+			for _, match := range matches {
+				fmt.Fprintf(&candidates, "\n  %s  %s %s", match.ID, ruleAction(match), match.Expression)
+			}
+			return fmt.Errorf("handlerRules: rule ID %s is ambiguous, it matches:%s", args[0], candidates.String())
+		}
+		if _, err := stPtr.dbPtr.DeleteRule(context.Background(), database.DeleteRuleParams{
+			ID:     matches[0].ID,
+			UserID: user.ID,
+		}); err != nil {
+			return fmt.Errorf("handlerRules: couldn't delete rule: %w", err)
+		}
+		fmt.Printf("Rule [%s] deleted.\n", shortID(matches[0].ID))
+
+	case "apply":
+		if len(args) != 0 {
+			return cmd.usageError("apply takes no arguments")
+		}
+		return applyRulesToStoredPosts(stPtr, user)
+
+	default:
+		return cmd.usageError("unknown subcommand %q, expected add, list, delete or apply", cmd.Args[0])
+	}
+	return nil
+}
+
+// applyRulesToStoredPosts runs each of the user's rules against every stored post of
+// the feeds they follow and prints how many posts each rule matched and changed.
+func applyRulesToStoredPosts(stPtr *state, user database.User) error {
+	userRules, err := stPtr.dbPtr.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("handlerRules: couldn't retrieve rules: %w", err)
+	}
+	if len(userRules) == 0 {
+		fmt.Println("No rules found for this user.")
+		return nil
+	}
+	exprs := make([]rules.Expr, len(userRules))
+	for i, rule := range userRules {
+		if exprs[i], err = rules.Parse(rule.Expression); err != nil {
+			return fmt.Errorf("handlerRules: rule [%s]: %w", shortID(rule.ID), err)
+		}
+	}
+
+	posts, err := stPtr.dbPtr.GetRuleCandidatePosts(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("handlerRules: couldn't retrieve posts: %w", err)
+	}
+
+	matched := make([]int, len(userRules))
+	changed := make([]int, len(userRules))
+	for _, post := range posts {
+		matchPost := rules.Post{
+			Feed:        feedNames(post.FeedName, post.FeedUrl, post.FeedTitle),
+			Title:       post.Title,
+			Description: render.PlainText(post.Description.String),
+			Author:      post.Author.String,
+			Categories:  post.Categories,
+		}
+		for i, rule := range userRules {
+			if !exprs[i].Match(matchPost) {
+				continue
+			}
+			matched[i]++
+			ok, err := applyRule(stPtr, rule, post.ID)
+			if err != nil {
+				return fmt.Errorf("handlerRules: rule [%s] on post %s: %w", shortID(rule.ID), post.ID, err)
+			}
+			if ok {
+				changed[i]++
+			}
+		}
+	}
+
+	fmt.Printf("Checked %d posts:\n", len(posts))
+	for i, rule := range userRules {
+		fmt.Printf("[%s] %s %s: %d matched, %d changed\n",
+			shortID(rule.ID), ruleAction(rule), rule.Expression, matched[i], changed[i])
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerSearch runs a full-text search over post titles, summaries and article text and
+// prints the best matches with a highlighted snippet. The query accepts "quoted phrases",
+// OR and -excluded words; several arguments are joined into one query. Only the feeds the
+// user follows are searched unless --all-feeds is given.
+func handlerSearch(stPtr *state, cmd command, user database.User) error {
+	limit := cmd.intFlag("limit")
+	if limit <= 0 {
+		return cmd.usageError("limit must be positive")
+	}
+	query := strings.Join(cmd.Args, " ")
+
+	results, err := stPtr.dbPtr.SearchPosts(context.Background(), database.SearchPostsParams{
+		Query:    query,
+		UserID:   user.ID,
+		AllFeeds: cmd.boolFlag("all-feeds"),
+		Limit:    int32(limit),
+	})
+	if err != nil {
+		return fmt.Errorf("handlerSearch: couldn't search posts: %w", err)
+	}
+	dates, err := stPtr.dateDisplay(postDateLayout)
+	if err != nil {
+		return fmt.Errorf("handlerSearch: %w", err)
+	}
+
+	items := make([]searchItem, len(results))
+	for i, result := range results {
+		items[i] = searchItem{
+			ID:          result.ID,
+			PublishedAt: nullTime(result.PublishedAt),
+			Feed:        result.FeedName,
+			Title:       result.Title,
+			URL:         result.Url,
+			Rank:        result.Rank,
+			Snippet:     highlightSnippet(result.Snippet, "", ""),
+		}
+	}
+
+	return stPtr.outPtr.Print(items, func() {
+		start, stop := "*", "*"
+		if info, err := os.Stdout.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			start, stop = "\x1b[1m", "\x1b[0m"
+		}
+		fmt.Printf("Found %d posts matching %q:\n", len(results), query)
+		for _, result := range results {
+			fmt.Printf("[%s] %s from %s\n", shortID(result.ID), dates.formatNull(result.PublishedAt), result.FeedName)
+			fmt.Printf("--- %s ---\n", result.Title)
+			fmt.Printf("%s\n", indent(highlightSnippet(result.Snippet, start, stop), "    "))
+			fmt.Printf("Link: %s\n", result.Url)
+			fmt.Println("=====================================")
+		}
+	})
+}
+
+// searchItem is a search result in structured output.
+type searchItem struct {
+	ID          uuid.UUID  `json:"id"`
+	PublishedAt *time.Time `json:"published_at"`
+	Feed        string     `json:"feed"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Rank        float32    `json:"rank"`
+	Snippet     string     `json:"snippet"`
+}
+
+// highlightSnippet replaces the <b></b> markers SearchPosts puts around matches with
+// start and stop (e.g. bold on and off) and collapses the whitespace left behind by
+// stripped markup.
+func highlightSnippet(snippet, start, stop string) string {
+	snippet = strings.ReplaceAll(snippet, "<b>", start)
+	snippet = strings.ReplaceAll(snippet, "</b>", stop)
+	return html.UnescapeString(strings.Join(strings.Fields(snippet), " "))
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Marcus-Gustafsson/gator/internal/output"
+	"github.com/chzyer/readline"
+)
+
+// shellHistoryFile is where the shell keeps its history, in the user's home directory
+// next to the config file.
+const shellHistoryFile = ".gator_history"
+
+// handlerShell runs an interactive prompt that reads commands line by line and runs
+// them through the same dispatcher as the command line, keeping the config and database
+// connection open in between. Lines are split into words like a POSIX shell would
+// ('single' and "double" quotes, backslash escapes), so search queries and rule
+// expressions can be quoted as usual. A line may carry its own --output flag. History
+// is kept across sessions, Tab completes like the shell completion scripts, and "exit",
+// "quit" or Ctrl-D leave the shell. A failing command prints its error and the prompt
+// comes back.
+func (cmdsPtr *cmds) handlerShell(stPtr *state, cmd command) error {
+	historyFile := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		historyFile = filepath.Join(home, shellHistoryFile)
+	}
+
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          "gator> ",
+		HistoryFile:     historyFile,
+		AutoComplete:    shellCompleter{cmds: cmdsPtr, st: stPtr},
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+	})
+	if err != nil {
+		return fmt.Errorf("handlerShell: couldn't start the prompt: %w", err)
+	}
+	defer rl.Close()
+
+	fmt.Println(`Type a command, "help" for the list, or "exit" to leave.`)
+	for {
+		line, err := rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("handlerShell: couldn't read the command: %w", err)
+		}
+
+		words, err := splitWords(line)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		if len(words) == 0 {
+			continue
+		}
+		if words[0] == "exit" || words[0] == "quit" {
+			return nil
+		}
+		if words[0] == cmd.Name {
+			fmt.Fprintln(os.Stderr, "already in the shell")
+			continue
+		}
+
+		if err := cmdsPtr.runShellLine(stPtr, words); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+// runShellLine runs one line of the shell, applying a --output flag given on it to
+// that command only.
+func (cmdsPtr *cmds) runShellLine(stPtr *state, words []string) error {
+	outputSpec, args, err := extractOutputFlag(words)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New("missing command")
+	}
+	if outputSpec != "" {
+		outPtr, err := output.New(outputSpec, os.Stdout)
+		if err != nil {
+			return err
+		}
+		defer func(previous *output.Printer) { stPtr.outPtr = previous }(stPtr.outPtr)
+		stPtr.outPtr = outPtr
+	}
+	return cmdsPtr.run(stPtr, command{Name: args[0], Args: args[1:]})
+}
+
+// splitWords splits a line into words at unquoted whitespace. Single quotes keep
+// everything up to the next single quote literally; double quotes and unquoted text
+// treat a backslash as escaping the next character. An unterminated quote is an error.
+func splitWords(line string) ([]string, error) {
+	line = strings.TrimRight(line, " \t")
+	if line == "" {
+		return nil, nil
+	}
+	words, open := scanWords(line)
+	if open != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", open)
+	}
+	return words, nil
+}
+
+// scanWords does the splitting for splitWords and the completer. It returns the quote
+// character still open at the end of the line, or 0. A line ending in whitespace (and
+// not inside quotes) gets a final empty word, which is the word being completed.
+func scanWords(line string) (words []string, open rune) {
+	var word strings.Builder
+	inWord, escaped := false, false
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case open == '\'':
+			if r == '\'' {
+				open = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case open == '"':
+			if r == '"' {
+				open = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			open, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord || open != 0 || len(words) == 0 || strings.HasSuffix(line, " ") || strings.HasSuffix(line, "\t") {
+		words = append(words, word.String())
+	}
+	return words, open
+}
+
+// shellCompleter completes the shell's input with the same candidates as the shell
+// completion scripts.
+type shellCompleter struct {
+	cmds *cmds
+	st   *state
+}
+
+// Do implements readline.AutoCompleter: it returns the endings of the candidates for
+// the word before the cursor, and that word's length.
+func (c shellCompleter) Do(line []rune, pos int) ([][]rune, int) {
+	typed := string(line[:pos])
+	words, _ := scanWords(typed)
+	current := words[len(words)-1]
+	if !strings.HasSuffix(typed, current) {
+		return nil, 0 // the word is quoted or escaped; leave it alone
+	}
+
+	var endings [][]rune
+	for _, candidate := range c.cmds.complete(c.st, words[:len(words)-1], current) {
+		if ending, ok := strings.CutPrefix(candidate.Value, current); ok {
+			if !strings.HasSuffix(candidate.Value, "=") {
+				ending += " "
+			}
+			endings = append(endings, []rune(ending))
+		}
+	}
+	return endings, len([]rune(current))
+}
@@ -3,8 +3,8 @@
 -- Uses a CTE (Common Table Expression) to first insert a new row and save it as a special variable/name, then join with related tables
 -- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
-    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
-    VALUES ($1, $2, $3, $4, $5)
+    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, folder_id)
+    VALUES ($1, $2, $3, $4, $5, $6)
     RETURNING *
 )
 SELECT
@@ -16,14 +16,39 @@ INNER JOIN feeds ON inserted_feed_follow.feed_id = feeds.id
 INNER JOIN users ON inserted_feed_follow.user_id = users.id;
 --
 
+-- feed_name is the user's own title for the feed when they set one. Ordered by folder
+-- (feeds outside any folder first), then priority (highest first) and name.
 -- name: GetFeedFollowsForUser :many
-SELECT feed_follows.*, feeds.name AS feed_name, users.name AS user_name
+SELECT feed_follows.*, COALESCE(feed_follows.title, feeds.name)::TEXT AS feed_name,
+    users.name AS user_name, folders.name AS folder_name
 FROM feed_follows
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
 INNER JOIN users ON feed_follows.user_id = users.id
-WHERE feed_follows.user_id = $1;
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = $1
+ORDER BY folders.name NULLS FIRST, feed_follows.priority DESC, feed_name;
 --
 
 -- name: DeleteFeedFollow :exec
 DELETE FROM feed_follows WHERE feed_id = $1 AND user_id = $2;
---
\ No newline at end of file
+--
+
+-- folder_id NULL takes the feed out of its folder.
+-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder_id = sqlc.narg(folder_id), updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(user_id) AND feed_id = sqlc.arg(feed_id);
+--
+
+-- Changes the user's settings for a followed feed. NULL arguments keep the current value;
+-- clear_title removes the custom title so the feed's own name is used again.
+-- name: UpdateFeedFollowSettings :one
+UPDATE feed_follows
+SET title = CASE WHEN sqlc.arg(clear_title)::boolean THEN NULL ELSE COALESCE(sqlc.narg(title), title) END,
+    muted = COALESCE(sqlc.narg(muted), muted),
+    notify = COALESCE(sqlc.narg(notify), notify),
+    priority = COALESCE(sqlc.narg(priority), priority),
+    updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(user_id) AND feed_id = sqlc.arg(feed_id)
+RETURNING *;
+--
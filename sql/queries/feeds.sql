@@ -19,12 +19,40 @@ WHERE url = $1;
 
 -- name: MarkFeedFetched :one
 UPDATE feeds
-SET last_fetched_at = NOW(),
-updated_at = NOW()
-WHERE id = $1
+SET last_fetched_at = sqlc.arg(now)::timestamptz,
+updated_at = sqlc.arg(now)::timestamptz
+WHERE id = sqlc.arg(id)
 RETURNING *;
 
 -- name: GetNextFeedToFetch :one
 SELECT * FROM feeds
 ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+LIMIT 1;
+
+-- name: GetFeedByID :one
+SELECT * FROM feeds
+WHERE id = $1;
+
+-- Same as GetNextFeedToFetch, but skips feeds that currently receive WebSub pushes.
+-- name: GetNextPolledFeedToFetch :one
+SELECT feeds.* FROM feeds
+LEFT JOIN websub_subscriptions ON websub_subscriptions.feed_id = feeds.id
+    AND websub_subscriptions.lease_expires_at > sqlc.arg(now)::timestamptz
+WHERE websub_subscriptions.id IS NULL
+ORDER BY feeds.last_fetched_at ASC NULLS FIRST
+LIMIT 1;
+
+-- name: SetFeedFetchFullContent :one
+UPDATE feeds
+SET fetch_full_content = $2,
+updated_at = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: SetFeedRetention :one
+UPDATE feeds
+SET retain_posts = $2,
+retain_seconds = $3,
+updated_at = $4
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,37 @@
+-- Marks a post read together with the other copies of its story, since browse shows a
+-- story only once.
+-- name: MarkPostRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT sqlc.arg(user_id)::uuid, posts.id, sqlc.arg(read_at)::timestamptz FROM posts
+WHERE posts.story_id = (SELECT story.story_id FROM posts AS story WHERE story.id = sqlc.arg(post_id)::uuid)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+--
+
+-- name: MarkPostUnread :execrows
+DELETE FROM post_reads
+WHERE post_reads.user_id = sqlc.arg(user_id)::uuid
+AND post_reads.post_id IN (
+    SELECT posts.id FROM posts
+    WHERE posts.story_id = (SELECT story.story_id FROM posts AS story WHERE story.id = sqlc.arg(post_id)::uuid)
+);
+--
+
+-- Marks every post in the user's followed feeds read, optionally only for one feed and/or
+-- only posts published (or, lacking a date, fetched) before a cutoff.
+-- name: MarkAllPostsRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, sqlc.arg(read_at)::timestamptz FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)::uuid
+AND (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id)::uuid)
+AND (sqlc.narg(before)::timestamptz IS NULL OR COALESCE(posts.published_at, posts.created_at) < sqlc.narg(before)::timestamptz)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+--
+
+-- Copies read state from a post that is about to be merged into another one.
+-- name: MovePostReads :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT post_reads.user_id, sqlc.arg(to_post_id)::uuid, post_reads.read_at FROM post_reads
+WHERE post_reads.post_id = sqlc.arg(from_post_id)::uuid
+ON CONFLICT (user_id, post_id) DO NOTHING;
+--
@@ -1,14 +1,199 @@
+-- Inserts nothing, and so returns no row, when the feed already has a post with the URL.
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, base_url, original_url, fingerprint, story_id, author, categories)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+ON CONFLICT (feed_id, url) DO NOTHING
 RETURNING *;
 --
 
+-- Returns one row per story: when the same story arrived through several followed feeds,
+-- the earliest published copy is shown and the other feeds are listed in also_in. Feeds
+-- are named by the user's own title when they set one, and muted feeds are left out
+-- unless they are asked for with feed_id, as are posts a rule hid unless show_hidden.
+-- Optional filters narrow the stories by read/starred state, tag, feed, folder and date
+-- range (the publication date, or the fetch date for posts without one). A story is read
+-- once any copy of it is, so a copy arriving later through another feed isn't new again.
+--
+-- Rows are ordered by (sort_group ASC, sort_time DESC, id DESC): sort_group is the feed
+-- name when sorting by feed and '' otherwise, sort_time the publication or fetch time
+-- with missing dates mapped to year 1 so they come last. Passing the last row's keys as
+-- the cursor continues after it, which stays stable while new posts arrive.
 -- name: GetPostsForUser :many
+WITH visible AS (
+    SELECT posts.*, COALESCE(feed_follows.title, feeds.name)::TEXT AS feed_name, feed_follows.folder_id,
+        EXISTS (
+            SELECT 1 FROM post_reads
+            JOIN posts AS read_posts ON read_posts.id = post_reads.post_id
+            WHERE post_reads.user_id = feed_follows.user_id AND read_posts.story_id = posts.story_id
+        ) AS is_read,
+        EXISTS (
+            SELECT 1 FROM post_stars
+            WHERE post_stars.user_id = feed_follows.user_id AND post_stars.post_id = posts.id
+        ) AS is_starred,
+        EXISTS (
+            SELECT 1 FROM post_highlights
+            WHERE post_highlights.user_id = feed_follows.user_id AND post_highlights.post_id = posts.id
+        ) AS is_highlighted
+    FROM posts
+    JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+    JOIN feeds ON posts.feed_id = feeds.id
+    WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND (NOT feed_follows.muted OR feed_follows.feed_id = sqlc.narg(feed_id)::uuid)
+    AND (sqlc.arg(show_hidden)::boolean OR NOT EXISTS (
+        SELECT 1 FROM post_hides
+        WHERE post_hides.user_id = feed_follows.user_id AND post_hides.post_id = posts.id
+    ))
+), stories AS (
+    SELECT DISTINCT ON (visible.story_id) visible.* FROM visible
+    WHERE NOT (sqlc.arg(unread_only)::boolean AND visible.is_read)
+    AND (NOT sqlc.arg(starred_only)::boolean OR visible.is_starred)
+    AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+        SELECT 1 FROM post_tags
+        JOIN tags ON tags.id = post_tags.tag_id
+        WHERE post_tags.post_id = visible.id AND tags.user_id = sqlc.arg(user_id) AND tags.name = sqlc.narg(tag)::text
+    ))
+    AND (sqlc.narg(feed_id)::uuid IS NULL OR visible.feed_id = sqlc.narg(feed_id)::uuid)
+    AND (sqlc.narg(folder_id)::uuid IS NULL OR visible.folder_id = sqlc.narg(folder_id)::uuid)
+    AND (sqlc.narg(since)::timestamptz IS NULL OR COALESCE(visible.published_at, visible.created_at) >= sqlc.narg(since)::timestamptz)
+    AND (sqlc.narg(until)::timestamptz IS NULL OR COALESCE(visible.published_at, visible.created_at) < sqlc.narg(until)::timestamptz)
+    ORDER BY visible.story_id, visible.published_at ASC NULLS LAST, visible.created_at ASC
+), keyed AS (
+    SELECT stories.*,
+        (CASE WHEN sqlc.arg(sort)::text = 'feed' THEN stories.feed_name ELSE '' END)::TEXT AS sort_group,
+        COALESCE(
+            CASE WHEN sqlc.arg(sort)::text = 'fetched' THEN stories.created_at ELSE stories.published_at END,
+            '0001-01-01 00:00:00+00'::timestamptz
+        )::TIMESTAMPTZ AS sort_time
+    FROM stories
+)
+SELECT keyed.*,
+    ARRAY(
+        SELECT DISTINCT visible.feed_name FROM visible
+        WHERE visible.story_id = keyed.story_id AND visible.feed_id <> keyed.feed_id
+        ORDER BY visible.feed_name
+    )::TEXT[] AS also_in,
+    ARRAY(
+        SELECT tags.name FROM post_tags
+        JOIN tags ON tags.id = post_tags.tag_id
+        WHERE post_tags.post_id = keyed.id AND tags.user_id = sqlc.arg(user_id)
+        ORDER BY tags.name
+    )::TEXT[] AS tags
+FROM keyed
+WHERE sqlc.narg(cursor_id)::uuid IS NULL
+OR keyed.sort_group > sqlc.narg(cursor_group)::text
+OR (keyed.sort_group = sqlc.narg(cursor_group)::text AND keyed.sort_time < sqlc.narg(cursor_time)::timestamptz)
+OR (keyed.sort_group = sqlc.narg(cursor_group)::text AND keyed.sort_time = sqlc.narg(cursor_time)::timestamptz AND keyed.id < sqlc.narg(cursor_id)::uuid)
+ORDER BY keyed.sort_group ASC, keyed.sort_time DESC, keyed.id DESC
+OFFSET sqlc.arg('offset')
+LIMIT sqlc.arg('limit');
+--
+-- name: UpdatePostContent :exec
+UPDATE posts
+SET content = $2,
+updated_at = $3
+WHERE id = $1;
+--
+
+-- name: GetFeedPostByURL :one
+SELECT * FROM posts
+WHERE feed_id = $1 AND url = $2;
+--
+
+-- Oldest first, so when two posts normalise to the same URL the older one keeps it.
+-- name: GetPostURLs :many
+SELECT id, feed_id, url, original_url FROM posts
+ORDER BY created_at ASC;
+--
+
+-- name: UpdatePostURL :exec
+UPDATE posts
+SET url = $2,
+original_url = $3,
+updated_at = $4
+WHERE id = $1;
+--
+
+-- name: DeletePost :exec
+DELETE FROM posts WHERE id = $1;
+--
+
+-- Story of a post from another feed linking to the same (normalised) URL, if any.
+-- name: GetStoryIDByURL :one
+SELECT story_id FROM posts
+WHERE url = $1 AND feed_id <> $2
+ORDER BY created_at ASC
+LIMIT 1;
+--
+
+-- Fingerprinted posts from other feeds since a cutoff, to match near-duplicate titles against.
+-- name: GetRecentPostFingerprints :many
+SELECT id, story_id, fingerprint FROM posts
+WHERE fingerprint IS NOT NULL AND feed_id <> $1 AND created_at > $2;
+--
+
+-- name: SetPostStory :exec
+UPDATE posts
+SET story_id = $2
+WHERE id = $1;
+--
+
+-- Posts the user can see (followed feeds or starred) whose ID lies in a range; a short
+-- ID prefix is turned into the range of all UUIDs starting with it so the primary key
+-- index can be used.
+-- name: FindUserPostsByIDRange :many
 SELECT posts.*, feeds.name AS feed_name FROM posts
-JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds ON feeds.id = posts.feed_id
+WHERE posts.id BETWEEN sqlc.arg(low_id)::uuid AND sqlc.arg(high_id)::uuid
+AND (
+    EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = sqlc.arg(user_id)::uuid
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id AND post_stars.user_id = sqlc.arg(user_id)::uuid
+    )
+)
+ORDER BY posts.id
+LIMIT 10;
+--
+
+-- query uses web search syntax: "quoted phrases", OR, and -excluded words. Results are
+-- ranked by cover density (title matches weigh most) and carry a snippet of the title
+-- and text with the matches wrapped in <b></b>, plus the post body and the user's read
+-- (of any copy of the story) and star state for readers that show the whole post. Unless all_feeds is set, only
+-- posts of feeds the user follows are searched.
+-- name: SearchPosts :many
+WITH search AS (
+    SELECT websearch_to_tsquery('english', sqlc.arg(query)::text) AS query
+)
+SELECT posts.id, posts.title, posts.url, posts.published_at, feeds.name AS feed_name,
+    posts.description, posts.content, posts.base_url,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        JOIN posts AS read_posts ON read_posts.id = post_reads.post_id
+        WHERE post_reads.user_id = sqlc.arg(user_id) AND read_posts.story_id = posts.story_id
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.user_id = sqlc.arg(user_id) AND post_stars.post_id = posts.id
+    ) AS is_starred,
+    ts_rank_cd(posts.search_vector, search.query)::REAL AS rank,
+    ts_headline(
+        'english',
+        coalesce(posts.title, '') || ' — ' ||
+            regexp_replace(coalesce(posts.content, posts.description, ''), '<[^>]*>', ' ', 'g'),
+        search.query,
+        'StartSel=<b>, StopSel=</b>, MaxFragments=2, MaxWords=20, MinWords=8'
+    )::TEXT AS snippet
+FROM posts
 JOIN feeds ON posts.feed_id = feeds.id
-WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2;
---
\ No newline at end of file
+CROSS JOIN search
+WHERE posts.search_vector @@ search.query
+AND (sqlc.arg(all_feeds)::boolean OR EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = sqlc.arg(user_id)
+))
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST
+LIMIT sqlc.arg('limit');
+--
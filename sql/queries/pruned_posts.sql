@@ -0,0 +1,40 @@
+-- Posts beyond their feed's retention: past its retain_posts most recent posts, or saved
+-- more than retain_seconds before now, both by when the post was saved. default_posts
+-- and default_seconds stand in for a feed's unset (NULL) settings, and 0 means no
+-- limit. Posts anyone starred or tagged are never returned, though they count towards
+-- the most recent. feed_id, if given, limits the check to one feed.
+-- name: GetPrunablePosts :many
+WITH ranked AS (
+    SELECT posts.id, posts.feed_id, feeds.name AS feed_name, posts.title, posts.url, posts.created_at,
+        ROW_NUMBER() OVER (PARTITION BY posts.feed_id ORDER BY posts.created_at DESC, posts.id DESC) AS position,
+        COALESCE(feeds.retain_posts, sqlc.arg(default_posts)::integer) AS keep_posts,
+        COALESCE(feeds.retain_seconds, sqlc.arg(default_seconds)::bigint) AS keep_seconds
+    FROM posts
+    JOIN feeds ON feeds.id = posts.feed_id
+    WHERE sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id)::uuid
+)
+SELECT ranked.id, ranked.feed_id, ranked.feed_name, ranked.title, ranked.url, ranked.created_at
+FROM ranked
+WHERE ((ranked.keep_posts > 0 AND ranked.position > ranked.keep_posts)
+    OR (ranked.keep_seconds > 0 AND ranked.created_at < sqlc.arg(now)::timestamptz - ranked.keep_seconds * INTERVAL '1 second'))
+    AND NOT EXISTS (SELECT 1 FROM post_stars WHERE post_stars.post_id = ranked.id)
+    AND NOT EXISTS (SELECT 1 FROM post_tags WHERE post_tags.post_id = ranked.id)
+ORDER BY ranked.feed_name, ranked.feed_id, ranked.created_at DESC, ranked.id DESC;
+
+-- name: CreatePrunedPost :exec
+INSERT INTO pruned_posts (feed_id, url, pruned_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (feed_id, url) DO NOTHING;
+
+-- Whether a post with the URL was pruned from the feed, and so shouldn't be saved again.
+-- name: IsPostPruned :one
+SELECT EXISTS (
+    SELECT 1 FROM pruned_posts WHERE feed_id = $1 AND url = $2
+);
+
+-- name: GetFeedPrunedURLs :many
+SELECT url FROM pruned_posts WHERE feed_id = $1 ORDER BY url;
+
+-- Forgets a pruned post once its feed no longer lists it, so tombstones don't pile up.
+-- name: DeletePrunedPost :exec
+DELETE FROM pruned_posts WHERE feed_id = $1 AND url = $2;
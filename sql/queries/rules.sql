@@ -0,0 +1,63 @@
+-- name: CreateRule :one
+INSERT INTO rules (id, created_at, user_id, expression, action, tag)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+--
+
+-- name: GetRulesForUser :many
+SELECT * FROM rules
+WHERE user_id = $1
+ORDER BY created_at;
+--
+
+-- name: DeleteRule :execrows
+DELETE FROM rules
+WHERE id = $1 AND user_id = $2;
+--
+
+-- The rules of every user following the feed, with that user's own title for it.
+-- name: GetRulesForFeed :many
+SELECT rules.*, feed_follows.title AS feed_title FROM rules
+JOIN feed_follows ON feed_follows.user_id = rules.user_id
+WHERE feed_follows.feed_id = $1
+ORDER BY rules.user_id, rules.created_at;
+--
+
+-- Everything a rule can match on, for each post in the feeds the user follows.
+-- name: GetRuleCandidatePosts :many
+SELECT posts.id, posts.title, posts.description, posts.author, posts.categories,
+    feeds.name AS feed_name, feeds.url AS feed_url, feed_follows.title AS feed_title
+FROM posts
+JOIN feeds ON feeds.id = posts.feed_id
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY posts.created_at;
+--
+
+-- name: HidePost :execrows
+INSERT INTO post_hides (user_id, post_id, hidden_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+--
+
+-- name: HighlightPost :execrows
+INSERT INTO post_highlights (user_id, post_id, highlighted_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+--
+
+-- Copies hides from a post that is about to be merged into another one.
+-- name: MovePostHides :exec
+INSERT INTO post_hides (user_id, post_id, hidden_at)
+SELECT post_hides.user_id, sqlc.arg(to_post_id)::uuid, post_hides.hidden_at FROM post_hides
+WHERE post_hides.post_id = sqlc.arg(from_post_id)::uuid
+ON CONFLICT (user_id, post_id) DO NOTHING;
+--
+
+-- Copies highlights from a post that is about to be merged into another one.
+-- name: MovePostHighlights :exec
+INSERT INTO post_highlights (user_id, post_id, highlighted_at)
+SELECT post_highlights.user_id, sqlc.arg(to_post_id)::uuid, post_highlights.highlighted_at FROM post_highlights
+WHERE post_highlights.post_id = sqlc.arg(from_post_id)::uuid
+ON CONFLICT (user_id, post_id) DO NOTHING;
+--
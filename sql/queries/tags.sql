@@ -0,0 +1,44 @@
+-- Returns the user's tag with this name, creating it first if needed.
+-- name: UpsertTag :one
+INSERT INTO tags (id, created_at, user_id, name)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+--
+
+-- name: TagPost :execrows
+INSERT INTO post_tags (tag_id, post_id, tagged_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (tag_id, post_id) DO NOTHING;
+--
+
+-- name: UntagPost :execrows
+DELETE FROM post_tags
+USING tags
+WHERE post_tags.tag_id = tags.id
+AND tags.user_id = $1 AND tags.name = $2 AND post_tags.post_id = $3;
+--
+
+-- Removes the user's tags that no longer label any post.
+-- name: DeleteUnusedTags :exec
+DELETE FROM tags
+WHERE tags.user_id = $1
+AND NOT EXISTS (SELECT 1 FROM post_tags WHERE post_tags.tag_id = tags.id);
+--
+
+-- name: GetTagsForUser :many
+SELECT tags.name, COUNT(post_tags.post_id) AS post_count
+FROM tags
+JOIN post_tags ON post_tags.tag_id = tags.id
+WHERE tags.user_id = $1
+GROUP BY tags.id, tags.name
+ORDER BY tags.name;
+--
+
+-- Copies tags from a post that is about to be merged into another one.
+-- name: MovePostTags :exec
+INSERT INTO post_tags (tag_id, post_id, tagged_at)
+SELECT post_tags.tag_id, sqlc.arg(to_post_id)::uuid, post_tags.tagged_at FROM post_tags
+WHERE post_tags.post_id = sqlc.arg(from_post_id)::uuid
+ON CONFLICT (tag_id, post_id) DO NOTHING;
+--
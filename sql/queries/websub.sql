@@ -0,0 +1,48 @@
+-- Creates the subscription for a feed, or points an existing one at a (possibly new) hub.
+-- Renewing at the same hub and topic keeps the secret, so pushes signed during the renewal
+-- still verify, and the lease. A new hub or topic gets the new secret and no lease until
+-- it verifies, so the feed is polled in the meantime and the old hub's pushes are refused.
+-- name: UpsertWebSubSubscription :one
+INSERT INTO websub_subscriptions (id, created_at, updated_at, feed_id, hub_url, topic_url, secret, requested_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (feed_id) DO UPDATE
+SET hub_url = EXCLUDED.hub_url,
+    topic_url = EXCLUDED.topic_url,
+    secret = CASE WHEN websub_subscriptions.hub_url = EXCLUDED.hub_url AND websub_subscriptions.topic_url = EXCLUDED.topic_url
+        THEN websub_subscriptions.secret ELSE EXCLUDED.secret END,
+    lease_expires_at = CASE WHEN websub_subscriptions.hub_url = EXCLUDED.hub_url AND websub_subscriptions.topic_url = EXCLUDED.topic_url
+        THEN websub_subscriptions.lease_expires_at ELSE NULL END,
+    updated_at = EXCLUDED.updated_at,
+    requested_at = EXCLUDED.requested_at
+RETURNING *;
+--
+
+-- name: GetWebSubSubscription :one
+SELECT * FROM websub_subscriptions
+WHERE id = $1;
+--
+
+-- name: GetWebSubSubscriptionForFeed :one
+SELECT * FROM websub_subscriptions
+WHERE feed_id = $1;
+--
+
+-- name: ConfirmWebSubSubscription :one
+UPDATE websub_subscriptions
+SET lease_expires_at = $2,
+updated_at = $3
+WHERE id = $1
+RETURNING *;
+--
+
+-- Subscriptions whose lease runs out soon and that were not already re-requested recently.
+-- name: GetWebSubSubscriptionsToRenew :many
+SELECT * FROM websub_subscriptions
+WHERE lease_expires_at IS NOT NULL
+AND lease_expires_at < sqlc.arg(expires_before)::timestamptz
+AND requested_at < sqlc.arg(requested_before)::timestamptz;
+--
+
+-- name: DeleteWebSubSubscription :exec
+DELETE FROM websub_subscriptions WHERE id = $1;
+--
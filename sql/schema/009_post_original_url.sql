@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN original_url TEXT;
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN original_url;
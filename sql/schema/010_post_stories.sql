@@ -0,0 +1,21 @@
+-- +goose Up
+-- Posts are unique per feed rather than globally, so an aggregator linking to an
+-- article we also get from its publisher keeps its own copy in the same story.
+ALTER TABLE posts DROP CONSTRAINT posts_url_key;
+ALTER TABLE posts ADD CONSTRAINT posts_feed_id_url_key UNIQUE (feed_id, url);
+CREATE INDEX posts_url_idx ON posts (url);
+
+ALTER TABLE posts ADD COLUMN fingerprint BIGINT;
+ALTER TABLE posts ADD COLUMN story_id UUID;
+UPDATE posts SET story_id = id;
+ALTER TABLE posts ALTER COLUMN story_id SET NOT NULL;
+CREATE INDEX posts_story_id_idx ON posts (story_id);
+
+-- +goose Down
+DROP INDEX posts_story_id_idx;
+ALTER TABLE posts DROP COLUMN story_id;
+ALTER TABLE posts DROP COLUMN fingerprint;
+
+DROP INDEX posts_url_idx;
+ALTER TABLE posts DROP CONSTRAINT posts_feed_id_url_key;
+ALTER TABLE posts ADD CONSTRAINT posts_url_key UNIQUE (url);
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Per-user settings for a followed feed: a display title replacing the feed's shared
+-- name, muting (hidden from browse), whether to be notified of new posts, and a priority
+-- ordering the feed list (higher first).
+ALTER TABLE feed_follows
+    ADD COLUMN title TEXT,
+    ADD COLUMN muted BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN notify BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE feed_follows
+    DROP COLUMN priority,
+    DROP COLUMN notify,
+    DROP COLUMN muted,
+    DROP COLUMN title;
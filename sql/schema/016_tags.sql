@@ -0,0 +1,21 @@
+-- +goose Up
+-- Tags are per user and free-form; names are stored lower-cased so "Go" and "go" are the
+-- same tag. Tagged posts, like starred ones, must never be pruned.
+CREATE TABLE tags (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    UNIQUE (user_id, name)
+);
+CREATE TABLE post_tags (
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    tagged_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (tag_id, post_id)
+);
+CREATE INDEX post_tags_post_id_idx ON post_tags (post_id);
+
+-- +goose Down
+DROP TABLE post_tags;
+DROP TABLE tags;
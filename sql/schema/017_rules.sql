@@ -0,0 +1,39 @@
+-- +goose Up
+-- Author and categories are kept so rules can match on them after ingest too.
+ALTER TABLE posts
+    ADD COLUMN author TEXT,
+    ADD COLUMN categories TEXT[] NOT NULL DEFAULT '{}';
+
+-- A rule applies its action to every post of a followed feed that its expression
+-- (see internal/rules) matches. tag is the tag name for the "tag" action.
+CREATE TABLE rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expression TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('hide', 'read', 'star', 'tag', 'highlight')),
+    tag TEXT,
+    CHECK ((action = 'tag') = (tag IS NOT NULL))
+);
+
+-- Posts a rule hid from or highlighted in a user's browse list.
+CREATE TABLE post_hides (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    hidden_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id)
+);
+CREATE TABLE post_highlights (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    highlighted_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE post_highlights;
+DROP TABLE post_hides;
+DROP TABLE rules;
+ALTER TABLE posts
+    DROP COLUMN categories,
+    DROP COLUMN author;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Per-feed retention, overriding the retain_posts and retain_for defaults of the config
+-- file: the feed keeps its retain_posts most recent posts and the posts saved in the
+-- last retain_seconds. NULL uses the default; 0 means no limit.
+ALTER TABLE feeds
+    ADD COLUMN retain_posts INTEGER,
+    ADD COLUMN retain_seconds BIGINT;
+
+-- The URLs of pruned posts, so a feed that still lists them doesn't bring them back as
+-- new posts on its next fetch.
+CREATE TABLE pruned_posts (
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    pruned_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (feed_id, url)
+);
+
+-- +goose Down
+DROP TABLE pruned_posts;
+ALTER TABLE feeds
+    DROP COLUMN retain_seconds,
+    DROP COLUMN retain_posts;
@@ -0,0 +1,82 @@
+-- +goose Up
+-- Store every time with its time zone. The columns so far held UTC wall-clock times
+-- (apart from a few written in the server's local time, which can't be told apart now),
+-- so existing values are read as UTC.
+ALTER TABLE users
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC';
+ALTER TABLE feeds
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN last_fetched_at TYPE TIMESTAMPTZ USING last_fetched_at AT TIME ZONE 'UTC';
+ALTER TABLE feed_follows
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC';
+ALTER TABLE posts
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN published_at TYPE TIMESTAMPTZ USING published_at AT TIME ZONE 'UTC';
+ALTER TABLE websub_subscriptions
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN requested_at TYPE TIMESTAMPTZ USING requested_at AT TIME ZONE 'UTC',
+    ALTER COLUMN lease_expires_at TYPE TIMESTAMPTZ USING lease_expires_at AT TIME ZONE 'UTC';
+ALTER TABLE post_reads
+    ALTER COLUMN read_at TYPE TIMESTAMPTZ USING read_at AT TIME ZONE 'UTC';
+ALTER TABLE post_stars
+    ALTER COLUMN starred_at TYPE TIMESTAMPTZ USING starred_at AT TIME ZONE 'UTC';
+ALTER TABLE folders
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC';
+ALTER TABLE tags
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE post_tags
+    ALTER COLUMN tagged_at TYPE TIMESTAMPTZ USING tagged_at AT TIME ZONE 'UTC';
+ALTER TABLE rules
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE post_hides
+    ALTER COLUMN hidden_at TYPE TIMESTAMPTZ USING hidden_at AT TIME ZONE 'UTC';
+ALTER TABLE post_highlights
+    ALTER COLUMN highlighted_at TYPE TIMESTAMPTZ USING highlighted_at AT TIME ZONE 'UTC';
+ALTER TABLE pruned_posts
+    ALTER COLUMN pruned_at TYPE TIMESTAMPTZ USING pruned_at AT TIME ZONE 'UTC';
+
+-- +goose Down
+ALTER TABLE pruned_posts
+    ALTER COLUMN pruned_at TYPE TIMESTAMP USING pruned_at AT TIME ZONE 'UTC';
+ALTER TABLE post_highlights
+    ALTER COLUMN highlighted_at TYPE TIMESTAMP USING highlighted_at AT TIME ZONE 'UTC';
+ALTER TABLE post_hides
+    ALTER COLUMN hidden_at TYPE TIMESTAMP USING hidden_at AT TIME ZONE 'UTC';
+ALTER TABLE rules
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE post_tags
+    ALTER COLUMN tagged_at TYPE TIMESTAMP USING tagged_at AT TIME ZONE 'UTC';
+ALTER TABLE tags
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE folders
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC';
+ALTER TABLE post_stars
+    ALTER COLUMN starred_at TYPE TIMESTAMP USING starred_at AT TIME ZONE 'UTC';
+ALTER TABLE post_reads
+    ALTER COLUMN read_at TYPE TIMESTAMP USING read_at AT TIME ZONE 'UTC';
+ALTER TABLE websub_subscriptions
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN requested_at TYPE TIMESTAMP USING requested_at AT TIME ZONE 'UTC',
+    ALTER COLUMN lease_expires_at TYPE TIMESTAMP USING lease_expires_at AT TIME ZONE 'UTC';
+ALTER TABLE posts
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN published_at TYPE TIMESTAMP USING published_at AT TIME ZONE 'UTC';
+ALTER TABLE feed_follows
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC';
+ALTER TABLE feeds
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN last_fetched_at TYPE TIMESTAMP USING last_fetched_at AT TIME ZONE 'UTC';
+ALTER TABLE users
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC';
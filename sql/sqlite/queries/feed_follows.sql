@@ -0,0 +1,50 @@
+-- SQLite has no INSERT in WITH, so CreateFeedFollow inserts and then reads the row back
+-- with the feed and user names through GetFeedFollow.
+-- name: InsertFeedFollow :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, folder_id)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: GetFeedFollow :one
+SELECT
+    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id,
+    feed_follows.feed_id, feed_follows.folder_id, feed_follows.title, feed_follows.muted,
+    feed_follows.notify, feed_follows.priority,
+    feeds.name AS feed_name,
+    users.name AS user_name
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+INNER JOIN users ON feed_follows.user_id = users.id
+WHERE feed_follows.id = ?;
+
+-- feed_name is the user's own title for the feed when they set one. Ordered by folder
+-- (feeds outside any folder first), then priority (highest first) and name.
+-- name: GetFeedFollowsForUser :many
+SELECT feed_follows.*, CAST(COALESCE(feed_follows.title, feeds.name) AS TEXT) AS feed_name,
+    users.name AS user_name, folders.name AS folder_name
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+INNER JOIN users ON feed_follows.user_id = users.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = ?
+ORDER BY folders.name NULLS FIRST, feed_follows.priority DESC, feed_name;
+
+-- name: DeleteFeedFollow :exec
+DELETE FROM feed_follows WHERE feed_id = ? AND user_id = ?;
+
+-- folder_id NULL takes the feed out of its folder.
+-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder_id = sqlc.narg(folder_id), updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(user_id) AND feed_id = sqlc.arg(feed_id);
+
+-- Changes the user's settings for a followed feed. NULL arguments keep the current value;
+-- clear_title removes the custom title so the feed's own name is used again.
+-- name: UpdateFeedFollowSettings :one
+UPDATE feed_follows
+SET title = CASE WHEN CAST(sqlc.arg(clear_title) AS BOOLEAN) THEN NULL ELSE COALESCE(sqlc.narg(title), title) END,
+    muted = COALESCE(sqlc.narg(muted), muted),
+    notify = COALESCE(sqlc.narg(notify), notify),
+    priority = COALESCE(sqlc.narg(priority), priority),
+    updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(user_id) AND feed_id = sqlc.arg(feed_id)
+RETURNING *;
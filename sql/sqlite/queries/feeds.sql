@@ -0,0 +1,51 @@
+-- name: CreateFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetFeeds :many
+SELECT * FROM feeds;
+
+-- name: GetFeedByURL :one
+SELECT * FROM feeds
+WHERE url = ?;
+
+-- name: MarkFeedFetched :one
+UPDATE feeds
+SET last_fetched_at = sqlc.arg(now),
+updated_at = sqlc.arg(now)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: GetNextFeedToFetch :one
+SELECT * FROM feeds
+ORDER BY last_fetched_at ASC NULLS FIRST
+LIMIT 1;
+
+-- name: GetFeedByID :one
+SELECT * FROM feeds
+WHERE id = ?;
+
+-- Same as GetNextFeedToFetch, but skips feeds that currently receive WebSub pushes.
+-- name: GetNextPolledFeedToFetch :one
+SELECT feeds.* FROM feeds
+LEFT JOIN websub_subscriptions ON websub_subscriptions.feed_id = feeds.id
+    AND websub_subscriptions.lease_expires_at > sqlc.arg(now)
+WHERE websub_subscriptions.id IS NULL
+ORDER BY feeds.last_fetched_at ASC NULLS FIRST
+LIMIT 1;
+
+-- name: SetFeedFetchFullContent :one
+UPDATE feeds
+SET fetch_full_content = sqlc.arg(fetch_full_content),
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: SetFeedRetention :one
+UPDATE feeds
+SET retain_posts = sqlc.arg(retain_posts),
+retain_seconds = sqlc.arg(retain_seconds),
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
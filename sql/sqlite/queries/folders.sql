@@ -0,0 +1,22 @@
+-- name: CreateFolder :one
+INSERT INTO folders (id, created_at, updated_at, user_id, name)
+VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetFolderByName :one
+SELECT * FROM folders
+WHERE user_id = ? AND name = ?;
+
+-- name: GetFoldersForUser :many
+SELECT * FROM folders
+WHERE user_id = ?
+ORDER BY name;
+
+-- name: RenameFolder :execrows
+UPDATE folders
+SET name = sqlc.arg(new_name), updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(user_id) AND name = sqlc.arg(name);
+
+-- name: DeleteFolder :execrows
+DELETE FROM folders
+WHERE user_id = ? AND name = ?;
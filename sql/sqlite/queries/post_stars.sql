@@ -0,0 +1,23 @@
+-- name: StarPost :execrows
+INSERT INTO post_stars (user_id, post_id, starred_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: UnstarPost :execrows
+DELETE FROM post_stars WHERE user_id = ? AND post_id = ?;
+
+-- Starred posts are listed whether or not the user still follows their feed.
+-- name: GetStarredPostsForUser :many
+SELECT posts.*, feeds.name AS feed_name, post_stars.starred_at FROM post_stars
+JOIN posts ON posts.id = post_stars.post_id
+JOIN feeds ON feeds.id = posts.feed_id
+WHERE post_stars.user_id = ?
+ORDER BY post_stars.starred_at DESC
+LIMIT ?;
+
+-- Copies stars from a post that is about to be merged into another one.
+-- name: MovePostStars :exec
+INSERT INTO post_stars (user_id, post_id, starred_at)
+SELECT post_stars.user_id, sqlc.arg(to_post_id), post_stars.starred_at FROM post_stars
+WHERE post_stars.post_id = sqlc.arg(from_post_id)
+ON CONFLICT (user_id, post_id) DO NOTHING;
@@ -0,0 +1,144 @@
+-- Inserts nothing, and so returns no row, when the feed already has a post with the URL.
+-- name: CreatePost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, base_url, original_url, fingerprint, story_id, author, categories)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (feed_id, url) DO NOTHING
+RETURNING *;
+
+-- Returns one row per story, like the PostgreSQL query of the same name; see there for
+-- the filters and the cursor. The earliest published copy of a story is picked with
+-- ROW_NUMBER instead of DISTINCT ON; also_in and tags are unordered JSON arrays that may
+-- contain null (the Store sorts them and drops the nulls), and sort_time is returned as
+-- text since SQLite doesn't type expressions.
+-- name: GetPostsForUser :many
+WITH visible AS (
+    SELECT posts.*, CAST(COALESCE(feed_follows.title, feeds.name) AS TEXT) AS feed_name, feed_follows.folder_id,
+        EXISTS (
+            SELECT 1 FROM post_reads
+            JOIN posts AS read_posts ON read_posts.id = post_reads.post_id
+            WHERE post_reads.user_id = feed_follows.user_id AND read_posts.story_id = posts.story_id
+        ) AS is_read,
+        EXISTS (
+            SELECT 1 FROM post_stars
+            WHERE post_stars.user_id = feed_follows.user_id AND post_stars.post_id = posts.id
+        ) AS is_starred,
+        EXISTS (
+            SELECT 1 FROM post_highlights
+            WHERE post_highlights.user_id = feed_follows.user_id AND post_highlights.post_id = posts.id
+        ) AS is_highlighted
+    FROM posts
+    JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+    JOIN feeds ON posts.feed_id = feeds.id
+    WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND (NOT feed_follows.muted OR feed_follows.feed_id = sqlc.narg(feed_id))
+    AND (CAST(sqlc.arg(show_hidden) AS BOOLEAN) OR NOT EXISTS (
+        SELECT 1 FROM post_hides
+        WHERE post_hides.user_id = feed_follows.user_id AND post_hides.post_id = posts.id
+    ))
+), filtered AS (
+    SELECT visible.*,
+        ROW_NUMBER() OVER (
+            PARTITION BY visible.story_id
+            ORDER BY visible.published_at ASC NULLS LAST, visible.created_at ASC
+        ) AS story_rank
+    FROM visible
+    WHERE (CAST(sqlc.arg(unread_only) AS BOOLEAN) = false OR NOT visible.is_read)
+    AND (CAST(sqlc.arg(starred_only) AS BOOLEAN) = false OR visible.is_starred)
+    AND (sqlc.narg(tag) IS NULL OR EXISTS (
+        SELECT 1 FROM post_tags
+        JOIN tags ON tags.id = post_tags.tag_id
+        WHERE post_tags.post_id = visible.id AND tags.user_id = sqlc.arg(user_id) AND tags.name = sqlc.narg(tag)
+    ))
+    AND (sqlc.narg(feed_id) IS NULL OR visible.feed_id = sqlc.narg(feed_id))
+    AND (sqlc.narg(folder_id) IS NULL OR visible.folder_id = sqlc.narg(folder_id))
+    AND (sqlc.narg(since) IS NULL OR COALESCE(visible.published_at, visible.created_at) >= sqlc.narg(since))
+    AND (sqlc.narg(until) IS NULL OR COALESCE(visible.published_at, visible.created_at) < sqlc.narg(until))
+), keyed AS (
+    SELECT filtered.id, filtered.created_at, filtered.updated_at, filtered.title, filtered.url,
+        filtered.description, filtered.published_at, filtered.feed_id, filtered.content,
+        filtered.base_url, filtered.original_url, filtered.fingerprint, filtered.story_id,
+        filtered.author, filtered.categories, filtered.feed_name, filtered.folder_id,
+        filtered.is_read, filtered.is_starred, filtered.is_highlighted,
+        CAST(CASE WHEN sqlc.arg(sort) = 'feed' THEN filtered.feed_name ELSE '' END AS TEXT) AS sort_group,
+        CAST(COALESCE(
+            CASE WHEN sqlc.arg(sort) = 'fetched' THEN filtered.created_at ELSE filtered.published_at END,
+            '0001-01-01 00:00:00+00:00'
+        ) AS TEXT) AS sort_time
+    FROM filtered
+    WHERE filtered.story_rank = 1
+)
+SELECT keyed.*,
+    CAST(json_group_array(DISTINCT other.feed_name) AS TEXT) AS also_in,
+    CAST(json_group_array(DISTINCT tags.name) AS TEXT) AS tags
+FROM keyed
+LEFT JOIN visible AS other ON other.story_id = keyed.story_id AND other.feed_id <> keyed.feed_id
+LEFT JOIN post_tags ON post_tags.post_id = keyed.id
+LEFT JOIN tags ON tags.id = post_tags.tag_id AND tags.user_id = sqlc.arg(user_id)
+WHERE sqlc.narg(cursor_id) IS NULL
+OR keyed.sort_group > CAST(sqlc.narg(cursor_group) AS TEXT)
+OR (keyed.sort_group = CAST(sqlc.narg(cursor_group) AS TEXT) AND keyed.sort_time < CAST(sqlc.narg(cursor_time) AS TEXT))
+OR (keyed.sort_group = CAST(sqlc.narg(cursor_group) AS TEXT) AND keyed.sort_time = CAST(sqlc.narg(cursor_time) AS TEXT) AND keyed.id < CAST(sqlc.narg(cursor_id) AS TEXT))
+GROUP BY keyed.id
+ORDER BY keyed.sort_group ASC, keyed.sort_time DESC, keyed.id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: UpdatePostContent :exec
+UPDATE posts
+SET content = ?,
+updated_at = ?
+WHERE id = ?;
+
+-- name: GetFeedPostByURL :one
+SELECT * FROM posts
+WHERE feed_id = ? AND url = ?;
+
+-- Oldest first, so when two posts normalise to the same URL the older one keeps it.
+-- name: GetPostURLs :many
+SELECT id, feed_id, url, original_url FROM posts
+ORDER BY created_at ASC;
+
+-- name: UpdatePostURL :exec
+UPDATE posts
+SET url = ?,
+original_url = ?,
+updated_at = ?
+WHERE id = ?;
+
+-- name: DeletePost :exec
+DELETE FROM posts WHERE id = ?;
+
+-- Story of a post from another feed linking to the same (normalised) URL, if any.
+-- name: GetStoryIDByURL :one
+SELECT story_id FROM posts
+WHERE url = ? AND feed_id <> ?
+ORDER BY created_at ASC
+LIMIT 1;
+
+-- Fingerprinted posts from other feeds since a cutoff, to match near-duplicate titles against.
+-- name: GetRecentPostFingerprints :many
+SELECT id, story_id, fingerprint FROM posts
+WHERE fingerprint IS NOT NULL AND feed_id <> ? AND created_at > ?;
+
+-- name: SetPostStory :exec
+UPDATE posts
+SET story_id = ?
+WHERE id = ?;
+
+-- Posts the user can see (followed feeds or starred) whose ID lies in a range.
+-- name: FindUserPostsByIDRange :many
+SELECT posts.*, feeds.name AS feed_name FROM posts
+JOIN feeds ON feeds.id = posts.feed_id
+WHERE posts.id >= sqlc.arg(low_id) AND posts.id <= sqlc.arg(high_id)
+AND (
+    EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = sqlc.arg(user_id)
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id AND post_stars.user_id = sqlc.arg(user_id)
+    )
+)
+ORDER BY posts.id
+LIMIT 10;
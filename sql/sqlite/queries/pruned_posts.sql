@@ -0,0 +1,35 @@
+-- Posts beyond their feed's retention, like the PostgreSQL query of the same name.
+-- Times are compared as Unix seconds, since the age limit varies by feed.
+-- name: GetPrunablePosts :many
+WITH ranked AS (
+    SELECT posts.id, posts.feed_id, feeds.name AS feed_name, posts.title, posts.url, posts.created_at,
+        ROW_NUMBER() OVER (PARTITION BY posts.feed_id ORDER BY posts.created_at DESC, posts.id DESC) AS position,
+        COALESCE(feeds.retain_posts, CAST(sqlc.arg(default_posts) AS INTEGER)) AS keep_posts,
+        COALESCE(feeds.retain_seconds, CAST(sqlc.arg(default_seconds) AS INTEGER)) AS keep_seconds
+    FROM posts
+    JOIN feeds ON feeds.id = posts.feed_id
+    WHERE sqlc.narg(feed_id) IS NULL OR posts.feed_id = sqlc.narg(feed_id)
+)
+SELECT ranked.id, ranked.feed_id, ranked.feed_name, ranked.title, ranked.url, ranked.created_at
+FROM ranked
+WHERE ((ranked.keep_posts > 0 AND ranked.position > ranked.keep_posts)
+    OR (ranked.keep_seconds > 0 AND unixepoch(ranked.created_at) < unixepoch(sqlc.arg(now)) - ranked.keep_seconds))
+    AND NOT EXISTS (SELECT 1 FROM post_stars WHERE post_stars.post_id = ranked.id)
+    AND NOT EXISTS (SELECT 1 FROM post_tags WHERE post_tags.post_id = ranked.id)
+ORDER BY ranked.feed_name, ranked.feed_id, ranked.created_at DESC, ranked.id DESC;
+
+-- name: CreatePrunedPost :exec
+INSERT INTO pruned_posts (feed_id, url, pruned_at)
+VALUES (?, ?, ?)
+ON CONFLICT (feed_id, url) DO NOTHING;
+
+-- name: IsPostPruned :one
+SELECT EXISTS (
+    SELECT 1 FROM pruned_posts WHERE feed_id = ? AND url = ?
+);
+
+-- name: GetFeedPrunedURLs :many
+SELECT url FROM pruned_posts WHERE feed_id = ? ORDER BY url;
+
+-- name: DeletePrunedPost :exec
+DELETE FROM pruned_posts WHERE feed_id = ? AND url = ?;
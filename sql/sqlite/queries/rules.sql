@@ -0,0 +1,54 @@
+-- name: CreateRule :one
+INSERT INTO rules (id, created_at, user_id, expression, action, tag)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetRulesForUser :many
+SELECT * FROM rules
+WHERE user_id = ?
+ORDER BY created_at;
+
+-- name: DeleteRule :execrows
+DELETE FROM rules
+WHERE id = ? AND user_id = ?;
+
+-- The rules of every user following the feed, with that user's own title for it.
+-- name: GetRulesForFeed :many
+SELECT rules.*, feed_follows.title AS feed_title FROM rules
+JOIN feed_follows ON feed_follows.user_id = rules.user_id
+WHERE feed_follows.feed_id = ?
+ORDER BY rules.user_id, rules.created_at;
+
+-- Everything a rule can match on, for each post in the feeds the user follows.
+-- name: GetRuleCandidatePosts :many
+SELECT posts.id, posts.title, posts.description, posts.author, posts.categories,
+    feeds.name AS feed_name, feeds.url AS feed_url, feed_follows.title AS feed_title
+FROM posts
+JOIN feeds ON feeds.id = posts.feed_id
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = ?
+ORDER BY posts.created_at;
+
+-- name: HidePost :execrows
+INSERT INTO post_hides (user_id, post_id, hidden_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: HighlightPost :execrows
+INSERT INTO post_highlights (user_id, post_id, highlighted_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- Copies hides from a post that is about to be merged into another one.
+-- name: MovePostHides :exec
+INSERT INTO post_hides (user_id, post_id, hidden_at)
+SELECT post_hides.user_id, sqlc.arg(to_post_id), post_hides.hidden_at FROM post_hides
+WHERE post_hides.post_id = sqlc.arg(from_post_id)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- Copies highlights from a post that is about to be merged into another one.
+-- name: MovePostHighlights :exec
+INSERT INTO post_highlights (user_id, post_id, highlighted_at)
+SELECT post_highlights.user_id, sqlc.arg(to_post_id), post_highlights.highlighted_at FROM post_highlights
+WHERE post_highlights.post_id = sqlc.arg(from_post_id)
+ON CONFLICT (user_id, post_id) DO NOTHING;
@@ -0,0 +1,39 @@
+-- Returns the user's tag with this name, creating it first if needed.
+-- name: UpsertTag :one
+INSERT INTO tags (id, created_at, user_id, name)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (user_id, name) DO UPDATE SET name = excluded.name
+RETURNING *;
+
+-- name: TagPost :execrows
+INSERT INTO post_tags (tag_id, post_id, tagged_at)
+VALUES (?, ?, ?)
+ON CONFLICT (tag_id, post_id) DO NOTHING;
+
+-- name: UntagPost :execrows
+DELETE FROM post_tags
+WHERE post_tags.post_id = sqlc.arg(post_id)
+AND post_tags.tag_id IN (
+    SELECT tags.id FROM tags WHERE tags.user_id = sqlc.arg(user_id) AND tags.name = sqlc.arg(name)
+);
+
+-- Removes the user's tags that no longer label any post.
+-- name: DeleteUnusedTags :exec
+DELETE FROM tags
+WHERE tags.user_id = ?
+AND NOT EXISTS (SELECT 1 FROM post_tags WHERE post_tags.tag_id = tags.id);
+
+-- name: GetTagsForUser :many
+SELECT tags.name, COUNT(post_tags.post_id) AS post_count
+FROM tags
+JOIN post_tags ON post_tags.tag_id = tags.id
+WHERE tags.user_id = ?
+GROUP BY tags.id, tags.name
+ORDER BY tags.name;
+
+-- Copies tags from a post that is about to be merged into another one.
+-- name: MovePostTags :exec
+INSERT INTO post_tags (tag_id, post_id, tagged_at)
+SELECT post_tags.tag_id, sqlc.arg(to_post_id), post_tags.tagged_at FROM post_tags
+WHERE post_tags.post_id = sqlc.arg(from_post_id)
+ON CONFLICT (tag_id, post_id) DO NOTHING;
@@ -0,0 +1,18 @@
+-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, name)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetUser :one
+SELECT * FROM users
+WHERE name = ?;
+
+-- name: GetUserByUUID :one
+SELECT * FROM users
+WHERE id = ?;
+
+-- name: DeleteUsers :exec
+DELETE FROM users;
+
+-- name: GetUsers :many
+SELECT name FROM users;
@@ -0,0 +1,42 @@
+-- Creates the subscription for a feed, or points an existing one at a (possibly new) hub.
+-- Renewing at the same hub and topic keeps the secret, so pushes signed during the renewal
+-- still verify, and the lease. A new hub or topic gets the new secret and no lease until
+-- it verifies, so the feed is polled in the meantime and the old hub's pushes are refused.
+-- name: UpsertWebSubSubscription :one
+INSERT INTO websub_subscriptions (id, created_at, updated_at, feed_id, hub_url, topic_url, secret, requested_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (feed_id) DO UPDATE
+SET hub_url = excluded.hub_url,
+    topic_url = excluded.topic_url,
+    secret = CASE WHEN websub_subscriptions.hub_url = excluded.hub_url AND websub_subscriptions.topic_url = excluded.topic_url
+        THEN websub_subscriptions.secret ELSE excluded.secret END,
+    lease_expires_at = CASE WHEN websub_subscriptions.hub_url = excluded.hub_url AND websub_subscriptions.topic_url = excluded.topic_url
+        THEN websub_subscriptions.lease_expires_at ELSE NULL END,
+    updated_at = excluded.updated_at,
+    requested_at = excluded.requested_at
+RETURNING *;
+
+-- name: GetWebSubSubscription :one
+SELECT * FROM websub_subscriptions
+WHERE id = ?;
+
+-- name: GetWebSubSubscriptionForFeed :one
+SELECT * FROM websub_subscriptions
+WHERE feed_id = ?;
+
+-- name: ConfirmWebSubSubscription :one
+UPDATE websub_subscriptions
+SET lease_expires_at = sqlc.arg(lease_expires_at),
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- Subscriptions whose lease runs out soon and that were not already re-requested recently.
+-- name: GetWebSubSubscriptionsToRenew :many
+SELECT * FROM websub_subscriptions
+WHERE lease_expires_at IS NOT NULL
+AND lease_expires_at < sqlc.arg(expires_before)
+AND requested_at < sqlc.arg(requested_before);
+
+-- name: DeleteWebSubSubscription :exec
+DELETE FROM websub_subscriptions WHERE id = ?;
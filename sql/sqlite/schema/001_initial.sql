@@ -0,0 +1,175 @@
+-- +goose Up
+-- The SQLite schema matches the PostgreSQL one in sql/schema as of its migration 017.
+-- IDs are UUIDs stored as text, timestamps are stored by the driver as text that sorts
+-- chronologically, and posts.categories is a JSON array. Full-text search uses an FTS5
+-- table kept in step with posts by triggers.
+CREATE TABLE users (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT UNIQUE
+);
+
+CREATE TABLE feeds (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    url TEXT UNIQUE NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    last_fetched_at TIMESTAMP,
+    fetch_full_content BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE TABLE folders (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE feed_follows (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    folder_id UUID REFERENCES folders(id) ON DELETE SET NULL,
+    title TEXT,
+    muted BOOLEAN NOT NULL DEFAULT false,
+    notify BOOLEAN NOT NULL DEFAULT false,
+    priority INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (user_id, feed_id)
+);
+
+CREATE TABLE posts (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL,
+    description TEXT,
+    published_at TIMESTAMP,
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    content TEXT,
+    base_url TEXT,
+    original_url TEXT,
+    fingerprint BIGINT,
+    story_id UUID NOT NULL,
+    author TEXT,
+    categories TEXT NOT NULL DEFAULT '[]',
+    UNIQUE (feed_id, url)
+);
+CREATE INDEX posts_url_idx ON posts (url);
+CREATE INDEX posts_story_id_idx ON posts (story_id);
+
+CREATE VIRTUAL TABLE posts_fts USING fts5 (
+    title, description, content,
+    content = 'posts',
+    tokenize = 'porter unicode61'
+);
+-- +goose StatementBegin
+CREATE TRIGGER posts_fts_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO posts_fts (rowid, title, description, content)
+    VALUES (new.rowid, new.title, new.description, new.content);
+END;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TRIGGER posts_fts_delete AFTER DELETE ON posts BEGIN
+    INSERT INTO posts_fts (posts_fts, rowid, title, description, content)
+    VALUES ('delete', old.rowid, old.title, old.description, old.content);
+END;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TRIGGER posts_fts_update AFTER UPDATE OF title, description, content ON posts BEGIN
+    INSERT INTO posts_fts (posts_fts, rowid, title, description, content)
+    VALUES ('delete', old.rowid, old.title, old.description, old.content);
+    INSERT INTO posts_fts (rowid, title, description, content)
+    VALUES (new.rowid, new.title, new.description, new.content);
+END;
+-- +goose StatementEnd
+
+CREATE TABLE websub_subscriptions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL UNIQUE REFERENCES feeds(id) ON DELETE CASCADE,
+    hub_url TEXT NOT NULL,
+    topic_url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    requested_at TIMESTAMP NOT NULL,
+    lease_expires_at TIMESTAMP
+);
+
+CREATE TABLE post_reads (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    read_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE TABLE post_stars (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    starred_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE TABLE tags (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    UNIQUE (user_id, name)
+);
+CREATE TABLE post_tags (
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    tagged_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (tag_id, post_id)
+);
+CREATE INDEX post_tags_post_id_idx ON post_tags (post_id);
+
+CREATE TABLE rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expression TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('hide', 'read', 'star', 'tag', 'highlight')),
+    tag TEXT,
+    CHECK ((action = 'tag') = (tag IS NOT NULL))
+);
+
+CREATE TABLE post_hides (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    hidden_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id)
+);
+CREATE TABLE post_highlights (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    highlighted_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE post_highlights;
+DROP TABLE post_hides;
+DROP TABLE rules;
+DROP TABLE post_tags;
+DROP TABLE tags;
+DROP TABLE post_stars;
+DROP TABLE post_reads;
+DROP TABLE websub_subscriptions;
+DROP TRIGGER posts_fts_update;
+DROP TRIGGER posts_fts_delete;
+DROP TRIGGER posts_fts_insert;
+DROP TABLE posts_fts;
+DROP TABLE posts;
+DROP TABLE feed_follows;
+DROP TABLE folders;
+DROP TABLE feeds;
+DROP TABLE users;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Per-feed retention and pruned post URLs, as in the PostgreSQL migration of the same
+-- name.
+ALTER TABLE feeds ADD COLUMN retain_posts INTEGER;
+ALTER TABLE feeds ADD COLUMN retain_seconds BIGINT;
+
+CREATE TABLE pruned_posts (
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    pruned_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (feed_id, url)
+);
+
+-- +goose Down
+DROP TABLE pruned_posts;
+ALTER TABLE feeds DROP COLUMN retain_seconds;
+ALTER TABLE feeds DROP COLUMN retain_posts;
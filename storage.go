@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/Marcus-Gustafsson/gator/internal/sqlitedb"
+	_ "github.com/lib/pq"
+)
+
+// Database drivers, as stored in state.driver.
+const (
+	driverPostgres = "postgres"
+	driverSQLite   = "sqlite"
+)
+
+// openDatabase opens the database named by the config's db_url and returns the
+// connection, the queries to run on it and which driver it uses. A sqlite:path,
+// sqlite://path or file:path URL opens (or creates) a SQLite file, with a leading ~/
+// standing for the home directory; anything else, such as a postgres:// URL, is passed
+// to the PostgreSQL driver.
+func openDatabase(dbURL string) (*sql.DB, database.Querier, string, error) {
+	path, ok := sqlitePath(dbURL)
+	if !ok {
+		connPtr, err := sql.Open("postgres", dbURL)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		return connPtr, database.New(connPtr), driverPostgres, nil
+	}
+
+	connPtr, err := sqlitedb.Open(path)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return connPtr, sqlitedb.NewStore(connPtr), driverSQLite, nil
+}
+
+// txStore is implemented by stores that have no connection to begin a transaction on
+// and run transactions themselves, such as the in-memory store of the tests.
+type txStore interface {
+	InTx(fn func() error) error
+}
+
+// withTx runs fn with a copy of the state whose dbPtr runs every query in one
+// transaction, committed if fn returns nil and rolled back otherwise. fn must use only
+// txPtr for queries: with SQLite, a write on stPtr while the transaction is open waits
+// for it to finish. Work that doesn't need to be all-or-nothing, like downloading
+// pages, belongs after withTx so the transaction stays short.
+func (stPtr *state) withTx(ctx context.Context, fn func(txPtr *state) error) error {
+	if stPtr.connPtr == nil {
+		store, ok := stPtr.dbPtr.(txStore)
+		if !ok {
+			return fmt.Errorf("withTx: %T doesn't support transactions", stPtr.dbPtr)
+		}
+		return store.InTx(func() error { return fn(stPtr) })
+	}
+
+	tx, err := stPtr.connPtr.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("withTx: couldn't begin transaction: %w", err)
+	}
+	txState := *stPtr
+	switch queries := stPtr.dbPtr.(type) {
+	case *database.Queries:
+		txState.dbPtr = queries.WithTx(tx)
+	case *sqlitedb.Store:
+		txState.dbPtr = queries.WithTx(tx)
+	default:
+		tx.Rollback()
+		return fmt.Errorf("withTx: %T doesn't support transactions", stPtr.dbPtr)
+	}
+
+	if err := fn(&txState); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("withTx: couldn't commit: %w", err)
+	}
+	return nil
+}
+
+// sqlitePath returns the file path in a SQLite db_url, and whether it is one.
+func sqlitePath(dbURL string) (string, bool) {
+	var path string
+	switch {
+	case strings.HasPrefix(dbURL, "sqlite://"):
+		path = strings.TrimPrefix(dbURL, "sqlite://")
+	case strings.HasPrefix(dbURL, "sqlite:"):
+		path = strings.TrimPrefix(dbURL, "sqlite:")
+	case strings.HasPrefix(dbURL, "file:"):
+		path = strings.TrimPrefix(dbURL, "file:")
+	default:
+		return "", false
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	return path, true
+}
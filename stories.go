@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/Marcus-Gustafsson/gator/internal/simhash"
+	"github.com/google/uuid"
+)
+
+// storyWindow is how far back we look for the same story in other feeds. Coverage of
+// one story rarely spreads over more than a few days.
+const storyWindow = 72 * time.Hour
+
+// storyFinder groups newly ingested posts of one feed with copies of the same story
+// from other feeds. Candidate fingerprints are loaded once, on the first new post, so
+// scrapes that find nothing new cost no extra queries.
+type storyFinder struct {
+	feedID     uuid.UUID
+	candidates []database.GetRecentPostFingerprintsRow
+	loaded     bool
+}
+
+// assignStory moves a new post into an existing story if another feed has a post with
+// the same canonical URL or, failing that, a title fingerprint within
+// simhash.MaxDistance. Otherwise the post stays its own story. Errors are logged.
+func (finderPtr *storyFinder) assignStory(stPtr *state, post database.Post) {
+	storyID, err := stPtr.dbPtr.GetStoryIDByURL(context.Background(), database.GetStoryIDByURLParams{
+		Url:    post.Url,
+		FeedID: post.FeedID,
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("assignStory: couldn't look up story by URL: %v", err)
+		return
+	}
+	matchedURL := err == nil
+
+	if !matchedURL && post.Fingerprint.Valid {
+		if !finderPtr.loaded {
+			finderPtr.candidates, err = stPtr.dbPtr.GetRecentPostFingerprints(context.Background(), database.GetRecentPostFingerprintsParams{
+				FeedID:    finderPtr.feedID,
+				CreatedAt: time.Now().UTC().Add(-storyWindow),
+			})
+			if err != nil {
+				log.Printf("assignStory: couldn't load recent fingerprints: %v", err)
+				return
+			}
+			finderPtr.loaded = true
+		}
+
+		bestDistance := simhash.MaxDistance + 1
+		for _, candidate := range finderPtr.candidates {
+			distance := simhash.Distance(uint64(candidate.Fingerprint.Int64), uint64(post.Fingerprint.Int64))
+			if distance < bestDistance {
+				storyID, bestDistance = candidate.StoryID, distance
+			}
+		}
+	}
+
+	if storyID == uuid.Nil || storyID == post.StoryID {
+		return
+	}
+	err = stPtr.dbPtr.SetPostStory(context.Background(), database.SetPostStoryParams{
+		ID:      post.ID,
+		StoryID: storyID,
+	})
+	if err != nil {
+		log.Printf("assignStory: couldn't add post %s to story %s: %v", post.ID, storyID, err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// normalizeTag returns the stored form of a tag name: trimmed, lower-cased and without a
+// leading "#", so "#Go", "go" and " GO " are the same tag.
+func normalizeTag(name string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+}
+
+// tagPost adds the user's tag (created if needed) to a post and reports whether the post
+// didn't have it yet. name must already be normalised.
+func (st *state) tagPost(userID, postID uuid.UUID, name string) (bool, error) {
+	tag, err := st.dbPtr.UpsertTag(context.Background(), database.UpsertTagParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UserID:    userID,
+		Name:      name,
+	})
+	if err != nil {
+		return false, err
+	}
+	added, err := st.dbPtr.TagPost(context.Background(), database.TagPostParams{
+		TagID:    tag.ID,
+		PostID:   postID,
+		TaggedAt: time.Now().UTC(),
+	})
+	return added > 0, err
+}
+
+// handlerTag adds one or more tags to a post for the current user. It expects a post ID
+// followed by the tag names. Tagged posts can be listed with browse --tag and are never
+// removed by retention or pruning. Returns an error if the ID or a tag name is invalid or
+// the update fails.
+func handlerTag(stPtr *state, cmd command, user database.User) error {
+	post, err := getPostArg(stPtr, user, cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("handlerTag: %w", err)
+	}
+
+	var added []string
+	for _, arg := range cmd.Args[1:] {
+		name := normalizeTag(arg)
+		if name == "" {
+			return fmt.Errorf("handlerTag: invalid tag %q", arg)
+		}
+		ok, err := stPtr.tagPost(user.ID, post.ID, name)
+		if err != nil {
+			return fmt.Errorf("handlerTag: couldn't tag post: %w", err)
+		}
+		if ok {
+			added = append(added, name)
+		}
+	}
+
+	if len(added) == 0 {
+		fmt.Printf("Post already had those tags: %s\n", post.Title)
+		return nil
+	}
+	fmt.Printf("Tagged %s: %s\n", strings.Join(added, ", "), post.Title)
+	return nil
+}
+
+// handlerUntag removes one or more of the current user's tags from a post. Tags that no
+// longer label any post are deleted. Returns an error if the ID is invalid or the update
+// fails.
+func handlerUntag(stPtr *state, cmd command, user database.User) error {
+	post, err := getPostArg(stPtr, user, cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("handlerUntag: %w", err)
+	}
+
+	var removed []string
+	for _, arg := range cmd.Args[1:] {
+		name := normalizeTag(arg)
+		untagged, err := stPtr.dbPtr.UntagPost(context.Background(), database.UntagPostParams{
+			UserID: user.ID,
+			Name:   name,
+			PostID: post.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("handlerUntag: couldn't untag post: %w", err)
+		}
+		if untagged > 0 {
+			removed = append(removed, name)
+		}
+	}
+	if err := stPtr.dbPtr.DeleteUnusedTags(context.Background(), user.ID); err != nil {
+		return fmt.Errorf("handlerUntag: couldn't delete unused tags: %w", err)
+	}
+
+	if len(removed) == 0 {
+		fmt.Printf("Post didn't have those tags: %s\n", post.Title)
+		return nil
+	}
+	fmt.Printf("Untagged %s: %s\n", strings.Join(removed, ", "), post.Title)
+	return nil
+}
+
+// handlerTags lists the current user's tags with the number of posts carrying each.
+// Returns an error if retrieval fails.
+func handlerTags(stPtr *state, cmd command, user database.User) error {
+	tags, err := stPtr.dbPtr.GetTagsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("handlerTags: couldn't retrieve tags: %w", err)
+	}
+
+	items := make([]tagItem, len(tags))
+	for i, tag := range tags {
+		items[i] = tagItem{Name: tag.Name, Posts: tag.PostCount}
+	}
+
+	return stPtr.outPtr.Print(items, func() {
+		if len(tags) == 0 {
+			fmt.Println("No tags found for this user.")
+			return
+		}
+		fmt.Printf("Tags for user %s:\n", user.Name.String)
+		for _, tag := range tags {
+			fmt.Printf("* %s (%d)\n", tag.Name, tag.PostCount)
+		}
+	})
+}
+
+// tagItem is a tag as listed by the tags command in structured output.
+type tagItem struct {
+	Name  string `json:"name"`
+	Posts int64  `json:"posts"`
+}
@@ -0,0 +1,536 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/google/uuid"
+)
+
+// tuiPostLimit is how many posts the reader loads for a feed, folder or search.
+const tuiPostLimit = 200
+
+// The reader's three panes, in tab order.
+const (
+	paneSources = iota
+	panePosts
+	paneBody
+	paneCount
+)
+
+// tuiHelp is the key summary shown in the status line.
+const tuiHelp = "tab pane · ↑↓ move · enter open · r read · s star · o browser · / search · u unread/all · R refresh · q quit"
+
+// tuiSource is an entry of the left pane: everything, a folder, or a single feed.
+type tuiSource struct {
+	label    string
+	feedID   uuid.NullUUID
+	folderID uuid.NullUUID
+}
+
+// tuiPost is a post as the reader lists and shows it, from either browse or search.
+type tuiPost struct {
+	ID          uuid.UUID
+	Title       string
+	FeedName    string
+	PublishedAt sql.NullTime
+	Description sql.NullString
+	Content     sql.NullString
+	BaseUrl     sql.NullString
+	Url         string
+	IsRead      bool
+	IsStarred   bool
+}
+
+// Messages delivered to the model by the commands that talk to the database.
+type (
+	tuiSourcesMsg []tuiSource
+	tuiPostsMsg   []tuiPost
+	tuiStatusMsg  string
+	tuiErrMsg     struct{ err error }
+)
+
+// tuiModel is the bubbletea model of the reader. It reads and writes through the same
+// queries as the CLI commands, so read and star state is shared with browse and friends.
+type tuiModel struct {
+	st    *state
+	user  database.User
+	dates dateDisplay
+
+	width, height int
+	focus         int
+
+	sources      []tuiSource
+	sourceCursor int
+
+	posts      []tuiPost
+	postCursor int
+	unreadOnly bool
+	query      string // active search; empty when showing the selected source
+
+	body       []string
+	bodyScroll int
+
+	searching   bool
+	searchInput string
+	status      string
+}
+
+// handlerTUI starts the interactive three-pane reader: folders and feeds on the left,
+// the posts of the selected one in the middle and the selected post on the right.
+// Returns an error if the terminal can't be used.
+func handlerTUI(stPtr *state, cmd command, user database.User) error {
+	dates, err := stPtr.dateDisplay("Mon Jan 2 2006 15:04")
+	if err != nil {
+		return fmt.Errorf("handlerTUI: %w", err)
+	}
+	model := &tuiModel{st: stPtr, user: user, dates: dates, unreadOnly: true, status: tuiHelp}
+	if _, err := tea.NewProgram(model, tea.WithAltScreen()).Run(); err != nil {
+		return fmt.Errorf("handlerTUI: %w", err)
+	}
+	return nil
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return tea.Batch(m.loadSources(), m.loadPosts())
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.showPost()
+		return m, nil
+
+	case tuiSourcesMsg:
+		m.sources = msg
+		m.sourceCursor = min(m.sourceCursor, len(m.sources)-1)
+		return m, nil
+
+	case tuiPostsMsg:
+		m.posts = msg
+		m.postCursor = 0
+		m.showPost()
+		if m.query != "" {
+			m.status = fmt.Sprintf("%d posts matching %q · esc to leave search", len(m.posts), m.query)
+		}
+		return m, nil
+
+	case tuiStatusMsg:
+		m.status = string(msg)
+		return m, nil
+
+	case tuiErrMsg:
+		m.status = "Error: " + msg.err.Error()
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.searching {
+			return m, m.updateSearch(msg)
+		}
+		return m, m.updateKey(msg)
+	}
+	return m, nil
+}
+
+// updateSearch handles keys while the search prompt is open.
+func (m *tuiModel) updateSearch(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.searching = false
+		m.query = strings.TrimSpace(m.searchInput)
+		m.focus = panePosts
+		return m.loadPosts()
+	case tea.KeyEsc:
+		m.searching = false
+		m.status = tuiHelp
+	case tea.KeyBackspace:
+		if runes := []rune(m.searchInput); len(runes) > 0 {
+			m.searchInput = string(runes[:len(runes)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.searchInput += msg.String()
+	case tea.KeyCtrlC:
+		return tea.Quit
+	}
+	return nil
+}
+
+// updateKey handles keys in normal mode.
+func (m *tuiModel) updateKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return tea.Quit
+	case "tab":
+		m.focus = (m.focus + 1) % paneCount
+	case "shift+tab":
+		m.focus = (m.focus + paneCount - 1) % paneCount
+	case "up", "k":
+		m.move(-1)
+	case "down", "j":
+		m.move(1)
+	case "pgup":
+		m.move(-m.paneRows())
+	case "pgdown", " ":
+		m.move(m.paneRows())
+	case "enter", "right", "l":
+		switch m.focus {
+		case paneSources:
+			m.query = ""
+			m.focus = panePosts
+			return m.loadPosts()
+		case panePosts:
+			m.focus = paneBody
+			if post := m.selectedPost(); post != nil && !post.IsRead {
+				return m.toggleRead()
+			}
+		}
+	case "left", "h":
+		if m.focus > paneSources {
+			m.focus--
+		}
+	case "r":
+		return m.toggleRead()
+	case "s":
+		return m.toggleStar()
+	case "o":
+		if post := m.selectedPost(); post != nil {
+			return openInBrowser(post.Url)
+		}
+	case "/":
+		m.searching = true
+		m.searchInput = m.query
+	case "esc":
+		if m.query != "" {
+			m.query = ""
+			m.status = tuiHelp
+			return m.loadPosts()
+		}
+	case "u":
+		m.unreadOnly = !m.unreadOnly
+		if m.unreadOnly {
+			m.status = "Showing unread posts"
+		} else {
+			m.status = "Showing all posts"
+		}
+		return m.loadPosts()
+	case "R":
+		m.status = "Refreshed"
+		return tea.Batch(m.loadSources(), m.loadPosts())
+	}
+	return nil
+}
+
+// move moves the cursor of the focused pane by delta rows.
+func (m *tuiModel) move(delta int) {
+	switch m.focus {
+	case paneSources:
+		m.sourceCursor = clamp(m.sourceCursor+delta, 0, len(m.sources)-1)
+	case panePosts:
+		m.postCursor = clamp(m.postCursor+delta, 0, len(m.posts)-1)
+		m.showPost()
+	case paneBody:
+		m.bodyScroll = clamp(m.bodyScroll+delta, 0, len(m.body)-m.paneRows())
+	}
+}
+
+// selectedPost returns the post under the cursor, or nil if the list is empty.
+func (m *tuiModel) selectedPost() *tuiPost {
+	if m.postCursor < 0 || m.postCursor >= len(m.posts) {
+		return nil
+	}
+	return &m.posts[m.postCursor]
+}
+
+// showPost renders the selected post into the body pane.
+func (m *tuiModel) showPost() {
+	m.body, m.bodyScroll = nil, 0
+	post := m.selectedPost()
+	if post == nil {
+		return
+	}
+
+	width := max(m.bodyWidth()-2, 20)
+	byline := post.FeedName
+	if post.PublishedAt.Valid {
+		byline += " · " + m.dates.format(post.PublishedAt.Time)
+	}
+	lines := []string{
+		lipgloss.NewStyle().Bold(true).Width(width).Render(post.Title),
+		byline,
+		post.Url,
+		"",
+	}
+	body := post.Description.String
+	if post.Content.Valid {
+		body = post.Content.String
+	}
+	lines = append(lines, renderPostText(body, post.BaseUrl, width))
+	m.body = strings.Split(strings.Join(lines, "\n"), "\n")
+}
+
+// toggleRead marks the selected post read, or unread if it already is.
+func (m *tuiModel) toggleRead() tea.Cmd {
+	post := m.selectedPost()
+	if post == nil {
+		return nil
+	}
+	post.IsRead = !post.IsRead
+	id, read := post.ID, post.IsRead
+	return func() tea.Msg {
+		var err error
+		if read {
+			_, err = m.st.dbPtr.MarkPostRead(context.Background(), database.MarkPostReadParams{
+				UserID: m.user.ID,
+				ReadAt: time.Now().UTC(),
+				PostID: id,
+			})
+		} else {
+			_, err = m.st.dbPtr.MarkPostUnread(context.Background(), database.MarkPostUnreadParams{
+				UserID: m.user.ID,
+				PostID: id,
+			})
+		}
+		if err != nil {
+			return tuiErrMsg{err}
+		}
+		if read {
+			return tuiStatusMsg("Marked read")
+		}
+		return tuiStatusMsg("Marked unread")
+	}
+}
+
+// toggleStar stars the selected post, or unstars it if it already is.
+func (m *tuiModel) toggleStar() tea.Cmd {
+	post := m.selectedPost()
+	if post == nil {
+		return nil
+	}
+	post.IsStarred = !post.IsStarred
+	id, starred := post.ID, post.IsStarred
+	return func() tea.Msg {
+		var err error
+		if starred {
+			_, err = m.st.dbPtr.StarPost(context.Background(), database.StarPostParams{
+				UserID:    m.user.ID,
+				PostID:    id,
+				StarredAt: time.Now().UTC(),
+			})
+		} else {
+			_, err = m.st.dbPtr.UnstarPost(context.Background(), database.UnstarPostParams{
+				UserID: m.user.ID,
+				PostID: id,
+			})
+		}
+		if err != nil {
+			return tuiErrMsg{err}
+		}
+		if starred {
+			return tuiStatusMsg("Starred")
+		}
+		return tuiStatusMsg("Unstarred")
+	}
+}
+
+// loadSources lists "All posts", then the user's folders with their feeds, then the
+// feeds outside any folder.
+func (m *tuiModel) loadSources() tea.Cmd {
+	return func() tea.Msg {
+		follows, err := m.st.dbPtr.GetFeedFollowsForUser(context.Background(), m.user.ID)
+		if err != nil {
+			return tuiErrMsg{err}
+		}
+		folders, err := m.st.dbPtr.GetFoldersForUser(context.Background(), m.user.ID)
+		if err != nil {
+			return tuiErrMsg{err}
+		}
+
+		sources := []tuiSource{{label: "All posts"}}
+		for _, folder := range folders {
+			sources = append(sources, tuiSource{
+				label:    folder.Name + "/",
+				folderID: uuid.NullUUID{UUID: folder.ID, Valid: true},
+			})
+			for _, follow := range follows {
+				if follow.FolderID.Valid && follow.FolderID.UUID == folder.ID {
+					sources = append(sources, tuiSource{
+						label:  "  " + follow.FeedName,
+						feedID: uuid.NullUUID{UUID: follow.FeedID, Valid: true},
+					})
+				}
+			}
+		}
+		for _, follow := range follows {
+			if !follow.FolderID.Valid {
+				sources = append(sources, tuiSource{
+					label:  follow.FeedName,
+					feedID: uuid.NullUUID{UUID: follow.FeedID, Valid: true},
+				})
+			}
+		}
+		return tuiSourcesMsg(sources)
+	}
+}
+
+// loadPosts loads the posts of the selected source, or the search results while a
+// search is active.
+func (m *tuiModel) loadPosts() tea.Cmd {
+	query, unreadOnly := m.query, m.unreadOnly
+	var source tuiSource
+	if m.sourceCursor >= 0 && m.sourceCursor < len(m.sources) {
+		source = m.sources[m.sourceCursor]
+	}
+
+	return func() tea.Msg {
+		var posts []tuiPost
+		if query != "" {
+			results, err := m.st.dbPtr.SearchPosts(context.Background(), database.SearchPostsParams{
+				Query:  query,
+				UserID: m.user.ID,
+				Limit:  tuiPostLimit,
+			})
+			if err != nil {
+				return tuiErrMsg{err}
+			}
+			for _, r := range results {
+				posts = append(posts, tuiPost{
+					ID: r.ID, Title: r.Title, FeedName: r.FeedName, PublishedAt: r.PublishedAt,
+					Description: r.Description, Content: r.Content, BaseUrl: r.BaseUrl, Url: r.Url,
+					IsRead: r.IsRead, IsStarred: r.IsStarred,
+				})
+			}
+			return tuiPostsMsg(posts)
+		}
+
+		results, err := m.st.getPostsForUser(database.GetPostsForUserParams{
+			UserID:     m.user.ID,
+			UnreadOnly: unreadOnly,
+			FeedID:     source.feedID,
+			FolderID:   source.folderID,
+			Sort:       "published",
+			Limit:      tuiPostLimit,
+		})
+		if err != nil {
+			return tuiErrMsg{err}
+		}
+		for _, r := range results {
+			posts = append(posts, tuiPost{
+				ID: r.ID, Title: r.Title, FeedName: r.FeedName, PublishedAt: r.PublishedAt,
+				Description: r.Description, Content: r.Content, BaseUrl: r.BaseUrl, Url: r.Url,
+				IsRead: r.IsRead, IsStarred: r.IsStarred,
+			})
+		}
+		return tuiPostsMsg(posts)
+	}
+}
+
+// openInBrowser opens a link with the platform's default handler.
+func openInBrowser(link string) tea.Cmd {
+	return func() tea.Msg {
+		var cmd *exec.Cmd
+		switch runtime.GOOS {
+		case "darwin":
+			cmd = exec.Command("open", link)
+		case "windows":
+			cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", link)
+		default:
+			cmd = exec.Command("xdg-open", link)
+		}
+		if err := cmd.Start(); err != nil {
+			return tuiErrMsg{fmt.Errorf("couldn't open browser: %w", err)}
+		}
+		go cmd.Wait()
+		return tuiStatusMsg("Opened " + link)
+	}
+}
+
+// Pane geometry: the source pane has a fixed width, the post list takes two fifths of
+// the rest and the body the remainder. Each pane has a one-cell border.
+func (m *tuiModel) sourcesWidth() int { return min(28, m.width/4) }
+func (m *tuiModel) postsWidth() int   { return (m.width - m.sourcesWidth()) * 2 / 5 }
+func (m *tuiModel) bodyWidth() int    { return m.width - m.sourcesWidth() - m.postsWidth() }
+func (m *tuiModel) paneRows() int     { return max(m.height-3, 1) } // borders and status line
+
+func (m *tuiModel) View() string {
+	if m.width == 0 {
+		return "Loading…"
+	}
+
+	var sourceLines []string
+	for _, source := range m.sources {
+		sourceLines = append(sourceLines, source.label)
+	}
+	var postLines []string
+	for _, post := range m.posts {
+		marker := "  "
+		if !post.IsRead {
+			marker = "● "
+		}
+		if post.IsStarred {
+			marker = "★ "
+		}
+		postLines = append(postLines, marker+post.Title)
+	}
+
+	panes := lipgloss.JoinHorizontal(lipgloss.Top,
+		m.pane(paneSources, m.sourcesWidth(), sourceLines, m.sourceCursor, 0),
+		m.pane(panePosts, m.postsWidth(), postLines, m.postCursor, 0),
+		m.pane(paneBody, m.bodyWidth(), m.body, -1, m.bodyScroll),
+	)
+
+	status := m.status
+	if m.searching {
+		status = "Search: " + m.searchInput + "█"
+	}
+	return panes + "\n" + lipgloss.NewStyle().Faint(!m.searching).MaxWidth(m.width).Render(status)
+}
+
+// pane draws one bordered pane of the given outer width. Lists scroll to keep the
+// cursor row visible and highlight it; the body (cursor -1) scrolls by offset.
+func (m *tuiModel) pane(index, width int, lines []string, cursor, offset int) string {
+	rows := m.paneRows()
+	if cursor >= 0 {
+		offset = max(cursor-rows+1, 0)
+	}
+
+	inner := width - 2
+	line := lipgloss.NewStyle().Width(inner)
+	selected := line.Reverse(true)
+	if index != m.focus {
+		selected = line.Bold(true)
+	}
+
+	var visible []string
+	for i := offset; i < len(lines) && i < offset+rows; i++ {
+		text := ansi.Truncate(lines[i], inner, "…")
+		if i == cursor {
+			visible = append(visible, selected.Render(text))
+		} else {
+			visible = append(visible, line.Render(text))
+		}
+	}
+
+	border := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		Width(inner).
+		Height(rows)
+	if index == m.focus {
+		border = border.BorderForeground(lipgloss.Color("12"))
+	}
+	return border.Render(strings.Join(visible, "\n"))
+}
+
+// clamp limits v to [low, high], preferring low when the range is empty.
+func clamp(v, low, high int) int {
+	return max(low, min(v, high))
+}
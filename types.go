@@ -1,41 +1,69 @@
 package main
 
 import (
+    "database/sql"
+    "flag"
+
     "github.com/Marcus-Gustafsson/gator/internal/config"
     "github.com/Marcus-Gustafsson/gator/internal/database"
+    "github.com/Marcus-Gustafsson/gator/internal/output"
 )
 
 // state holds a pointer to the application's configuration.
 type state struct {
-    cfgPtr *config.Config
-    dbPtr  *database.Queries
+    cfgPtr    *config.Config
+    dbPtr     database.Querier  // PostgreSQL, SQLite or (in tests) in-memory queries
+    connPtr   *sql.DB           // the connection behind dbPtr, for migrations; nil in memory
+    driver    string            // driverPostgres or driverSQLite
+    webSubPtr *webSubSubscriber // nil unless running in serve mode
+    outPtr    *output.Printer   // format chosen with --output for listing commands
 }
 
-// command represents a CLI command with its name and argument list.
+// command represents a CLI command with its name and argument list. Once run has parsed
+// it, Args holds only the positional arguments and the flags are read with the
+// stringFlag, boolFlag and intFlag accessors.
 type command struct {
     Name string
     Args []string
+
+    flags *flag.FlagSet
+    spec  *commandSpec
 }
 
-// cmds stores available CLI command handlers/functions mapped by command name.
+// cmds stores the registered commands by name, and their names in registration order
+// for help.
 type cmds struct {
-    FunctionMap map[string]func(*state, command) error
+    Specs map[string]*commandSpec
+    Order []string
 }
 
 // RSSFeed represents the structure of an RSS feed with channel information and items.
 type RSSFeed struct {
     Channel struct {
-        Title       string    `xml:"title"`
-        Link        string    `xml:"link"`
-        Description string    `xml:"description"`
-        Item        []RSSItem `xml:"item"`
+        Base        string     `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+        Title       string     `xml:"title"`
+        AtomLinks   []AtomLink `xml:"http://www.w3.org/2005/Atom link"` // must stay before Link so atom:link isn't matched as <link>
+        Link        string     `xml:"link"`
+        Description string     `xml:"description"`
+        Item        []RSSItem  `xml:"item"`
     } `xml:"channel"`
 }
 
+// AtomLink represents an <atom:link> element inside an RSS channel, used by feeds
+// to advertise their canonical URL (rel="self") and WebSub hub (rel="hub").
+type AtomLink struct {
+    Rel  string `xml:"rel,attr"`
+    Href string `xml:"href,attr"`
+}
+
 // RSSItem represents a single item/article within an RSS feed.
 type RSSItem struct {
-    Title       string `xml:"title"`
-    Link        string `xml:"link"`
-    Description string `xml:"description"`
-    PubDate     string `xml:"pubDate"`
+    Base        string   `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+    Title       string   `xml:"title"`
+    Link        string   `xml:"link"`
+    Description string   `xml:"description"`
+    PubDate     string   `xml:"pubDate"`
+    Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"` // dc:creator, the usual author field
+    Author      string   `xml:"author"`                                   // RSS 2.0 author, an email address
+    Categories  []string `xml:"category"`
 }
\ No newline at end of file
@@ -6,7 +6,6 @@ import (
     "database/sql"
     "errors"
     "fmt"
-    "os"
     "time"
 
     "github.com/Marcus-Gustafsson/gator/internal/database"
@@ -19,14 +18,9 @@ import (
 
 // handlerLogin checks if a given username exists in the database, and sets it
 // as the current user in the config if found. If the username does not exist,
-// the process exits with code 1.
+// it returns an error.
 func handlerLogin(stPtr *state, cmd command) error {
 
-    // Check that exactly one argument was passed (the username). If not, return an error.
-    if len(cmd.Args) != 1 {
-        return errors.New("handlerLogin: expects a single argument, the username")
-    }
-
     // Try to fetch the user by name from the database.
     _, err := stPtr.dbPtr.GetUser(
         context.Background(),
@@ -36,7 +30,7 @@ func handlerLogin(stPtr *state, cmd command) error {
     // If the error is sql.ErrNoRows, that means no user was found with this name.
     // In this app, "login" must fail (exit with status code 1) if the user doesn't exist.
     if errors.Is(err, sql.ErrNoRows) {
-        os.Exit(1)
+        return fmt.Errorf("handlerLogin: no user named %q; create it with 'gator register %s'", cmd.Args[0], cmd.Args[0])
     }
 
     // If we see another error (for example, a database connection error),
@@ -59,14 +53,9 @@ func handlerLogin(stPtr *state, cmd command) error {
 
 // handlerRegister creates a new user in the database with the given username
 // and sets the new user as current in the config.
-// If the username already exists, the process exits with code 1.
+// If the username already exists, it returns an error.
 func handlerRegister(stPtr *state, cmd command) error {
 
-    // Make sure a username was provided and only one argument is present.
-    if len(cmd.Args) != 1 {
-        return errors.New("handlerRegister: expects a single argument, the username to register")
-    }
-
     // Check if a user with this name already exists in the db.
     _, err := stPtr.dbPtr.GetUser(
         context.Background(),
@@ -75,7 +64,7 @@ func handlerRegister(stPtr *state, cmd command) error {
 
     // If no error, that means user already exists—so we should not register again.
     if err == nil {
-        os.Exit(1)
+        return fmt.Errorf("handlerRegister: user %q already exists", cmd.Args[0])
     }
     // If the error is something other than "no rows found", it's an actual db error, return it.
     if !errors.Is(err, sql.ErrNoRows) {
@@ -87,8 +76,8 @@ func handlerRegister(stPtr *state, cmd command) error {
         context.Background(),
         database.CreateUserParams{
             ID:        uuid.New(),
-            CreatedAt: time.Now(),
-            UpdatedAt: time.Now(),
+            CreatedAt: time.Now().UTC(),
+            UpdatedAt: time.Now().UTC(),
             Name:      sql.NullString{String: cmd.Args[0], Valid: true},
         },
     )
@@ -116,7 +105,11 @@ func handlerRegister(stPtr *state, cmd command) error {
 // fails, in which case a non-zero exit code will be produced by main().
 func handlerReset(stPtr *state, cmd command) error {
 
-    err := stPtr.dbPtr.DeleteUsers(context.Background()) // Execute the DELETE
+    // Everything the users own goes with them through the schema's cascades, in the
+    // same transaction, so a failure part way leaves the database as it was.
+    err := stPtr.withTx(context.Background(), func(txPtr *state) error {
+        return txPtr.dbPtr.DeleteUsers(context.Background()) // Execute the DELETE
+    })
 
     if err != nil {
         return fmt.Errorf("handlerReset: couldn't delete all users in 'users' table: %w", err)  
@@ -139,15 +132,26 @@ func handlerGetUsers(stPtr *state, cmd command) error{
         return fmt.Errorf("handlerGetUsers: couldn't retrieve all user names in 'users' table: %w", err)  
     }
 
+    var items []userItem
     for _, user := range userNames{
         if user.Valid{
-            if stPtr.cfgPtr.CurrentUserName == user.String{
-                fmt.Printf("* %v (current)\n", user.String)
+            items = append(items, userItem{Name: user.String, Current: stPtr.cfgPtr.CurrentUserName == user.String})
+        }
+    }
+
+    return stPtr.outPtr.Print(items, func() {
+        for _, item := range items{
+            if item.Current{
+                fmt.Printf("* %v (current)\n", item.Name)
             }else{
-                fmt.Printf("* %v\n", user.String)
+                fmt.Printf("* %v\n", item.Name)
             }
         }
-    }
+    })
+}
 
-    return nil
+// userItem is a user as listed by the users command in structured output.
+type userItem struct {
+    Name    string `json:"name"`
+    Current bool   `json:"current"`
 }
\ No newline at end of file
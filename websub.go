@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Marcus-Gustafsson/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// webSubLeaseSeconds is the lease we ask hubs for (10 days); hubs may grant less.
+	webSubLeaseSeconds = 10 * 24 * 60 * 60
+	// webSubRenewBefore is how long before a lease expires we ask the hub to renew it.
+	webSubRenewBefore = 24 * time.Hour
+	// webSubRenewInterval is how often the renewal loop looks for expiring leases.
+	webSubRenewInterval = 10 * time.Minute
+	// webSubCallbackPath prefixes every callback URL; the subscription ID follows it.
+	webSubCallbackPath = "/websub/"
+)
+
+// webSubSubscriber subscribes to WebSub (PubSubHubbub) hubs on behalf of feeds and
+// serves the callback endpoint the hubs use to verify intent and push new content.
+type webSubSubscriber struct {
+	callbackBase *url.URL
+	client       *http.Client
+}
+
+// handlerServe runs agg together with a WebSub callback server. It expects three
+// arguments: the address to listen on (e.g. ":8080"), the public base URL hubs can
+// reach that address at, and the polling interval for feeds without a hub. Feeds that
+// advertise a hub are subscribed to while scraping and then skipped by the poller for
+// as long as their lease is valid. Returns an error if arguments are invalid or the
+// server fails to start.
+func handlerServe(stPtr *state, cmd command) error {
+	callbackBase, err := url.Parse(cmd.Args[1])
+	if err != nil || callbackBase.Scheme == "" || callbackBase.Host == "" {
+		return cmd.usageError("callback base URL must be absolute, got %q", cmd.Args[1])
+	}
+
+	timeBetweenRequests, err := time.ParseDuration(cmd.Args[2])
+	if err != nil {
+		return cmd.usageError("invalid duration %q: %v", cmd.Args[2], err)
+	}
+
+	stPtr.webSubPtr = &webSubSubscriber{
+		callbackBase: callbackBase,
+		client:       &http.Client{Timeout: time.Second * 10},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(webSubCallbackPath, func(w http.ResponseWriter, r *http.Request) {
+		stPtr.webSubPtr.handleCallback(stPtr, w, r)
+	})
+	server := &http.Server{Addr: cmd.Args[0], Handler: mux}
+
+	// Surface listen errors (e.g. port in use) before we start aggregating.
+	serverErr := make(chan error, 1)
+	go func() { serverErr <- server.ListenAndServe() }()
+	select {
+	case err := <-serverErr:
+		return fmt.Errorf("handlerServe: callback server failed: %w", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	go func() {
+		log.Fatalf("handlerServe: callback server stopped: %v", <-serverErr)
+	}()
+
+	log.Printf("Serving WebSub callbacks on %s (public URL %s)", cmd.Args[0], callbackBase)
+	go stPtr.webSubPtr.renewForever(stPtr)
+
+	log.Printf("Collecting feeds without a hub every %s...", timeBetweenRequests)
+	aggregateForever(stPtr, timeBetweenRequests, cmd.boolFlag("prune"))
+	return nil
+}
+
+// callbackURL returns the URL the hub should call for the given subscription.
+func (subPtr *webSubSubscriber) callbackURL(subscriptionID uuid.UUID) string {
+	return subPtr.callbackBase.JoinPath(webSubCallbackPath, subscriptionID.String()).String()
+}
+
+// ensureSubscribed makes sure the feed has a subscription at the given hub. Nothing is
+// sent if a lease for the same hub and topic is still comfortably valid; otherwise the
+// subscription is stored and a subscribe request is sent. Errors are logged.
+func (subPtr *webSubSubscriber) ensureSubscribed(stPtr *state, feed database.Feed, hubURL, topicURL string) {
+	now := time.Now().UTC()
+
+	existing, err := stPtr.dbPtr.GetWebSubSubscriptionForFeed(context.Background(), feed.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("ensureSubscribed: couldn't look up subscription for feed %s: %v", feed.Name, err)
+		return
+	}
+	if err == nil && existing.HubUrl == hubURL && existing.TopicUrl == topicURL &&
+		existing.LeaseExpiresAt.Valid && existing.LeaseExpiresAt.Time.After(now.Add(webSubRenewBefore)) {
+		return
+	}
+
+	secret, err := newWebSubSecret()
+	if err != nil {
+		log.Printf("ensureSubscribed: couldn't generate secret: %v", err)
+		return
+	}
+
+	subscription, err := stPtr.dbPtr.UpsertWebSubSubscription(context.Background(), database.UpsertWebSubSubscriptionParams{
+		ID:          uuid.New(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		FeedID:      feed.ID,
+		HubUrl:      hubURL,
+		TopicUrl:    topicURL,
+		Secret:      secret,
+		RequestedAt: now,
+	})
+	if err != nil {
+		log.Printf("ensureSubscribed: couldn't save subscription for feed %s: %v", feed.Name, err)
+		return
+	}
+
+	if err := subPtr.subscribe(subscription); err != nil {
+		log.Printf("ensureSubscribed: couldn't subscribe feed %s at %s: %v", feed.Name, hubURL, err)
+		return
+	}
+	log.Printf("ensureSubscribed: requested WebSub subscription for feed %s at %s", feed.Name, hubURL)
+}
+
+// dropWebSubSubscription deletes the feed's subscription, if it has one, once the feed
+// stops advertising a hub, so the poller doesn't keep skipping it under a lease from a
+// hub that no longer delivers its content. Errors are logged.
+func dropWebSubSubscription(stPtr *state, feed database.Feed) {
+	subscription, err := stPtr.dbPtr.GetWebSubSubscriptionForFeed(context.Background(), feed.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		log.Printf("dropWebSubSubscription: couldn't look up subscription for feed %s: %v", feed.Name, err)
+		return
+	}
+	if err := stPtr.dbPtr.DeleteWebSubSubscription(context.Background(), subscription.ID); err != nil {
+		log.Printf("dropWebSubSubscription: couldn't delete subscription for feed %s: %v", feed.Name, err)
+		return
+	}
+	log.Printf("dropWebSubSubscription: feed %s no longer advertises a hub, polling it again", feed.Name)
+}
+
+// subscribe sends a subscription request to the hub. The hub answers asynchronously by
+// calling our callback with a challenge, so a 202 Accepted is the expected response.
+func (subPtr *webSubSubscriber) subscribe(subscription database.WebsubSubscription) error {
+	form := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {subscription.TopicUrl},
+		"hub.callback":      {subPtr.callbackURL(subscription.ID)},
+		"hub.secret":        {subscription.Secret},
+		"hub.lease_seconds": {strconv.Itoa(webSubLeaseSeconds)},
+	}
+
+	request, err := http.NewRequest("POST", subscription.HubUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Add("User-Agent", "gator")
+
+	response, err := subPtr.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("error sending the request: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("hub responded %s: %s", response.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// renewForever periodically re-subscribes every subscription whose lease is about to
+// expire, so pushes keep flowing without falling back to polling. Never returns.
+func (subPtr *webSubSubscriber) renewForever(stPtr *state) {
+	ticker := time.NewTicker(webSubRenewInterval)
+
+	for ; ; <-ticker.C {
+		subPtr.renewExpiring(stPtr, time.Now().UTC())
+	}
+}
+
+// renewExpiring re-subscribes the subscriptions whose lease expires within
+// webSubRenewBefore of now and that weren't requested in the last renewal interval.
+// Errors are logged.
+func (subPtr *webSubSubscriber) renewExpiring(stPtr *state, now time.Time) {
+	subscriptions, err := stPtr.dbPtr.GetWebSubSubscriptionsToRenew(context.Background(), database.GetWebSubSubscriptionsToRenewParams{
+		ExpiresBefore:   now.Add(webSubRenewBefore),
+		RequestedBefore: now.Add(-webSubRenewInterval),
+	})
+	if err != nil {
+		log.Printf("renewExpiring: couldn't get subscriptions to renew: %v", err)
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		feed, err := stPtr.dbPtr.GetFeedByID(context.Background(), subscription.FeedID)
+		if err != nil {
+			log.Printf("renewExpiring: couldn't get feed for subscription %s: %v", subscription.ID, err)
+			continue
+		}
+		log.Printf("renewExpiring: renewing WebSub lease for feed %s", feed.Name)
+		subPtr.ensureSubscribed(stPtr, feed, subscription.HubUrl, subscription.TopicUrl)
+	}
+}
+
+// handleCallback serves /websub/<subscription id>. GET requests are the hub verifying
+// our intent to (un)subscribe or telling us it denied the subscription; POST requests
+// carry new feed content, which goes through the same ingest path as polled feeds.
+func (subPtr *webSubSubscriber) handleCallback(stPtr *state, w http.ResponseWriter, r *http.Request) {
+	subscriptionID, err := uuid.Parse(strings.TrimPrefix(r.URL.Path, webSubCallbackPath))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	subscription, err := stPtr.dbPtr.GetWebSubSubscription(r.Context(), subscriptionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("handleCallback: couldn't get subscription %s: %v", subscriptionID, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		subPtr.handleVerification(stPtr, w, r, subscription)
+	case http.MethodPost:
+		subPtr.handlePush(stPtr, w, r, subscription)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleVerification answers the hub's intent verification. A subscribe request for the
+// topic we asked for is confirmed by echoing hub.challenge and recording the granted
+// lease; a denial removes the subscription so the feed goes back to being polled.
+// Anything else (including unsubscribes, which we never request) is refused with 404.
+func (subPtr *webSubSubscriber) handleVerification(stPtr *state, w http.ResponseWriter, r *http.Request, subscription database.WebsubSubscription) {
+	query := r.URL.Query()
+
+	switch query.Get("hub.mode") {
+	case "subscribe":
+		if query.Get("hub.topic") != subscription.TopicUrl || query.Get("hub.challenge") == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		leaseSeconds, err := strconv.Atoi(query.Get("hub.lease_seconds"))
+		if err != nil || leaseSeconds <= 0 {
+			leaseSeconds = webSubLeaseSeconds
+		}
+		now := time.Now().UTC()
+		_, err = stPtr.dbPtr.ConfirmWebSubSubscription(r.Context(), database.ConfirmWebSubSubscriptionParams{
+			ID:             subscription.ID,
+			LeaseExpiresAt: sql.NullTime{Time: now.Add(time.Duration(leaseSeconds) * time.Second), Valid: true},
+			UpdatedAt:      now,
+		})
+		if err != nil {
+			log.Printf("handleVerification: couldn't confirm subscription %s: %v", subscription.ID, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("handleVerification: hub confirmed subscription to %s for %ds", subscription.TopicUrl, leaseSeconds)
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, query.Get("hub.challenge"))
+
+	case "denied":
+		log.Printf("handleVerification: hub denied subscription to %s: %s", subscription.TopicUrl, query.Get("hub.reason"))
+		if err := stPtr.dbPtr.DeleteWebSubSubscription(r.Context(), subscription.ID); err != nil {
+			log.Printf("handleVerification: couldn't delete subscription %s: %v", subscription.ID, err)
+		}
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handlePush ingests content distributed by the hub. The body is checked against the
+// X-Hub-Signature HMAC using the subscription's secret; per the spec, a bad signature
+// is still acknowledged with 2xx but the content is ignored.
+func (subPtr *webSubSubscriber) handlePush(stPtr *state, w http.ResponseWriter, r *http.Request, subscription database.WebsubSubscription) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 10<<20))
+	if err != nil {
+		http.Error(w, "couldn't read body", http.StatusBadRequest)
+		return
+	}
+
+	if !validWebSubSignature(subscription.Secret, r.Header.Get("X-Hub-Signature"), body) {
+		log.Printf("handlePush: ignoring push for %s with invalid signature", subscription.TopicUrl)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	feed, err := stPtr.dbPtr.GetFeedByID(r.Context(), subscription.FeedID)
+	if err != nil {
+		log.Printf("handlePush: couldn't get feed for subscription %s: %v", subscription.ID, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	feedData, err := parseFeed(body)
+	if err != nil {
+		log.Printf("handlePush: couldn't parse push for feed %s: %v", feed.Name, err)
+		http.Error(w, "couldn't parse feed", http.StatusBadRequest)
+		return
+	}
+
+	if err := ingestFeedItems(stPtr, feed, feedData, false); err != nil {
+		log.Printf("handlePush: couldn't save push for feed %s: %v", feed.Name, err)
+		http.Error(w, "internal error", http.StatusInternalServerError) // the hub retries
+		return
+	}
+	log.Printf("handlePush: feed %s pushed, %d posts found", feed.Name, len(feedData.Channel.Item))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validWebSubSignature checks an X-Hub-Signature header of the form "<algo>=<hex hmac>"
+// against the body, accepting the sha1/sha256/sha384/sha512 algorithms the spec allows.
+func validWebSubSignature(secret, header string, body []byte) bool {
+	algo, signature, ok := strings.Cut(header, "=")
+	if !ok {
+		return false
+	}
+
+	var newHash func() hash.Hash
+	switch algo {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// newWebSubSecret returns a random hex secret the hub uses to sign pushed content.
+func newWebSubSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}